@@ -0,0 +1,84 @@
+// Command backup writes every table of the inventory database to one
+// checksummed file (see internal/backup).
+//
+//	go run ./cmd/backup -o inventory.backup.json.gz
+//	go run ./cmd/backup -dir /var/backups/inventory -keep 14   # Nightly, from cron
+//
+// Restore it with ./cmd/restore, here or on another machine.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/config"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/backup"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func main() {
+	out := flag.String("o", "", "backup file (- for stdout)")
+	dir := flag.String("dir", "", "write a timestamped backup into this directory instead")
+	keep := flag.Int("keep", 0, "with -dir: keep only the newest N backups (0 = keep all)")
+	flag.Parse()
+	if (*out == "") == (*dir == "") {
+		log.Fatal("backup: give either -o file or -dir directory")
+	}
+
+	db, err := config.Load().ConnectDB()
+	if err != nil {
+		log.Fatalf("backup: failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	store := repository.NewPostgresStore(db)
+
+	path := *out
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o700); err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		path = filepath.Join(*dir, backup.FileName(time.Now()))
+	}
+
+	var w io.Writer = os.Stdout
+	var tmp *os.File
+	if path != "-" {
+		// Write next to the target and rename, so a failed run never
+		// leaves half a backup under the real name
+		tmp, err = os.CreateTemp(filepath.Dir(path), ".backup-*")
+		if err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		defer os.Remove(tmp.Name())
+		w = tmp
+	}
+
+	manifest, err := backup.Create(w, store)
+	if err != nil {
+		log.Fatalf("backup: %v", err)
+	}
+	if tmp != nil {
+		if err := tmp.Close(); err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		log.Printf("backup: wrote %s (%d products, %d movements, sha256 %s)",
+			path, manifest.Counts["products"], manifest.Counts["movements"], manifest.SHA256[:12])
+	}
+
+	if *dir != "" && *keep > 0 {
+		deleted, err := backup.Prune(*dir, *keep)
+		if err != nil {
+			log.Fatalf("backup: prune: %v", err)
+		}
+		for _, p := range deleted {
+			log.Printf("backup: deleted old backup %s", p)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Command catalog imports and exports the product catalog in bulk.
+//
+//	go run ./cmd/catalog import -dry-run products.csv   # Check every row, write nothing
+//	go run ./cmd/catalog import products.csv             # Upsert by brand + size + container
+//	go run ./cmd/catalog export -format csv > products.csv
+//
+// Files are CSV (as Excel saves it, Hebrew headers too) or JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/config"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/catalog"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: catalog import [-dry-run] [-format csv|json] [-actor name] <file|->")
+	fmt.Fprintln(os.Stderr, "       catalog export [-format csv|json] [-all] [-o file]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+// openStore connects to PostgreSQL with the server's configuration
+func openStore() repository.Repository {
+	db, err := config.Load().ConnectDB()
+	if err != nil {
+		log.Fatalf("catalog: failed to connect to database: %v", err)
+	}
+	return repository.NewPostgresStore(db)
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would change, write nothing")
+	format := fs.String("format", "", "csv or json (default: from the file extension, else detected)")
+	actor := fs.String("actor", "catalog-import", "name recorded in the audit trail")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	path := fs.Arg(0)
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("catalog: %v", err)
+		}
+		defer f.Close()
+		in = f
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+			if *format != catalog.FormatCSV && *format != catalog.FormatJSON {
+				*format = ""
+			}
+		}
+	}
+	rows, err := catalog.Read(*format, in)
+	if err != nil {
+		log.Fatalf("catalog: %v", err)
+	}
+
+	report, err := catalog.NewService(openStore().WithActor(*actor)).Import(rows, *dryRun, nil)
+	if err != nil {
+		if report != nil {
+			for _, row := range report.Rows {
+				if row.Written {
+					fmt.Printf("line %d  written   %s\n", row.Line, row.Name)
+				}
+			}
+		}
+		log.Fatalf("catalog: import stopped: %v", err)
+	}
+	for _, row := range report.Rows {
+		switch row.Action {
+		case catalog.ActionError:
+			fmt.Printf("line %d  %-9s %s: %s\n", row.Line, row.Action, row.Name, strings.Join(row.Errors, "; "))
+		case catalog.ActionUpdate:
+			fmt.Printf("line %d  %-9s %s (%s)\n", row.Line, row.Action, row.Name, strings.Join(row.Changed, ", "))
+		default:
+			fmt.Printf("line %d  %-9s %s\n", row.Line, row.Action, row.Name)
+		}
+	}
+	fmt.Printf("\n%d to create, %d to update, %d unchanged, %d with errors\n",
+		report.Created, report.Updated, report.Unchanged, report.Errors)
+	switch {
+	case report.Errors > 0:
+		fmt.Println("Nothing was written - fix the rows above and import again.")
+		os.Exit(1)
+	case report.DryRun:
+		fmt.Println("Dry run - nothing was written.")
+	default:
+		fmt.Println("Imported.")
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", catalog.FormatCSV, "csv or json")
+	all := fs.Bool("all", false, "include deleted products")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	fs.Parse(args)
+
+	rows, err := catalog.NewService(openStore()).Export(*all)
+	if err != nil {
+		log.Fatalf("catalog: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("catalog: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case catalog.FormatCSV:
+		err = catalog.WriteCSV(w, rows)
+	case catalog.FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rows)
+	default:
+		err = catalog.ErrUnknownFormat
+	}
+	if err != nil {
+		log.Fatalf("catalog: %v", err)
+	}
+	if *out != "-" {
+		log.Printf("catalog: exported %d products to %s", len(rows), *out)
+	}
+}
@@ -0,0 +1,56 @@
+// Command mcp exposes the inventory to AI assistants over the
+// Model Context Protocol (stdio transport).
+//
+// Example client config (Claude Desktop / VS Code):
+//
+//	{"command": "go", "args": ["run", "./cmd/mcp", "-read-only"]}
+//
+// stdout is reserved for the protocol - all logs go to stderr.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/config"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/mcp"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
+)
+
+func main() {
+	var (
+		readOnly = flag.Bool("read-only", false, "expose only read tools (no record_movement)")
+		memory   = flag.Bool("memory", false, "use an empty in-memory store instead of PostgreSQL")
+		reporter = flag.String("reporter", "mcp", "ReportedBy value for movements recorded through MCP")
+	)
+	flag.Parse()
+	log.SetOutput(os.Stderr)
+
+	var store repository.Repository
+	if *memory {
+		store = repository.NewMemoryStore()
+	} else {
+		cfg := config.Load()
+		db, err := cfg.ConnectDB()
+		if err != nil {
+			log.Fatalf("mcp: failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		store = repository.NewPostgresStore(db)
+	}
+	store = staff.NewStore(store) // Bind performer/reporter to the staff directory
+
+	server := mcp.NewServer(store, mcp.Options{ReadOnly: *readOnly, Reporter: *reporter})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	log.Printf("mcp: serving on stdio (read-only=%v)", *readOnly)
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		log.Printf("mcp: %v", err)
+	}
+}
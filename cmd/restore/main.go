@@ -0,0 +1,77 @@
+// Command restore loads a backup made by ./cmd/backup (or downloaded from
+// GET /admin/backup) into the inventory database, replacing everything
+// in it. Start an empty database first (docker-compose up -d applies the
+// migrations), then:
+//
+//	go run ./cmd/restore -verify inventory-20261018-0300.backup.json.gz  # Check only
+//	go run ./cmd/restore -yes inventory-20261018-0300.backup.json.gz
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/config"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/backup"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: restore [-verify | -yes] <file|->")
+	os.Exit(2)
+}
+
+func main() {
+	verify := flag.Bool("verify", false, "check the file and print what it holds, change nothing")
+	yes := flag.Bool("yes", false, "replace everything in the database with the backup")
+	flag.Parse()
+	if flag.NArg() != 1 || *verify == *yes {
+		usage()
+	}
+
+	var in io.Reader = os.Stdin
+	if path := flag.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("restore: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	manifest, snap, err := backup.Read(in)
+	if err != nil {
+		log.Fatalf("restore: %v", err)
+	}
+	if err := snap.Validate(); err != nil {
+		log.Fatalf("restore: %v", err)
+	}
+	fmt.Printf("Backup of %s (format %d, sha256 %s)\n",
+		manifest.CreatedAt.Local().Format("2006-01-02 15:04"), manifest.Version, manifest.SHA256)
+	tables := make([]string, 0, len(manifest.Counts))
+	for table := range manifest.Counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("  %-13s %d\n", table, manifest.Counts[table])
+	}
+	if *verify {
+		fmt.Println("The backup is intact. Nothing was changed.")
+		return
+	}
+
+	db, err := config.Load().ConnectDB()
+	if err != nil {
+		log.Fatalf("restore: failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	if err := repository.NewPostgresStore(db).Restore(snap); err != nil {
+		log.Fatalf("restore: %v (the database was not changed)", err)
+	}
+	fmt.Println("Restored. Everyone has to log in again.")
+}
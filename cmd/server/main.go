@@ -6,8 +6,11 @@ import (
 
 	"github.com/mennyaboush/restaurant-inventory-ai/config"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/api"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/chat"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
 )
 
 func main() {
@@ -34,8 +37,9 @@ func startHTTPServer() {
 	defer db.Close()
 	fmt.Println("✅ Database connected successfully")
 
-	// Create PostgresStore (implements Repository)
-	store := repository.NewPostgresStore(db)
+	// Create PostgresStore (implements Repository); movements recorded
+	// through it are bound to the staff directory
+	store := staff.NewStore(repository.NewPostgresStore(db))
 
 	// Add demo products to store (for now)
 	demoProducts := []struct {
@@ -50,7 +54,7 @@ func startHTTPServer() {
 		{"קוקה קולה 330 מ״ל פחית", "Coca Cola", 330, "can", 24, 5.50, "drinks"},
 		{"פנטה 330 מ״ל פחית", "Fanta", 330, "can", 24, 5.50, "drinks"},
 		{"פלפל אדום", "ירקות טריים", 1000, "kg", 0, 15.00, "vegetables"},
-		{"פלפל ירוק", "ירקות טריים", 1000, "kg", 0, 12.00, "vegetables"},
+		{"פלפל ירוק", "חוות הגליל", 1000, "kg", 0, 12.00, "vegetables"},
 		{"חומוס 400 גרם", "עשי", 400, "can", 12, 8.00, "canned"},
 	}
 	for _, p := range demoProducts {
@@ -69,6 +73,33 @@ func startHTTPServer() {
 
 	// Create API and use chi router
 	apiHandler := api.NewAPI(store)
+	apiHandler.IdempotencyTTL = cfg.API.IdempotencyTTL
+
+	// AI assistant is optional - enabled with AI_PROVIDER=openai
+	if cfg.AI.Provider == "openai" {
+		provider := chat.NewOpenAIProvider(cfg.AI.BaseURL, cfg.AI.APIKey, cfg.AI.Model)
+		apiHandler.Chat = chat.NewService(store, provider)
+		fmt.Printf("🤖 AI assistant enabled (%s)\n", cfg.AI.Model)
+	}
+
+	// Authentication is on unless explicitly disabled
+	if cfg.Auth.Disabled {
+		fmt.Println("⚠️  AUTH_DISABLED=true - the API is open to anyone on the network")
+	} else {
+		authService, err := auth.NewService(store, auth.Options{
+			Secret:     []byte(cfg.Auth.JWTSecret),
+			AccessTTL:  cfg.Auth.AccessTTL,
+			RefreshTTL: cfg.Auth.RefreshTTL,
+		})
+		if err != nil {
+			fmt.Printf("❌ Auth setup failed: %v (set JWT_SECRET, e.g. openssl rand -hex 32)\n", err)
+			return
+		}
+		apiHandler.Auth = authService
+		if authService.NeedsSetup() {
+			fmt.Println("👤 No users yet - create the first one with POST /auth/register")
+		}
+	}
 	router := apiHandler.Router()
 
 	fmt.Println("🚀 HTTP server running at http://localhost:8080 ...")
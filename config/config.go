@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
@@ -12,6 +13,9 @@ import (
 // Config holds all application configuration
 type Config struct {
 	Database DatabaseConfig
+	AI       AIConfig
+	Auth     AuthConfig
+	API      APIConfig
 }
 
 // DatabaseConfig holds database connection settings
@@ -24,6 +28,30 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// AIConfig selects the LLM provider for the chat assistant
+// Provider "" disables the assistant; "openai" works with any
+// OpenAI-compatible API (OpenAI, Ollama at http://localhost:11434/v1)
+type AIConfig struct {
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Model    string
+}
+
+// AuthConfig holds login/JWT settings
+// JWTSecret must be at least 32 bytes unless Disabled is set
+type AuthConfig struct {
+	Disabled   bool          // AUTH_DISABLED=true turns auth off (local dev only!)
+	JWTSecret  string        // HMAC key for access tokens
+	AccessTTL  time.Duration // Access token lifetime
+	RefreshTTL time.Duration // Refresh token lifetime
+}
+
+// APIConfig holds HTTP API settings
+type APIConfig struct {
+	IdempotencyTTL time.Duration // How long an Idempotency-Key replays its first response
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
@@ -35,6 +63,21 @@ func Load() *Config {
 			DBName:   getEnv("DB_NAME", "restaurant_inventory"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
+		AI: AIConfig{
+			Provider: getEnv("AI_PROVIDER", ""),
+			BaseURL:  getEnv("AI_BASE_URL", "https://api.openai.com/v1"),
+			APIKey:   getEnv("AI_API_KEY", ""),
+			Model:    getEnv("AI_MODEL", "gpt-4o-mini"),
+		},
+		Auth: AuthConfig{
+			Disabled:   getEnv("AUTH_DISABLED", "") == "true",
+			JWTSecret:  getEnv("JWT_SECRET", ""),
+			AccessTTL:  getEnvDuration("AUTH_ACCESS_TTL", 15*time.Minute),
+			RefreshTTL: getEnvDuration("AUTH_REFRESH_TTL", 30*24*time.Hour),
+		},
+		API: APIConfig{
+			IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		},
 	}
 }
 
@@ -70,3 +113,11 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvDuration reads a duration like "15m" or "720h", with a fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -3,6 +3,8 @@ module github.com/mennyaboush/restaurant-inventory-ai
 go 1.25.6
 
 require (
-	github.com/go-chi/chi/v5 v5.2.4 // indirect
-	github.com/lib/pq v1.11.1 // indirect
+	github.com/go-chi/chi/v5 v5.2.4
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/lib/pq v1.11.1
+	golang.org/x/crypto v0.54.0
 )
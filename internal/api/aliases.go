@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// searchResult is one entry of GET /products/search
+type searchResult struct {
+	Product      *models.Product `json:"product"`
+	Score        float64         `json:"score"`
+	MatchedField string          `json:"matchedField"`
+	MatchedAlias string          `json:"matchedAlias,omitempty"`
+}
+
+// handleSearchProducts handles GET /products/search?q=...
+// Matches name, brand and aliases; best match first
+func (api *API) handleSearchProducts(w http.ResponseWriter, r *http.Request) {
+	matches := api.Store.SearchProductsRanked(r.URL.Query().Get("q"))
+	results := make([]searchResult, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, searchResult{
+			Product:      m.Product,
+			Score:        m.Score,
+			MatchedField: m.MatchedField,
+			MatchedAlias: m.MatchedAlias,
+		})
+	}
+	respondJSON(w, http.StatusOK, results)
+}
+
+// handleListAliases handles GET /products/{id}/aliases
+func (api *API) handleListAliases(w http.ResponseWriter, r *http.Request) {
+	aliases, err := api.Store.ListAliases(chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, aliases)
+}
+
+// handleAddAlias handles POST /products/{id}/aliases
+func (api *API) handleAddAlias(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	alias, err := api.Store.AddAlias(chi.URLParam(r, "id"), input.Alias)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, alias)
+}
+
+// handleDeleteAlias handles DELETE /products/{id}/aliases/{aliasId}
+func (api *API) handleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	if err := api.Store.DeleteAlias(chi.URLParam(r, "id"), chi.URLParam(r, "aliasId")); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,23 +5,53 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/chat"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/invoice"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/service"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
 )
 
 // API holds dependencies for HTTP handlers (e.g., the repository)
 type API struct {
-	Store repository.ProductRepository
+	Store    repository.Repository
+	Variance *service.VarianceService
+	Chat     *chat.Service // nil when no LLM provider is configured
+	Invoices *invoice.Service
+	Staff    *staff.Service
+	Sync     *service.SyncService
+	Auth     *auth.Service // nil disables authentication (AUTH_DISABLED, tests)
+
+	// IdempotencyTTL is how long an Idempotency-Key replays its first
+	// response; 0 turns the header off
+	IdempotencyTTL time.Duration
+
+	idempotencyPurgedAt atomic.Int64 // Unix nanos of the last purge of expired keys
 }
 
 // NewAPI creates a new API instance with the given repository
-func NewAPI(store repository.ProductRepository) *API {
-	return &API{Store: store}
+func NewAPI(store repository.Repository) *API {
+	return &API{
+		Store:    store,
+		Variance: service.NewVarianceService(store),
+		Invoices: invoice.NewService(store),
+		Staff:    staff.NewService(store),
+		Sync:     service.NewSyncService(store),
+
+		IdempotencyTTL: DefaultIdempotencyTTL,
+	}
 }
 
 // LoggingMiddleware logs each HTTP request with method, path, and duration
@@ -36,8 +66,12 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 // ErrorResponse represents a JSON error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error   string `json:"error"`   // Kind of error: "not_found", "validation_error"
+	Code    string `json:"code"`    // What exactly failed: "product_name_required" (i18n catalog)
+	Message string `json:"message"` // Code's text in the caller's language
+
+	// Fields lists the input fields that failed validation and why
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
 // respondJSON writes a JSON response with the given status code
@@ -49,23 +83,45 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// respondError writes a JSON error response
-func respondError(w http.ResponseWriter, status int, errType, message string) {
-	respondJSON(w, status, ErrorResponse{Error: errType, Message: message})
+// respondError writes a JSON error response in the caller's language.
+// message is the English text (usually err.Error()); the parts of it the
+// catalog knows are translated. Code falls back to errType when none is.
+func respondError(w http.ResponseWriter, r *http.Request, status int, errType, message string) {
+	lang := requestLanguage(r)
+	code, localized := i18n.Localize(lang, message)
+	if code == "" {
+		code = errType
+	}
+	w.Header().Set("Content-Language", lang)
+	respondJSON(w, status, ErrorResponse{Error: errType, Code: code, Message: localized})
 }
 
-// validateProductInput validates product input fields and returns error message if invalid
-func validateProductInput(name string, size int, price float64) (string, bool) {
+// requestLanguage is the language to answer in: the logged-in user's
+// preference, else the best match of Accept-Language, else i18n.Default
+func requestLanguage(r *http.Request) string {
+	if id, ok := auth.IdentityFrom(r.Context()); ok && id.Language != "" {
+		return id.Language
+	}
+	if lang := i18n.Negotiate(r.Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return i18n.Default
+}
+
+// validateProductInput checks the product fields every request must get
+// right; it reports all of them at once (errors.Join), nil if all are fine
+func validateProductInput(name string, size int, price float64) error {
+	var errs []error
 	if name == "" {
-		return "name is required", false
+		errs = append(errs, models.ErrProductNameRequired)
 	}
 	if size <= 0 {
-		return "size must be positive", false
+		errs = append(errs, models.ErrProductInvalidSize)
 	}
 	if price < 0 {
-		return "price cannot be negative", false
+		errs = append(errs, models.ErrProductInvalidPrice)
 	}
-	return "", true
+	return errors.Join(errs...)
 }
 
 // Router returns a chi.Router with all API routes registered
@@ -75,20 +131,197 @@ func (api *API) Router() chi.Router {
 	// Add middleware
 	r.Use(LoggingMiddleware)
 
-	r.Route("/products", func(r chi.Router) {
-		r.Get("/", api.handleListProducts)
-		r.Post("/", api.handleCreateProduct)
-		r.Get("/{id}", api.handleGetProduct)
-		r.Put("/{id}", api.handleUpdateProduct)
-		r.Delete("/{id}", api.handleDeleteProduct)
+	// Public: login and token refresh
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/login", api.handleLogin)
+		r.Post("/refresh", api.handleRefresh)
+		r.Post("/register", api.handleRegister) // Open only while no user exists
+		r.With(api.requireAuth).Post("/logout", api.handleLogout)
+		r.With(api.requireAuth).Get("/me", api.handleMe)
+		r.With(api.requireAuth).Put("/me/language", api.handleSetLanguage)
+	})
+
+	// Everything else needs a valid access token or API key
+	r.Group(func(r chi.Router) {
+		r.Use(api.requireAuth)
+		r.Use(api.idempotent) // Retries with the same Idempotency-Key replay the first response
+
+		// Every role can view; API keys need the inventory:view scope
+		view := api.requirePermission(auth.PermViewInventory)
+
+		r.Route("/products", func(r chi.Router) {
+			r.With(view).Get("/", api.handleListProducts)
+			r.With(view).Get("/search", api.handleSearchProducts)
+			r.With(view).Get("/export", api.handleExportProducts)
+			r.With(view).Get("/by-barcode/{code}", api.handleGetByBarcode)
+			r.With(view).Get("/{id}", api.handleGetProduct)
+			r.With(view).Get("/{id}/aliases", api.handleListAliases)
+			r.With(view).Get("/{id}/barcodes", api.handleListBarcodes)
+
+			// Catalog changes: owner and manager
+			r.Group(func(r chi.Router) {
+				r.Use(api.requirePermission(auth.PermEditProducts))
+				r.Post("/", api.handleCreateProduct)
+				r.Post("/import", api.handleImportProducts)
+				r.Put("/{id}", api.handleUpdateProduct)
+				r.Patch("/{id}", api.handlePatchProduct)
+				r.Post("/{id}/aliases", api.handleAddAlias)
+				r.Delete("/{id}/aliases/{aliasId}", api.handleDeleteAlias)
+				r.Post("/{id}/barcodes", api.handleAddBarcode)
+				r.Delete("/{id}/barcodes/{code}", api.handleDeleteBarcode)
+			})
+
+			r.With(api.requirePermission(auth.PermDeleteProducts)).Delete("/{id}", api.handleDeleteProduct)
+			r.With(api.requirePermission(auth.PermDeleteProducts)).Post("/{id}/restore", api.handleRestoreProduct)
+			r.With(api.requirePermission(auth.PermPurgeProducts)).Post("/{id}/purge", api.handlePurgeProduct)
+			r.With(api.requirePermission(auth.PermDeleteProducts)).Post("/{id}/merge", api.handleMergeProduct)
+			r.With(api.requirePermission(auth.PermSetMinStock)).Put("/{id}/min-stock", api.handleSetMinStock)
+		})
+
+		r.With(view).Get("/stock/{productId}", api.handleGetStock)
+
+		// Movement types are checked per role inside the handlers
+		r.Post("/scan", api.handleScan)
+
+		r.Route("/movements", func(r chi.Router) {
+			r.With(view).Get("/", api.handleListMovements)
+			r.Post("/", api.handleCreateMovement)
+		})
+
+		// Offline devices: movement types are checked per movement
+		r.With(view).Post("/sync", api.handleSync)
+
+		r.With(api.requirePermission(auth.PermViewReports)).Post("/reports/variance", api.handleVarianceReport)
+
+		r.Route("/invoices", func(r chi.Router) {
+			r.Use(api.requirePermission(auth.PermReceiveInvoices))
+			r.Post("/", api.handleIngestInvoice)
+			r.Get("/drafts", api.handleListInvoiceDrafts)
+			r.Get("/drafts/{id}", api.handleGetInvoiceDraft)
+			r.Post("/drafts/{id}/approve", api.handleApproveInvoice)
+			r.Post("/drafts/{id}/reject", api.handleRejectInvoice)
+		})
+
+		r.Route("/categories", func(r chi.Router) {
+			r.With(view).Get("/", api.handleListCategories)
+			r.With(view).Get("/{id}", api.handleGetCategory)
+			r.With(api.requirePermission(auth.PermEditProducts)).Post("/", api.handleCreateCategory)
+			r.With(api.requirePermission(auth.PermEditProducts)).Put("/{id}", api.handleUpdateCategory)
+			r.With(api.requirePermission(auth.PermDeleteProducts)).Delete("/{id}", api.handleDeleteCategory)
+		})
+
+		r.Route("/staff", func(r chi.Router) {
+			r.With(view).Get("/", api.handleListStaff)
+			r.With(view).Get("/match", api.handleMatchStaff)
+			r.With(view).Get("/{id}", api.handleGetStaff)
+			r.With(api.requirePermission(auth.PermManageStaff)).Post("/", api.handleCreateStaff)
+			r.With(api.requirePermission(auth.PermManageStaff)).Put("/{id}", api.handleUpdateStaff)
+			r.With(api.requirePermission(auth.PermManageUsers)).Post("/backfill", api.handleBackfillStaff)
+		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Use(api.requirePermission(auth.PermManageUsers))
+			r.Get("/", api.handleListUsers)
+			r.Put("/{id}/role", api.handleSetUserRole)
+		})
+
+		r.With(api.requirePermission(auth.PermViewAudit)).Get("/audit", api.handleListAudit)
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(api.requirePermission(auth.PermManageBackups))
+			r.Get("/backup", api.handleDownloadBackup)
+			r.Post("/restore", api.handleRestoreBackup)
+		})
+
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(api.requirePermission(auth.PermManageAPIKeys))
+			r.Get("/", api.handleListAPIKeys)
+			r.Get("/scopes", api.handleListKeyScopes)
+			r.Post("/", api.handleCreateAPIKey)
+			r.Delete("/{id}", api.handleRevokeAPIKey)
+		})
+
+		r.Route("/chat", func(r chi.Router) {
+			r.Use(view)
+			r.Post("/", api.handleChat)
+			r.Get("/drafts/{id}", api.handleGetDraft)
+			r.Post("/drafts/{id}/confirm", api.handleConfirmDraft)
+			r.Post("/drafts/{id}/cancel", api.handleCancelDraft)
+		})
 	})
 	return r
 }
 
+// ProductListResponse is one page of GET /products
+type ProductListResponse struct {
+	Products   []*models.Product `json:"products"`
+	Total      int               `json:"total"`                // Matching products on all pages
+	NextCursor string            `json:"nextCursor,omitempty"` // Send as ?cursor= for the next page
+}
+
 // handleListProducts handles GET /products
+// Filters: ?category=&brand=&containerType=&status=active|inactive|all
+// &minPrice=&maxPrice=&lowStock=true; sorting: ?sort=name|brand|category|
+// price|size|updatedAt&order=asc|desc; paging: ?limit=&cursor=
 func (api *API) handleListProducts(w http.ResponseWriter, r *http.Request) {
-	products := api.Store.ListProducts()
-	respondJSON(w, http.StatusOK, products)
+	query, msg := parseProductQuery(r.URL.Query())
+	if msg != "" {
+		respondError(w, r, http.StatusBadRequest, "invalid_query", msg)
+		return
+	}
+	page, err := api.Store.QueryProducts(query)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, ProductListResponse{Products: page.Products, Total: page.Total, NextCursor: page.NextCursor})
+}
+
+// parseProductQuery reads the GET /products parameters; a non-empty
+// message says which one is wrong
+func parseProductQuery(q url.Values) (repository.ProductQuery, string) {
+	query := repository.ProductQuery{
+		Category:      q.Get("category"),
+		Brand:         q.Get("brand"),
+		ContainerType: q.Get("containerType"),
+		Status:        q.Get("status"),
+		Sort:          q.Get("sort"),
+		Cursor:        q.Get("cursor"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  *float64
+	}{{"minPrice", &query.MinPrice}, {"maxPrice", &query.MaxPrice}} {
+		if v := q.Get(p.name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return query, p.name + " must be a number"
+			}
+			*p.dst = f
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return query, "limit must be a positive whole number"
+		}
+		query.Limit = n
+	}
+	switch q.Get("lowStock") {
+	case "", "false":
+	case "true":
+		query.LowStock = true
+	default:
+		return query, "lowStock must be true or false"
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, "order must be asc or desc"
+	}
+	return query, ""
 }
 
 // handleCreateProduct handles POST /products
@@ -103,15 +336,15 @@ func (api *API) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		Category      string  `json:"category"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
 		return
 	}
 	// Validate input
-	if msg, ok := validateProductInput(input.Name, input.Size, input.Price); !ok {
-		respondError(w, http.StatusBadRequest, "validation_error", msg)
+	if err := validateProductInput(input.Name, input.Size, input.Price); err != nil {
+		respondDomainError(w, r, err)
 		return
 	}
-	id, err := api.Store.AddProduct(&models.Product{
+	product := &models.Product{
 		Name:          input.Name,
 		Brand:         input.Brand,
 		Size:          input.Size,
@@ -119,28 +352,40 @@ func (api *API) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		BoxSize:       input.BoxSize,
 		Price:         input.Price,
 		Category:      input.Category,
-	})
+	}
+	id, err := api.storeFor(r).AddProduct(product)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "create_error", err.Error())
+		respondDomainError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", etag(product.Version))
 	respondJSON(w, http.StatusCreated, map[string]string{"id": id})
 }
 
 // handleGetProduct handles GET /products/{id}
+// Sends the version as ETag; If-None-Match with it gets 304
 func (api *API) handleGetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	product, err := api.Store.GetProduct(id)
 	if err != nil {
-		respondError(w, http.StatusNotFound, "not_found", err.Error())
+		respondDomainError(w, r, err)
+		return
+	}
+	if notModified(w, r, product.Version) {
 		return
 	}
 	respondJSON(w, http.StatusOK, product)
 }
 
 // handleUpdateProduct handles PUT /products/{id}
+// Needs If-Match with the ETag from GET; 412 if someone changed it since.
+// PUT replaces every field; isActive may be left out to keep it as is.
 func (api *API) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
 	var input struct {
 		Name          string  `json:"name"`
 		Brand         string  `json:"brand"`
@@ -149,14 +394,20 @@ func (api *API) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
 		BoxSize       int     `json:"boxSize"`
 		Price         float64 `json:"price"`
 		Category      string  `json:"category"`
+		IsActive      *bool   `json:"isActive"` // Omitted = keep
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
 		return
 	}
 	// Validate input
-	if msg, ok := validateProductInput(input.Name, input.Size, input.Price); !ok {
-		respondError(w, http.StatusBadRequest, "validation_error", msg)
+	if err := validateProductInput(input.Name, input.Size, input.Price); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	current, err := api.Store.GetProduct(id)
+	if err != nil {
+		respondDomainError(w, r, err)
 		return
 	}
 	product := &models.Product{
@@ -168,20 +419,72 @@ func (api *API) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
 		BoxSize:       input.BoxSize,
 		Price:         input.Price,
 		Category:      input.Category,
+		IsActive:      current.IsActive,
+		Version:       version,
 	}
-	if err := api.Store.UpdateProduct(product); err != nil {
-		respondError(w, http.StatusBadRequest, "update_error", err.Error())
-		return
+	if input.IsActive != nil {
+		product.IsActive = *input.IsActive
 	}
-	respondJSON(w, http.StatusOK, product)
+	api.saveProduct(w, r, current, product)
 }
 
-// handleDeleteProduct handles DELETE /products/{id}
+// handleDeleteProduct handles DELETE /products/{id} (If-Match required)
 func (api *API) handleDeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := api.Store.DeleteProduct(id); err != nil {
-		respondError(w, http.StatusNotFound, "not_found", err.Error())
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	if err := api.storeFor(r).DeleteProduct(id, version); err != nil {
+		respondDomainError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// handleSetMinStock handles PUT /products/{id}/min-stock
+// Body: {"minStock": 48} (total units; below it the product is "low")
+// Needs If-Match with the stock ETag from GET /stock/{productId}
+func (api *API) handleSetMinStock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		MinStock int `json:"minStock"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if input.MinStock < 0 {
+		respondDomainError(w, r, &inputError{Field: "minStock", Err: errNegative})
+		return
+	}
+	if err := api.storeFor(r).SetMinStock(id, input.MinStock, version); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	stock, err := api.Store.GetStock(id)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(stock.Version))
+	respondJSON(w, http.StatusOK, stock)
+}
+
+// handleGetStock handles GET /stock/{productId}
+// Sends the stock version as ETag; If-None-Match with it gets 304
+func (api *API) handleGetStock(w http.ResponseWriter, r *http.Request) {
+	stock, err := api.Store.GetStock(chi.URLParam(r, "productId"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	if notModified(w, r, stock.Version) {
+		return
+	}
+	respondJSON(w, http.StatusOK, stock)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testServer runs the whole router over a memory store, with logins on
+type testServer struct {
+	t      *testing.T
+	api    *API
+	router http.Handler
+	tokens map[string]string // Access token by username
+}
+
+// newTestServer starts a server with one user per role, named after it
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	authService, err := auth.NewService(store, auth.Options{Secret: []byte(strings.Repeat("k", 32)), BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("auth.NewService failed: %v", err)
+	}
+	api := NewAPI(store)
+	api.Auth = authService
+	s := &testServer{t: t, api: api, router: api.Router(), tokens: make(map[string]string)}
+	for _, role := range []string{models.RoleOwner, models.RoleManager, models.RoleEmployee} {
+		s.addUser(role, role)
+	}
+	return s
+}
+
+// addUser registers and logs in a user, whose name then works as a token
+func (s *testServer) addUser(username, role string) {
+	s.t.Helper()
+	if _, err := s.api.Auth.Register(username, username, "correct-horse", role); err != nil {
+		s.t.Fatalf("Register %s failed: %v", username, err)
+	}
+	pair, err := s.api.Auth.Login(username, "correct-horse")
+	if err != nil {
+		s.t.Fatalf("Login %s failed: %v", username, err)
+	}
+	s.tokens[username] = pair.AccessToken
+}
+
+// addProduct stores a product directly and returns its ID
+func (s *testServer) addProduct(name string, size int) string {
+	s.t.Helper()
+	id, err := s.api.Store.AddProduct(&models.Product{Name: name, Brand: "Coca-Cola", Size: size, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	if err != nil {
+		s.t.Fatalf("AddProduct failed: %v", err)
+	}
+	return id
+}
+
+// do sends a request as user; headers come in name, value pairs
+func (s *testServer) do(user, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, path, reader)
+	if user != "" {
+		r.Header.Set("Authorization", "Bearer "+s.tokens[user])
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// Brand + size + container is unique in every store
+func TestCreateDuplicateProductConflicts(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"name": "קולה", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks"}`
+	if w := s.do("manager", http.MethodPost, "/products", body); w.Code != http.StatusCreated {
+		t.Fatalf("first POST: got %d, want 201: %s", w.Code, w.Body)
+	}
+	if w := s.do("manager", http.MethodPost, "/products", body); w.Code != http.StatusConflict {
+		t.Errorf("same brand, size and container: got %d, want 409: %s", w.Code, w.Body)
+	}
+	if n := len(s.api.Store.ListProducts()); n != 1 {
+		t.Errorf("got %d products, want 1", n)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+)
+
+// handleListAPIKeys handles GET /api-keys (owner only)
+// Shows name, scopes and last use; never the key itself
+func (api *API) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := api.Store.ListAPIKeys()
+	result := make([]auth.APIKeyInfo, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, auth.PublicAPIKey(k))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// handleListKeyScopes handles GET /api-keys/scopes
+func (api *API) handleListKeyScopes(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, auth.KeyScopes())
+}
+
+// handleCreateAPIKey handles POST /api-keys (owner only)
+// Body: {"name": "POS", "scopes": ["inventory:view", "movements:out"]}
+// The key is in the response only - store it on the machine right away.
+func (api *API) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	var input struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	createdBy := ""
+	if id, ok := auth.IdentityFrom(r.Context()); ok {
+		createdBy = id.UserID
+	}
+	key, err := api.Auth.CreateAPIKey(input.Name, input.Scopes, createdBy)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, key)
+}
+
+// handleRevokeAPIKey handles DELETE /api-keys/{id} (owner only)
+// The key stops working on its next request; it stays listed as revoked
+func (api *API) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	k, err := api.Auth.RevokeAPIKey(chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, auth.PublicAPIKey(k))
+}
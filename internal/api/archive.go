@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ============================================
+// ARCHIVE: deleted products, back or gone for good
+// ============================================
+// DELETE /products/{id} only archives a product (IsActive = false); it is
+// listed by GET /products?status=inactive. Restore brings it back; purge
+// removes it for good, but only once it is archived, out of stock and no
+// movement refers to it - the movement history must stay whole.
+
+// handleRestoreProduct handles POST /products/{id}/restore (If-Match required)
+func (api *API) handleRestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	store := api.storeFor(r)
+	if err := store.RestoreProduct(id, version); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	product, err := store.GetProduct(id)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(product.Version))
+	respondJSON(w, http.StatusOK, product)
+}
+
+// handlePurgeProduct handles POST /products/{id}/purge (If-Match required)
+func (api *API) handlePurgeProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	if err := api.storeFor(r).PurgeProduct(id, version); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// handleListAudit handles GET /audit (owner and manager)
+// Optional query params: entity ("product"), id, actor, limit; newest first
+func (api *API) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.AuditFilter{
+		Entity:   q.Get("entity"),
+		EntityID: q.Get("id"),
+		Actor:    q.Get("actor"),
+	}
+	if filter.Entity != "" && filter.Entity != models.AuditEntityProduct {
+		respondError(w, r, http.StatusBadRequest, "invalid_query", "entity must be product")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondError(w, r, http.StatusBadRequest, "invalid_query", "limit must be a number ≥ 0")
+			return
+		}
+		filter.Limit = n
+	}
+	respondJSON(w, http.StatusOK, api.Store.ListAudit(filter))
+}
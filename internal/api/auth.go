@@ -0,0 +1,312 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// requireAuth rejects requests without a valid "Authorization: Bearer" token
+// and puts the user into the request context.
+// With api.Auth == nil (AUTH_DISABLED, tests) every request passes.
+func (api *API) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.Auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := api.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="restaurant-inventory"`)
+			respondError(w, r, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
+	})
+}
+
+// authenticate reads and checks the bearer token of a request.
+// Machines may send their API key in "X-API-Key" instead.
+func (api *API) authenticate(r *http.Request) (*auth.Identity, error) {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return api.Auth.Authenticate(key)
+	}
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, errors.New("missing bearer token")
+	}
+	return api.Auth.Authenticate(strings.TrimSpace(token))
+}
+
+// requirePermission answers 403 unless the caller's role (or API key
+// scopes) has p. Must run after requireAuth; with auth disabled every
+// request passes.
+func (api *API) requirePermission(p auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id, ok := auth.IdentityFrom(r.Context()); ok && !id.Can(p) {
+				respondError(w, r, http.StatusForbidden, "forbidden", auth.ErrForbidden.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowMovement answers 403 and returns false when the caller's role
+// may not record this movement type (e.g. an employee logging IN)
+func allowMovement(w http.ResponseWriter, r *http.Request, movementType string) bool {
+	if err := movementForbidden(r, movementType); err != nil {
+		respondError(w, r, http.StatusForbidden, "forbidden", err.Error())
+		return false
+	}
+	return true
+}
+
+// movementForbidden says why the caller may not record this movement
+// type, or returns nil when they may
+func movementForbidden(r *http.Request, movementType string) error {
+	id, ok := auth.IdentityFrom(r.Context())
+	switch {
+	case !ok || id.CanRecordMovement(movementType):
+		return nil
+	case id.IsAPIKey():
+		return fmt.Errorf("API key %s may not record %s movements", id.DisplayName, movementType)
+	default:
+		return fmt.Errorf("role %s may not record %s movements", id.Role, movementType)
+	}
+}
+
+// currentUser returns the logged-in username, or fallback when auth is off.
+// With auth on, names sent in the body are ignored - nobody can log
+// movements in someone else's name. API keys report for the person the
+// machine names (the cashier on the POS), so they get the fallback too.
+func currentUser(r *http.Request, fallback string) string {
+	if id, ok := auth.IdentityFrom(r.Context()); ok && !id.IsAPIKey() {
+		return id.Username
+	}
+	return fallback
+}
+
+// storeFor returns the store that records the caller as the actor of
+// audited changes: the username, or the key name for API keys
+func (api *API) storeFor(r *http.Request) repository.Repository {
+	id, ok := auth.IdentityFrom(r.Context())
+	switch {
+	case !ok:
+		return api.Store
+	case id.IsAPIKey():
+		return api.Store.WithActor("key:" + id.DisplayName)
+	default:
+		return api.Store.WithActor(id.Username)
+	}
+}
+
+// requireSession answers 400 for API keys on routes that only make
+// sense for a logged-in person (logout, me)
+func requireSession(w http.ResponseWriter, r *http.Request) (*auth.Identity, bool) {
+	id, _ := auth.IdentityFrom(r.Context())
+	if id.IsAPIKey() {
+		respondError(w, r, http.StatusBadRequest, "api_key", "not available for API keys")
+		return nil, false
+	}
+	return id, true
+}
+
+// requireAuthService answers 503 when authentication is disabled
+func (api *API) requireAuthService(w http.ResponseWriter, r *http.Request) bool {
+	if api.Auth == nil {
+		respondError(w, r, http.StatusServiceUnavailable, "auth_disabled", "authentication is disabled")
+		return false
+	}
+	return true
+}
+
+// handleLogin handles POST /auth/login
+// Body: {"username": "dana", "password": "..."}
+func (api *API) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	var input struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	tokens, err := api.Auth.Login(input.Username, input.Password)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, tokens)
+}
+
+// handleRefresh handles POST /auth/refresh
+// Body: {"refreshToken": "..."}; the old refresh token stops working
+func (api *API) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	var input struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	tokens, err := api.Auth.Refresh(input.RefreshToken)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, tokens)
+}
+
+// handleLogout handles POST /auth/logout (revokes the current session)
+func (api *API) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	id, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	if err := api.Auth.Logout(id.SessionID); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe handles GET /auth/me
+func (api *API) handleMe(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	id, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	u, err := api.Store.GetUser(id.UserID)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, auth.PublicUser(u))
+}
+
+// handleSetLanguage handles PUT /auth/me/language
+// Body: {"language": "he"}; "" goes back to Accept-Language
+func (api *API) handleSetLanguage(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	id, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	u, err := api.Auth.SetLanguage(id.UserID, strings.ToLower(strings.TrimSpace(input.Language)))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, auth.PublicUser(u))
+}
+
+// handleRegister handles POST /auth/register
+// Body: {"username", "displayName", "password", "role"}
+// The very first user can register without a token and becomes the
+// owner (first-run setup); after that only owners can add accounts.
+func (api *API) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	id, authErr := api.authenticate(r)
+	if authErr == nil && !id.Can(auth.PermManageUsers) {
+		respondError(w, r, http.StatusForbidden, "forbidden", auth.ErrForbidden.Error())
+		return
+	}
+	var input struct {
+		Username    string `json:"username"`
+		DisplayName string `json:"displayName"`
+		Password    string `json:"password"`
+		Role        string `json:"role"` // Default: employee
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	var u *models.User
+	var err error
+	if authErr != nil {
+		// No token: only allowed while there is no user at all. The
+		// service checks that itself, as another request may be first.
+		u, err = api.Auth.Setup(input.Username, input.DisplayName, input.Password)
+		if errors.Is(err, auth.ErrSetupDone) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="restaurant-inventory"`)
+			respondError(w, r, http.StatusUnauthorized, "unauthorized", authErr.Error())
+			return
+		}
+	} else {
+		u, err = api.Auth.Register(input.Username, input.DisplayName, input.Password, input.Role)
+	}
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	// Every account is a staff member, so it can report movements
+	if _, err := api.Staff.LinkUser(u); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, auth.PublicUser(u))
+}
+
+// handleListUsers handles GET /users (owner only)
+func (api *API) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users := api.Store.ListUsers()
+	result := make([]auth.UserInfo, 0, len(users))
+	for _, u := range users {
+		result = append(result, auth.PublicUser(u))
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// handleSetUserRole handles PUT /users/{id}/role (owner only)
+// Body: {"role": "manager"}
+func (api *API) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	if !api.requireAuthService(w, r) {
+		return
+	}
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	u, err := api.Auth.SetRole(chi.URLParam(r, "id"), input.Role)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, auth.PublicUser(u))
+}
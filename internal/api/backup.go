@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/backup"
+)
+
+// maxBackupSize limits an uploaded backup file (256 MB, gzipped)
+const maxBackupSize = 256 << 20
+
+// handleDownloadBackup handles GET /admin/backup (owner only)
+// The whole inventory as one file for ./cmd/restore or POST /admin/restore
+func (api *API) handleDownloadBackup(w http.ResponseWriter, r *http.Request) {
+	snap, err := api.Store.Snapshot()
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	var buf bytes.Buffer
+	now := time.Now()
+	manifest, err := backup.Write(&buf, snap, now)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+backup.FileName(now)+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Backup-SHA256", manifest.SHA256)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("send backup: %v", err)
+	}
+}
+
+// handleRestoreBackup handles POST /admin/restore (owner only)
+// Body: a backup file. Replaces everything - products, stock, movements,
+// users - and logs everyone out. ?dryRun=true only checks the file.
+func (api *API) handleRestoreBackup(w http.ResponseWriter, r *http.Request) {
+	manifest, snap, err := backup.Read(http.MaxBytesReader(w, r.Body, maxBackupSize))
+	if err == nil {
+		err = snap.Validate()
+	}
+	if err != nil {
+		respondDomainErrorOr(w, r, err, http.StatusUnprocessableEntity, "invalid_backup")
+		return
+	}
+	if r.URL.Query().Get("dryRun") == "true" {
+		respondJSON(w, http.StatusOK, manifest)
+		return
+	}
+
+	if err := api.Store.Restore(snap); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	log.Printf("restored backup of %s (sha256 %s) by %s",
+		manifest.CreatedAt.Format(time.RFC3339), manifest.SHA256, currentUser(r, "anonymous"))
+	respondJSON(w, http.StatusOK, manifest)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// barcodeLookup is the response of GET /products/by-barcode/{code}
+type barcodeLookup struct {
+	Product *models.Product        `json:"product"`
+	Barcode *models.ProductBarcode `json:"barcode"`
+}
+
+// scanInput is the JSON body for POST /scan
+type scanInput struct {
+	Code        string `json:"code"`
+	Type        string `json:"type"` // IN, OUT, WASTE, ADJUSTMENT
+	PerformedBy string `json:"performedBy"`
+	ReportedBy  string `json:"reportedBy"`
+	Reason      string `json:"reason"`
+}
+
+// scanResult is the response of POST /scan
+type scanResult struct {
+	Product   *models.Product       `json:"product"`
+	PackLevel string                `json:"packLevel"`
+	Movement  *models.StockMovement `json:"movement"`
+}
+
+// handleGetByBarcode handles GET /products/by-barcode/{code}
+func (api *API) handleGetByBarcode(w http.ResponseWriter, r *http.Request) {
+	product, barcode, err := api.Store.GetProductByBarcode(chi.URLParam(r, "code"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, barcodeLookup{Product: product, Barcode: barcode})
+}
+
+// handleListBarcodes handles GET /products/{id}/barcodes
+func (api *API) handleListBarcodes(w http.ResponseWriter, r *http.Request) {
+	barcodes, err := api.Store.ListBarcodes(chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, barcodes)
+}
+
+// handleAddBarcode handles POST /products/{id}/barcodes
+// Body: {"code": "7290000000015", "packLevel": "unit"|"case"}
+func (api *API) handleAddBarcode(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code      string `json:"code"`
+		PackLevel string `json:"packLevel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if input.PackLevel == "" {
+		input.PackLevel = models.PackUnit
+	}
+	barcode, err := api.Store.AddBarcode(chi.URLParam(r, "id"), input.Code, input.PackLevel)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, barcode)
+}
+
+// handleDeleteBarcode handles DELETE /products/{id}/barcodes/{code}
+func (api *API) handleDeleteBarcode(w http.ResponseWriter, r *http.Request) {
+	if err := api.Store.DeleteBarcode(chi.URLParam(r, "id"), chi.URLParam(r, "code")); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleScan handles POST /scan
+// One scan moves one unit (unit barcode) or one box (case barcode)
+func (api *API) handleScan(w http.ResponseWriter, r *http.Request) {
+	var input scanInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	product, barcode, err := api.Store.GetProductByBarcode(input.Code)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	if !product.IsActive {
+		respondError(w, r, http.StatusConflict, "product_inactive", "product is no longer active: "+product.ID)
+		return
+	}
+
+	boxes, units := 0, 1
+	if barcode.PackLevel == models.PackCase {
+		boxes, units = 1, 0
+	}
+	m, err := models.NewStockMovement(product.ID, input.Type, boxes, units, input.PerformedBy, currentUser(r, input.ReportedBy), input.Reason)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	if !allowMovement(w, r, m.Type) {
+		return
+	}
+	m.Source = models.SourceScan
+	if _, err := api.Store.RecordMovement(m); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, scanResult{Product: product, PackLevel: barcode.PackLevel, Movement: m})
+}
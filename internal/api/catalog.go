@@ -0,0 +1,85 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/catalog"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// maxCatalogSize limits an uploaded catalog file (10 MB)
+const maxCatalogSize = 10 << 20
+
+// handleImportProducts handles POST /products/import
+// Body: CSV or JSON (?format=, else Content-Type, else detected).
+// ?dryRun=true only reports what would happen; rows with errors get 422
+// and nothing is written. Rows that archive or restore a product are
+// errors for callers who may not delete products. If the store fails
+// midway the report comes back with the error's status (500 when
+// unknown), marking the rows that were written.
+func (api *API) handleImportProducts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		switch contentType := r.Header.Get("Content-Type"); {
+		case strings.Contains(contentType, "json"):
+			format = catalog.FormatJSON
+		case strings.Contains(contentType, "csv"):
+			format = catalog.FormatCSV
+		}
+	}
+	rows, err := catalog.Read(format, http.MaxBytesReader(w, r.Body, maxCatalogSize))
+	if err != nil {
+		respondDomainErrorOr(w, r, err, http.StatusUnprocessableEntity, "invalid_catalog")
+		return
+	}
+
+	check := func(*models.Product) error { return mayChangeActive(r) }
+	report, err := catalog.NewService(api.storeFor(r)).Import(rows, q.Get("dryRun") == "true", check)
+	switch {
+	case err != nil && report == nil:
+		respondDomainError(w, r, err)
+	case err != nil:
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		status := http.StatusInternalServerError
+		if rule, ok := findErrorRule(leafErrors(err)[0]); ok {
+			status = rule.status
+		}
+		respondJSON(w, status, report)
+	case report.Errors > 0 && !report.DryRun:
+		respondJSON(w, http.StatusUnprocessableEntity, report)
+	default:
+		respondJSON(w, http.StatusOK, report)
+	}
+}
+
+// handleExportProducts handles GET /products/export
+// JSON, or CSV with "Accept: text/csv" (or ?format=csv); ?all=true adds
+// deleted products
+func (api *API) handleExportProducts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	rows, err := catalog.NewService(api.Store).Export(q.Get("all") == "true")
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = catalog.FormatCSV
+	}
+	switch format {
+	case "", catalog.FormatJSON:
+		respondJSON(w, http.StatusOK, rows)
+	case catalog.FormatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+		if err := catalog.WriteCSV(w, rows); err != nil {
+			log.Printf("export csv: %v", err)
+		}
+	default:
+		respondError(w, r, http.StatusBadRequest, "invalid_query", catalog.ErrUnknownFormat.Error())
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/catalog"
+)
+
+// Archiving through an import is deleting, so it takes the delete permission
+func TestImportArchiveNeedsDeletePermission(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `[{"name": "קולה", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks", "isActive": false}]`
+
+	w := s.do("manager", http.MethodPost, "/products/import", body, "Content-Type", "application/json")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("manager archiving: got %d, want 422: %s", w.Code, w.Body)
+	}
+	var report catalog.Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if errs := report.Rows[0].Errors; len(errs) != 1 || errs[0] != errChangeActive.Error() {
+		t.Errorf("row errors: got %v, want %q", errs, errChangeActive)
+	}
+	if p, _ := s.api.Store.GetProduct(id); !p.IsActive {
+		t.Errorf("manager's import archived the product")
+	}
+
+	if w := s.do("owner", http.MethodPost, "/products/import", body, "Content-Type", "application/json"); w.Code != http.StatusOK {
+		t.Fatalf("owner archiving: got %d, want 200: %s", w.Code, w.Body)
+	}
+	if p, _ := s.api.Store.GetProduct(id); p.IsActive {
+		t.Errorf("owner's import should archive the product")
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// categoryInput is the body of POST and PUT /categories
+type categoryInput struct {
+	ID              string `json:"id"` // POST only; PUT takes it from the URL
+	NameHe          string `json:"nameHe"`
+	NameEn          string `json:"nameEn"`
+	SortOrder       int    `json:"sortOrder"`
+	ParentID        string `json:"parentId"`
+	DefaultMinStock int    `json:"defaultMinStock"`
+}
+
+// category builds the category the input describes
+func (in categoryInput) category(id string) *models.Category {
+	return &models.Category{
+		ID:              id,
+		NameHe:          in.NameHe,
+		NameEn:          in.NameEn,
+		SortOrder:       in.SortOrder,
+		ParentID:        in.ParentID,
+		DefaultMinStock: in.DefaultMinStock,
+	}
+}
+
+// localizedCategory is a category with its name in the caller's language
+// added as "Name", so lists don't have to pick NameHe or NameEn
+type localizedCategory struct {
+	*models.Category
+	Name string
+}
+
+// localizeCategory pairs c with its name in the request's language
+func localizeCategory(r *http.Request, c *models.Category) localizedCategory {
+	return localizedCategory{Category: c, Name: c.LocalizedName(requestLanguage(r))}
+}
+
+// handleListCategories handles GET /categories
+func (api *API) handleListCategories(w http.ResponseWriter, r *http.Request) {
+	categories := api.Store.ListCategories()
+	res := make([]localizedCategory, len(categories))
+	for i, c := range categories {
+		res[i] = localizeCategory(r, c)
+	}
+	respondJSON(w, http.StatusOK, res)
+}
+
+// handleGetCategory handles GET /categories/{id}
+func (api *API) handleGetCategory(w http.ResponseWriter, r *http.Request) {
+	c, err := api.Store.GetCategory(chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, localizeCategory(r, c))
+}
+
+// handleCreateCategory handles POST /categories
+// Body: {"id": "beer", "nameHe": "בירה", "nameEn": "Beer", "parentId": "drinks", "defaultMinStock": 48}
+func (api *API) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var input categoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	c := input.category(input.ID)
+	if err := api.Store.AddCategory(c); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, localizeCategory(r, c))
+}
+
+// handleUpdateCategory handles PUT /categories/{id}
+// Products keep the ID, so renaming a category doesn't touch them
+func (api *API) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var input categoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	c := input.category(chi.URLParam(r, "id"))
+	if err := api.Store.UpdateCategory(c); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, localizeCategory(r, c))
+}
+
+// handleDeleteCategory handles DELETE /categories/{id}
+// Refused (409) while products or subcategories are in it
+func (api *API) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	if err := api.Store.DeleteCategory(chi.URLParam(r, "id")); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// requireChat answers 503 when no LLM provider is configured
+func (api *API) requireChat(w http.ResponseWriter, r *http.Request) bool {
+	if api.Chat == nil {
+		respondError(w, r, http.StatusServiceUnavailable, "chat_disabled", "AI assistant is not configured")
+		return false
+	}
+	return true
+}
+
+// respondChatError answers chat errors; errors the rules don't know come
+// from the AI provider (502)
+func respondChatError(w http.ResponseWriter, r *http.Request, err error) {
+	respondDomainErrorOr(w, r, err, http.StatusBadGateway, "assistant_error")
+}
+
+// handleChat handles POST /chat
+// Body: {"sessionId": "...", "user": "Dani", "message": "יוסף לקח 2 ארגזים קולה"}
+// When logged in, the user comes from the access token and "user" is ignored
+func (api *API) handleChat(w http.ResponseWriter, r *http.Request) {
+	if !api.requireChat(w, r) {
+		return
+	}
+	var input struct {
+		SessionID string `json:"sessionId"`
+		User      string `json:"user"`
+		Message   string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	reply, err := api.Chat.Chat(r.Context(), input.SessionID, currentUser(r, input.User), input.Message)
+	if err != nil && reply != nil {
+		// The turn broke off after proposing movements: show the drafts,
+		// with the error as the assistant's message
+		_, reply.Message = localizeError(requestLanguage(r), err)
+		respondJSON(w, http.StatusOK, reply)
+		return
+	}
+	if err != nil {
+		respondChatError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, reply)
+}
+
+// handleGetDraft handles GET /chat/drafts/{id}?user=...
+func (api *API) handleGetDraft(w http.ResponseWriter, r *http.Request) {
+	if !api.requireChat(w, r) {
+		return
+	}
+	draft, err := api.Chat.Draft(chi.URLParam(r, "id"), currentUser(r, r.URL.Query().Get("user")))
+	if err != nil {
+		respondChatError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, draft)
+}
+
+// draftActionInput is the body for confirm/cancel
+type draftActionInput struct {
+	User string `json:"user"`
+}
+
+// handleConfirmDraft handles POST /chat/drafts/{id}/confirm
+// This is the ONLY way an assistant proposal reaches the database
+func (api *API) handleConfirmDraft(w http.ResponseWriter, r *http.Request) {
+	if !api.requireChat(w, r) {
+		return
+	}
+	var input draftActionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	user := currentUser(r, input.User)
+	draft, err := api.Chat.Draft(chi.URLParam(r, "id"), user)
+	if err != nil {
+		respondChatError(w, r, err)
+		return
+	}
+	if !allowMovement(w, r, draft.Movement.Type) {
+		return
+	}
+	movement, err := api.Chat.Confirm(draft.ID, user)
+	if err != nil {
+		respondChatError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, movement)
+}
+
+// handleCancelDraft handles POST /chat/drafts/{id}/cancel
+func (api *API) handleCancelDraft(w http.ResponseWriter, r *http.Request) {
+	if !api.requireChat(w, r) {
+		return
+	}
+	var input draftActionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if err := api.Chat.Cancel(chi.URLParam(r, "id"), currentUser(r, input.User)); err != nil {
+		respondChatError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
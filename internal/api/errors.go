@@ -0,0 +1,279 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/backup"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/catalog"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/chat"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/invoice"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/service"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
+)
+
+// ============================================
+// ERROR TRANSLATION
+// ============================================
+// Handlers hand errors from the store and the services to
+// respondDomainError, which finds the status with errors.Is: 404 for
+// what doesn't exist, 409 for clashes with what is stored, 422 for input
+// that fails validation (with the fields that failed) and 500 for
+// anything unknown, such as the database being down.
+
+// Errors about one field of the request body, for inputError
+var (
+	errRequired  = errors.New("is required")
+	errNegative  = errors.New("cannot be negative")
+	errReadOnly  = errors.New("cannot be changed")
+	errWrongType = errors.New("has the wrong type")
+)
+
+// inputError is a problem with one field of the request body that the
+// handler finds itself (before the store sees the input)
+type inputError struct {
+	Field string // JSON name: "minStock"
+	Err   error
+}
+
+func (e *inputError) Error() string { return e.Field + ": " + e.Err.Error() }
+func (e *inputError) Unwrap() error { return e.Err }
+
+// FieldError says why one input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field: "price"
+	Code    string `json:"code"`    // Message code: "product_invalid_price"
+	Message string `json:"message"` // In the caller's language
+}
+
+// errorRule maps a domain error to an HTTP answer
+type errorRule struct {
+	err    error
+	status int
+	kind   string // ErrorResponse.Error
+	code   string // ErrorResponse.Code; its catalog text is the message
+	field  string // Input field the error is about ("" = not about one field)
+}
+
+// errorRules are checked in order; the first rule whose error matches
+// (errors.Is) wins
+var errorRules = []errorRule{
+	// Missing
+	{repository.ErrProductNotFound, http.StatusNotFound, "not_found", "product_not_found", ""},
+	{repository.ErrStockNotFound, http.StatusNotFound, "not_found", "stock_not_found", ""},
+	{repository.ErrAliasNotFound, http.StatusNotFound, "not_found", "alias_not_found", ""},
+	{repository.ErrBarcodeNotFound, http.StatusNotFound, "not_found", "barcode_not_found", ""},
+	{repository.ErrSupplierSKUNotFound, http.StatusNotFound, "not_found", "supplier_sku_not_found", ""},
+	{repository.ErrMovementNotFound, http.StatusNotFound, "not_found", "movement_not_found", ""},
+	{repository.ErrCategoryNotFound, http.StatusNotFound, "not_found", "category_not_found", ""},
+	{repository.ErrUserNotFound, http.StatusNotFound, "not_found", "user_not_found", ""},
+	{repository.ErrAPIKeyNotFound, http.StatusNotFound, "not_found", "api_key_not_found", ""},
+	{repository.ErrStaffNotFound, http.StatusNotFound, "not_found", "staff_not_found", ""},
+	{invoice.ErrDraftNotFound, http.StatusNotFound, "not_found", "invoice_draft_not_found", ""},
+	{invoice.ErrUnknownLine, http.StatusNotFound, "not_found", "invoice_unknown_line", ""},
+	{chat.ErrSessionNotFound, http.StatusNotFound, "not_found", "chat_session_not_found", ""},
+	{chat.ErrDraftNotFound, http.StatusNotFound, "not_found", "chat_draft_not_found", ""},
+	{chat.ErrDraftExpired, http.StatusGone, "draft_expired", "chat_draft_expired", ""},
+
+	// Clashes with what is stored
+	{repository.ErrVersionConflict, http.StatusPreconditionFailed, "version_conflict", "version_conflict", ""},
+	{repository.ErrProductExists, http.StatusConflict, "product_exists", "product_exists", ""},
+	{repository.ErrProductActive, http.StatusConflict, "product_active", "product_active", ""},
+	{repository.ErrProductInUse, http.StatusConflict, "product_in_use", "product_in_use", ""},
+	{repository.ErrInvalidMerge, http.StatusConflict, "invalid_merge", "invalid_merge", ""},
+	{repository.ErrInsufficientStock, http.StatusConflict, "insufficient_stock", "insufficient_stock", ""},
+	{repository.ErrMovementExists, http.StatusConflict, "movement_exists", "movement_exists", ""},
+	{repository.ErrAliasExists, http.StatusConflict, "alias_exists", "alias_exists", ""},
+	{repository.ErrBarcodeExists, http.StatusConflict, "barcode_exists", "barcode_exists", ""},
+	{repository.ErrCategoryExists, http.StatusConflict, "category_exists", "category_exists", ""},
+	{repository.ErrCategoryInUse, http.StatusConflict, "category_in_use", "category_in_use", ""},
+	{repository.ErrUserExists, http.StatusConflict, "user_exists", "user_exists", ""},
+	{repository.ErrStaffUserLinked, http.StatusConflict, "user_linked", "staff_user_linked", ""},
+	{auth.ErrLastOwner, http.StatusConflict, "last_owner", "last_owner", ""},
+	{staff.ErrStaffInactive, http.StatusConflict, "staff_inactive", "staff_inactive", ""},
+	{repository.ErrProductInactive, http.StatusConflict, "product_inactive", "product_inactive", ""},
+	{invoice.ErrUnresolvedLines, http.StatusConflict, "unresolved_lines", "invoice_unresolved", ""},
+
+	// Logins and permissions
+	{auth.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials", "invalid_credentials", ""},
+	{auth.ErrInvalidToken, http.StatusUnauthorized, "invalid_token", "invalid_token", ""},
+	{auth.ErrAccountLocked, http.StatusLocked, "account_locked", "account_locked", ""},
+	{auth.ErrForbidden, http.StatusForbidden, "forbidden", "forbidden", ""},
+
+	// Query parameters
+	{repository.ErrInvalidProductQuery, http.StatusBadRequest, "invalid_query", "invalid_product_query", ""},
+	{repository.ErrInvalidCursor, http.StatusBadRequest, "invalid_query", "invalid_cursor", ""},
+	{catalog.ErrUnknownFormat, http.StatusBadRequest, "invalid_query", "catalog_unknown_format", "format"},
+	{service.ErrInvalidSyncToken, http.StatusBadRequest, "invalid_sync_token", "invalid_sync_token", "since"},
+	{service.ErrSyncBatchTooLarge, http.StatusRequestEntityTooLarge, "batch_too_large", "sync_batch_too_large", "movements"},
+
+	// Invalid input
+	{errRequired, http.StatusUnprocessableEntity, "validation_error", "field_required", ""},
+	{errNegative, http.StatusUnprocessableEntity, "validation_error", "field_negative", ""},
+	{errReadOnly, http.StatusUnprocessableEntity, "validation_error", "field_read_only", ""},
+	{errWrongType, http.StatusUnprocessableEntity, "validation_error", "field_wrong_type", ""},
+	{models.ErrProductNameRequired, http.StatusUnprocessableEntity, "validation_error", "product_name_required", "name"},
+	{models.ErrProductInvalidSize, http.StatusUnprocessableEntity, "validation_error", "product_invalid_size", "size"},
+	{models.ErrProductInvalidPrice, http.StatusUnprocessableEntity, "validation_error", "product_invalid_price", "price"},
+	{models.ErrProductInvalidCategory, http.StatusUnprocessableEntity, "validation_error", "product_invalid_category", "category"},
+	{models.ErrStockProductRequired, http.StatusUnprocessableEntity, "validation_error", "stock_product_required", "productId"},
+	{models.ErrStockNegative, http.StatusUnprocessableEntity, "validation_error", "stock_negative", "minStock"},
+	{models.ErrMovementInvalidType, http.StatusUnprocessableEntity, "validation_error", "movement_invalid_type", "type"},
+	{models.ErrMovementNoQuantity, http.StatusUnprocessableEntity, "validation_error", "movement_no_quantity", "units"},
+	{models.ErrMovementNoPerformer, http.StatusUnprocessableEntity, "validation_error", "movement_no_performer", "performedBy"},
+	{models.ErrMovementClientID, http.StatusUnprocessableEntity, "validation_error", "movement_client_id", "clientId"},
+	{models.ErrAliasRequired, http.StatusUnprocessableEntity, "validation_error", "alias_required", "alias"},
+	{models.ErrAliasTooLong, http.StatusUnprocessableEntity, "validation_error", "alias_too_long", "alias"},
+	{models.ErrAliasProductRequired, http.StatusUnprocessableEntity, "validation_error", "alias_product_required", "productId"},
+	{models.ErrBarcodeInvalid, http.StatusUnprocessableEntity, "invalid_barcode", "barcode_invalid", "code"},
+	{models.ErrBarcodeCheckDigit, http.StatusUnprocessableEntity, "invalid_barcode", "barcode_check_digit", "code"},
+	{models.ErrInvalidPackLevel, http.StatusUnprocessableEntity, "validation_error", "invalid_pack_level", "packLevel"},
+	{models.ErrBarcodeCaseNeedsBox, http.StatusUnprocessableEntity, "validation_error", "barcode_case_needs_box", "packLevel"},
+	{models.ErrSupplierRequired, http.StatusUnprocessableEntity, "validation_error", "supplier_required", "supplier"},
+	{models.ErrSKURequired, http.StatusUnprocessableEntity, "validation_error", "sku_required", "sku"},
+	{models.ErrSupplierSKUProductRequired, http.StatusUnprocessableEntity, "validation_error", "sku_product_required", "productId"},
+	{models.ErrCategoryInvalidID, http.StatusUnprocessableEntity, "validation_error", "category_invalid_id", "id"},
+	{models.ErrCategoryNameRequired, http.StatusUnprocessableEntity, "validation_error", "category_name_required", "nameHe"},
+	{models.ErrCategoryNameTooLong, http.StatusUnprocessableEntity, "validation_error", "category_name_too_long", "nameHe"},
+	{models.ErrCategoryMinStock, http.StatusUnprocessableEntity, "validation_error", "category_min_stock", "defaultMinStock"},
+	{models.ErrCategoryOwnParent, http.StatusUnprocessableEntity, "validation_error", "category_own_parent", "parentId"},
+	{repository.ErrCategoryParent, http.StatusUnprocessableEntity, "validation_error", "category_parent", "parentId"},
+	{models.ErrStaffNameRequired, http.StatusUnprocessableEntity, "validation_error", "staff_name_required", "nameHe"},
+	{models.ErrStaffNameTooLong, http.StatusUnprocessableEntity, "validation_error", "staff_name_too_long", "nameHe"},
+	{staff.ErrUnknownStaff, http.StatusUnprocessableEntity, "unknown_staff", "staff_unknown", ""},
+	{staff.ErrAmbiguousStaff, http.StatusUnprocessableEntity, "unknown_staff", "staff_ambiguous", ""},
+	{models.ErrUsernameInvalid, http.StatusUnprocessableEntity, "validation_error", "username_invalid", "username"},
+	{models.ErrInvalidRole, http.StatusUnprocessableEntity, "validation_error", "invalid_role", "role"},
+	{models.ErrInvalidLanguage, http.StatusUnprocessableEntity, "validation_error", "language_invalid", "language"},
+	{auth.ErrPasswordTooShort, http.StatusUnprocessableEntity, "validation_error", "password_too_short", "password"},
+	{auth.ErrPasswordTooLong, http.StatusUnprocessableEntity, "validation_error", "password_too_long", "password"},
+	{auth.ErrInvalidScope, http.StatusUnprocessableEntity, "validation_error", "invalid_scope", "scopes"},
+	{models.ErrAPIKeyNameRequired, http.StatusUnprocessableEntity, "validation_error", "api_key_name_required", "name"},
+	{models.ErrAPIKeyNameTooLong, http.StatusUnprocessableEntity, "validation_error", "api_key_name_too_long", "name"},
+	{models.ErrAPIKeyNoScopes, http.StatusUnprocessableEntity, "validation_error", "api_key_no_scopes", "scopes"},
+	{invoice.ErrUnknownFormat, http.StatusUnprocessableEntity, "validation_error", "invoice_unknown_format", "format"},
+	{invoice.ErrNoLines, http.StatusUnprocessableEntity, "validation_error", "invoice_no_lines", ""},
+	{invoice.ErrMissingColumn, http.StatusUnprocessableEntity, "validation_error", "invoice_missing_column", ""},
+	{invoice.ErrUserRequired, http.StatusUnprocessableEntity, "validation_error", "user_required", "user"},
+	{chat.ErrEmptyMessage, http.StatusUnprocessableEntity, "validation_error", "chat_empty_message", "message"},
+	{chat.ErrUserRequired, http.StatusUnprocessableEntity, "validation_error", "user_required", "user"},
+	{service.ErrInvalidPeriod, http.StatusUnprocessableEntity, "validation_error", "invalid_period", "to"},
+	{service.ErrNoUsageInput, http.StatusUnprocessableEntity, "validation_error", "no_usage_input", "items"},
+	{service.ErrNegativeUsage, http.StatusUnprocessableEntity, "validation_error", "negative_usage", "items"},
+	{service.ErrCountsMismatch, http.StatusUnprocessableEntity, "validation_error", "counts_mismatch", "items"},
+	{catalog.ErrNoRows, http.StatusUnprocessableEntity, "invalid_catalog", "catalog_no_rows", ""},
+	{catalog.ErrMissingColumn, http.StatusUnprocessableEntity, "invalid_catalog", "catalog_missing_column", ""},
+	{backup.ErrNotBackup, http.StatusUnprocessableEntity, "invalid_backup", "not_backup", ""},
+	{backup.ErrUnsupportedVersion, http.StatusUnprocessableEntity, "invalid_backup", "backup_unsupported", ""},
+	{backup.ErrChecksumMismatch, http.StatusUnprocessableEntity, "invalid_backup", "backup_checksum", ""},
+	{repository.ErrInvalidSnapshot, http.StatusUnprocessableEntity, "invalid_backup", "invalid_snapshot", ""},
+}
+
+// findErrorRule returns the rule for err; ok is false for errors no
+// rule knows
+func findErrorRule(err error) (rule errorRule, ok bool) {
+	var in *inputError
+	if errors.As(err, &in) {
+		rule, ok := matchErrorRule(in.Err)
+		if !ok {
+			rule = errorRule{err: in.Err, status: http.StatusUnprocessableEntity, kind: "validation_error"}
+		}
+		rule.field = in.Field
+		return rule, true
+	}
+	return matchErrorRule(err)
+}
+
+// matchErrorRule returns the first rule whose error err is (errors.Is)
+func matchErrorRule(err error) (errorRule, bool) {
+	for _, rule := range errorRules {
+		if errors.Is(err, rule.err) {
+			return rule, true
+		}
+	}
+	return errorRule{}, false
+}
+
+// localizeError returns the code and text of err in lang: by the code of
+// its rule, or by its English text for errors no rule knows
+func localizeError(lang string, err error) (code, msg string) {
+	rule, ok := findErrorRule(err)
+	if !ok || rule.code == "" {
+		return i18n.Localize(lang, err.Error())
+	}
+	return rule.code, i18n.LocalizeCode(lang, rule.code, rule.err.Error(), err.Error())
+}
+
+// leafErrors splits errors.Join results into the errors they hold
+func leafErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var leaves []error
+	for _, e := range joined.Unwrap() {
+		leaves = append(leaves, leafErrors(e)...)
+	}
+	return leaves
+}
+
+// respondDomainError answers with the status and kind the rules give
+// err. Every error about an input field is listed in Fields; join them
+// (errors.Join) to report several at once. Unknown errors are logged
+// and answered with a plain 500, without the details.
+func respondDomainError(w http.ResponseWriter, r *http.Request, err error) {
+	leaves := leafErrors(err)
+	first, ok := findErrorRule(leaves[0])
+	if !ok {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		respondError(w, r, http.StatusInternalServerError, "internal_error", i18n.T(i18n.English, "internal_error"))
+		return
+	}
+
+	lang := requestLanguage(r)
+	resp := ErrorResponse{Error: first.kind}
+	for _, leaf := range leaves {
+		rule, ok := findErrorRule(leaf)
+		if !ok || rule.field == "" {
+			continue
+		}
+		// The field only needs the rule's error and what follows it,
+		// not what the store wrapped around it ("validation failed: ")
+		text := leaf.Error()
+		if i := strings.Index(text, rule.err.Error()); i > 0 {
+			text = text[i:]
+		}
+		code, msg := rule.code, i18n.LocalizeCode(lang, rule.code, rule.err.Error(), text)
+		if code == "" {
+			code, msg = i18n.Localize(lang, text)
+		}
+		resp.Fields = append(resp.Fields, FieldError{Field: rule.field, Code: code, Message: msg})
+	}
+	if len(leaves) == 1 {
+		resp.Code, resp.Message = localizeError(lang, err)
+	} else {
+		resp.Code, resp.Message = "invalid_fields", i18n.T(lang, "invalid_fields")
+	}
+	if resp.Code == "" {
+		resp.Code = first.kind
+	}
+	w.Header().Set("Content-Language", lang)
+	respondJSON(w, first.status, resp)
+}
+
+// respondDomainErrorOr is respondDomainError for handlers whose unknown
+// errors are not server faults but, say, a parser's complaint about an
+// uploaded file: those get status and kind, with their text
+func respondDomainErrorOr(w http.ResponseWriter, r *http.Request, err error, status int, kind string) {
+	if _, ok := findErrorRule(leafErrors(err)[0]); !ok {
+		respondError(w, r, status, kind, err.Error())
+		return
+	}
+	respondDomainError(w, r, err)
+}
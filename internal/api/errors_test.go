@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// Every mapped error names its catalog code. The catalog's English text
+// must still be the error's own, or English answers would show stale text.
+func TestErrorRulesHaveCatalogCodes(t *testing.T) {
+	for _, rule := range errorRules {
+		en := i18n.T(i18n.English, rule.code)
+		switch {
+		case rule.code == "" || en == rule.code:
+			t.Errorf("%q (%s) has no catalog code", rule.err, rule.kind)
+		case en != rule.err.Error():
+			t.Errorf("%s: catalog says %q, the error %q", rule.code, en, rule.err)
+		}
+	}
+}
+
+// A reworded error keeps its code and translation: the rule has the code,
+// the text isn't looked up
+func TestRespondDomainErrorUsesRuleCode(t *testing.T) {
+	reworded := errors.New("price is below zero")
+	defer func(rules []errorRule) { errorRules = rules }(errorRules)
+	errorRules = append(errorRules, errorRule{reworded, http.StatusUnprocessableEntity, "validation_error", "product_invalid_price", "price"})
+
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	r.Header.Set("Accept-Language", "he")
+	w := httptest.NewRecorder()
+	respondDomainError(w, r, fmt.Errorf("validation failed: %w", reworded))
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Code != "product_invalid_price" || resp.Message != "האימות נכשל: מחיר המוצר לא יכול להיות שלילי" {
+		t.Errorf("got %s %q", resp.Code, resp.Message)
+	}
+	if len(resp.Fields) != 1 || resp.Fields[0].Code != "product_invalid_price" || resp.Fields[0].Message != "מחיר המוצר לא יכול להיות שלילי" {
+		t.Errorf("fields: got %+v", resp.Fields)
+	}
+}
+
+func TestRespondDomainError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		kind   string
+		code   string
+		fields []string
+	}{
+		{"wrapped not found", fmt.Errorf("merge: %w", fmt.Errorf("%w: PROD-001", repository.ErrProductNotFound)), http.StatusNotFound, "not_found", "product_not_found", nil},
+		{"unknown store error", fmt.Errorf("get product PROD-001: %w", errors.New("connection refused")), http.StatusInternalServerError, "internal_error", "internal_error", nil},
+		{"insufficient stock", fmt.Errorf("%w: have 1 boxes", repository.ErrInsufficientStock), http.StatusConflict, "insufficient_stock", "insufficient_stock", nil},
+		{"product exists", fmt.Errorf("%w: Coca-Cola 330 can", repository.ErrProductExists), http.StatusConflict, "product_exists", "product_exists", nil},
+		{"version conflict", fmt.Errorf("%w: PROD-001", repository.ErrVersionConflict), http.StatusPreconditionFailed, "version_conflict", "version_conflict", nil},
+		{"joined validation", validateProductInput("", 330, -1), http.StatusUnprocessableEntity, "validation_error", "invalid_fields", []string{"name", "price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			respondDomainError(w, httptest.NewRequest(http.MethodPost, "/api/v1/products", nil), tt.err)
+
+			if w.Code != tt.status {
+				t.Errorf("status: got %d, want %d", w.Code, tt.status)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Error != tt.kind || resp.Code != tt.code {
+				t.Errorf("got %s/%s, want %s/%s", resp.Error, resp.Code, tt.kind, tt.code)
+			}
+			var fields []string
+			for _, f := range resp.Fields {
+				if f.Code == "" {
+					t.Errorf("field %s has no code", f.Field)
+				}
+				fields = append(fields, f.Field)
+			}
+			if fmt.Sprint(fields) != fmt.Sprint(tt.fields) {
+				t.Errorf("fields: got %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
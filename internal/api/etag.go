@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ============================================
+// ETAGS: optimistic concurrency for products and stock
+// ============================================
+// The ETag is the version of the product (or stock row). Clients send it
+// back in If-Match on PUT/DELETE; if someone else changed the thing in
+// between, the store refuses with ErrVersionConflict and we answer 412.
+
+// etag formats a version as a strong ETag: "3"
+func etag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseETag reads one ETag (weak or strong) back into a version
+func parseETag(tag string) (int, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	return version, err == nil && version > 0
+}
+
+// ifMatchVersion returns the version a PUT/DELETE expects from If-Match.
+// A missing header gets 428 (so nobody overwrites blindly) and an ETag
+// that can't be ours gets 412. "*" means any version (0).
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		respondError(w, r, http.StatusPreconditionRequired, "precondition_required", "If-Match header with the ETag from GET is required")
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+	version, ok := parseETag(header)
+	if !ok {
+		respondError(w, r, http.StatusPreconditionFailed, "version_conflict", "If-Match must be a single ETag from GET")
+		return 0, false
+	}
+	return version, true
+}
+
+// notModified sets the ETag and answers 304 when If-None-Match already has it
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	w.Header().Set("ETag", etag(version))
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if v, ok := parseETag(tag); (ok && v == version) || strings.TrimSpace(tag) == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
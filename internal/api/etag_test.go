@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProductETags(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	path := "/products/" + id
+	body := `{"name": "קולה זירו", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks"}`
+
+	w := s.do("owner", http.MethodGet, path, "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET: got %d with ETag %s, want 200 with \"1\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := s.do("owner", http.MethodGet, path, "", "If-None-Match", `"1"`); w.Code != http.StatusNotModified {
+		t.Errorf("GET with current If-None-Match: got %d, want 304", w.Code)
+	}
+
+	if w := s.do("owner", http.MethodPut, path, body); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match: got %d, want 428", w.Code)
+	}
+	if w := s.do("owner", http.MethodDelete, path, ""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("DELETE without If-Match: got %d, want 428", w.Code)
+	}
+	if w := s.do("owner", http.MethodPut, path, body, "If-Match", "3"); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a malformed If-Match: got %d, want 412", w.Code)
+	}
+
+	w = s.do("owner", http.MethodPut, path, body, "If-Match", `"1"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT with current If-Match: got %d with ETag %s, want 200 with \"2\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := s.do("owner", http.MethodPut, path, body, "If-Match", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale If-Match: got %d, want 412", w.Code)
+	}
+	if w := s.do("owner", http.MethodDelete, path, "", "If-Match", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with a stale If-Match: got %d, want 412", w.Code)
+	}
+	if w := s.do("owner", http.MethodGet, path, "", "If-None-Match", `"1"`); w.Code != http.StatusOK {
+		t.Errorf("GET with an old If-None-Match: got %d, want 200", w.Code)
+	}
+}
+
+// Min stock is part of the stock row, so it is guarded by the stock's
+// ETag, not the product's
+func TestMinStockUsesStockETag(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"name": "קולה זירו", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks"}`
+	if w := s.do("owner", http.MethodPut, "/products/"+id, body, "If-Match", `"1"`); w.Code != http.StatusOK {
+		t.Fatalf("PUT product: got %d", w.Code)
+	}
+
+	w := s.do("owner", http.MethodGet, "/stock/"+id, "")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET stock: got %d with ETag %s, want 200 with \"1\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := s.do("owner", http.MethodGet, "/stock/"+id, "", "If-None-Match", `"1"`); w.Code != http.StatusNotModified {
+		t.Errorf("GET stock with current If-None-Match: got %d, want 304", w.Code)
+	}
+
+	path := "/products/" + id + "/min-stock"
+	if w := s.do("owner", http.MethodPut, path, `{"minStock": 48}`); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("min stock without If-Match: got %d, want 428", w.Code)
+	}
+	if w := s.do("owner", http.MethodPut, path, `{"minStock": 48}`, "If-Match", `"2"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("min stock with the product's ETag: got %d, want 412", w.Code)
+	}
+	w = s.do("owner", http.MethodPut, path, `{"minStock": 48}`, "If-Match", `"1"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("min stock with the stock's ETag: got %d with ETag %s, want 200 with \"2\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := s.do("owner", http.MethodPut, path, `{"minStock": 24}`, "If-Match", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("min stock with a stale ETag: got %d, want 412", w.Code)
+	}
+}
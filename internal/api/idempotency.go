@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// IDEMPOTENCY: safe retries of POST/PUT/DELETE
+// ============================================
+// A client that may retry (a phone on kitchen Wi-Fi, a POS queue) sends
+// "Idempotency-Key: <uuid>". The first response is stored for
+// api.IdempotencyTTL; a retry with the same key gets that response back
+// (with "Idempotent-Replayed: true") instead of running again.
+
+// DefaultIdempotencyTTL is how long a key is remembered unless configured
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyPurgeInterval limits how often expired keys are deleted
+const idempotencyPurgeInterval = time.Hour
+
+// maxIdempotentBodySize limits the body buffered to hash and replay a
+// request (10 MB, the largest catalog upload). Bigger requests, such as
+// a backup restore, must be sent without Idempotency-Key.
+const maxIdempotentBodySize = 10 << 20
+
+// replayedHeaders are the response headers stored with a key
+var replayedHeaders = []string{"Content-Type", "Content-Language", "ETag", "Location"}
+
+// idempotent replays the stored response for a repeated Idempotency-Key.
+// Keys are per caller (user or API key), so two users can't collide.
+// A 5xx answer isn't stored: the client may retry with the same key.
+// Must run after requireAuth.
+func (api *API) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" || api.IdempotencyTTL <= 0 || !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > models.MaxIdempotencyKeyLength {
+			respondError(w, r, http.StatusBadRequest, "validation_error", "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large for Idempotency-Key (at most 10 MB)")
+			return
+		}
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest, "invalid_body", "could not read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		api.purgeIdempotencyKeys(now)
+		rec := &models.IdempotencyRecord{
+			Scope:       idempotencyScope(r),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(api.IdempotencyTTL),
+		}
+		existing, err := api.Store.ReserveIdempotencyKey(rec)
+		if err != nil {
+			respondDomainError(w, r, err)
+			return
+		}
+		if existing != nil {
+			replayIdempotent(w, r, rec, existing)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		saved := false
+		defer func() {
+			if !saved {
+				if err := api.Store.ReleaseIdempotencyKey(rec.Scope, rec.Key); err != nil {
+					log.Printf("release idempotency key %q: %v", rec.Key, err)
+				}
+			}
+		}()
+		next.ServeHTTP(rw, r)
+
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+		rec.Status, rec.Body = rw.status, rw.body.Bytes()
+		rec.Headers = map[string]string{}
+		for _, h := range replayedHeaders {
+			if v := w.Header().Get(h); v != "" {
+				rec.Headers[h] = v
+			}
+		}
+		if err := api.Store.SaveIdempotentResponse(rec); err != nil {
+			log.Printf("save idempotency key %q: %v", rec.Key, err)
+			return
+		}
+		saved = true
+	})
+}
+
+// replayIdempotent answers a repeated key: the stored response, or an
+// error when the key is busy or was used for a different request
+func replayIdempotent(w http.ResponseWriter, r *http.Request, rec, existing *models.IdempotencyRecord) {
+	switch {
+	case existing.RequestHash != rec.RequestHash:
+		respondError(w, r, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+	case !existing.IsDone():
+		w.Header().Set("Retry-After", "1")
+		respondError(w, r, http.StatusConflict, "idempotency_in_progress", "a request with this Idempotency-Key is still running")
+	default:
+		for h, v := range existing.Headers {
+			w.Header().Set(h, v)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.Status)
+		w.Write(existing.Body)
+	}
+}
+
+// purgeIdempotencyKeys deletes expired keys, at most once per interval
+func (api *API) purgeIdempotencyKeys(now time.Time) {
+	last := api.idempotencyPurgedAt.Load()
+	if now.UnixNano()-last < int64(idempotencyPurgeInterval) ||
+		!api.idempotencyPurgedAt.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	if n := api.Store.PurgeIdempotencyKeys(now); n > 0 {
+		log.Printf("purged %d expired idempotency keys", n)
+	}
+}
+
+// isMutating reports whether a method changes something
+func isMutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut ||
+		method == http.MethodPatch || method == http.MethodDelete
+}
+
+// idempotencyScope returns whose keys a request uses: the user, the API
+// key, or nobody ("") with auth disabled
+func idempotencyScope(r *http.Request) string {
+	id, ok := auth.IdentityFrom(r.Context())
+	switch {
+	case !ok:
+		return ""
+	case id.IsAPIKey():
+		return "key:" + id.APIKeyID
+	default:
+		return id.UserID
+	}
+}
+
+// requestHash fingerprints a request, so a key reused for another
+// request is caught instead of answered with the wrong response
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter passes a response through and keeps a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
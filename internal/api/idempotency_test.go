@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// failingMovementStore fails RecordMovement while fail is set, like a
+// database that went away
+type failingMovementStore struct {
+	repository.Repository
+	fail bool
+}
+
+func (s *failingMovementStore) RecordMovement(m *models.StockMovement) (string, error) {
+	if s.fail {
+		return "", errors.New("connection refused")
+	}
+	return s.Repository.RecordMovement(m)
+}
+
+// movementsOf counts the recorded movements of a product
+func movementsOf(s *testServer, productID string) int {
+	return len(s.api.Store.ListMovements(repository.MovementFilter{ProductID: productID}))
+}
+
+func TestIdempotencyKeyReplaysFirstResponse(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"productId": "` + id + `", "type": "IN", "boxes": 2, "performedBy": "יוסף"}`
+
+	first := s.do("manager", http.MethodPost, "/movements", body, "Idempotency-Key", "key-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first POST: got %d, want 201: %s", first.Code, first.Body)
+	}
+	again := s.do("manager", http.MethodPost, "/movements", body, "Idempotency-Key", "key-1")
+	if again.Code != first.Code || again.Body.String() != first.Body.String() {
+		t.Errorf("replay: got %d %s, want %d %s", again.Code, again.Body, first.Code, first.Body)
+	}
+	if again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay must say Idempotent-Replayed: true")
+	}
+	if n := movementsOf(s, id); n != 1 {
+		t.Errorf("movements after a replay: got %d, want 1", n)
+	}
+
+	other := `{"productId": "` + id + `", "type": "IN", "boxes": 3, "performedBy": "יוסף"}`
+	if w := s.do("manager", http.MethodPost, "/movements", other, "Idempotency-Key", "key-1"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, different body: got %d, want 422", w.Code)
+	}
+	if n := movementsOf(s, id); n != 1 {
+		t.Errorf("movements after a reused key: got %d, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"productId": "` + id + `", "type": "IN", "boxes": 2, "performedBy": "יוסף"}`
+
+	// The first request is still running: its key is reserved, not done
+	user, err := s.api.Store.GetUserByUsername("manager")
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+	now := time.Now()
+	running := &models.IdempotencyRecord{
+		Scope:       user.ID,
+		Key:         "key-1",
+		RequestHash: requestHash(httptest.NewRequest(http.MethodPost, "/movements", nil), []byte(body)),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	if _, err := s.api.Store.ReserveIdempotencyKey(running); err != nil {
+		t.Fatalf("ReserveIdempotencyKey failed: %v", err)
+	}
+
+	w := s.do("manager", http.MethodPost, "/movements", body, "Idempotency-Key", "key-1")
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("key in flight: got %d (Retry-After %q), want 409 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	if n := movementsOf(s, id); n != 0 {
+		t.Errorf("movements while the key is in flight: got %d, want 0", n)
+	}
+}
+
+func TestIdempotencyKeyNotStoredOnServerError(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"productId": "` + id + `", "type": "IN", "boxes": 2, "performedBy": "יוסף"}`
+	store := &failingMovementStore{Repository: s.api.Store, fail: true}
+	s.api.Store = store
+
+	if w := s.do("manager", http.MethodPost, "/movements", body, "Idempotency-Key", "key-1"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("failing store: got %d, want 500", w.Code)
+	}
+	store.fail = false
+	w := s.do("manager", http.MethodPost, "/movements", body, "Idempotency-Key", "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a 500: got %d (replayed %q), want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if n := movementsOf(s, id); n != 1 {
+		t.Errorf("movements after the retry: got %d, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyBodyTooLarge(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"rows": "` + strings.Repeat("x", maxIdempotentBodySize) + `"}`
+	w := s.do("manager", http.MethodPost, "/products/import", body, "Idempotency-Key", "key-1")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body over 10 MB: got %d, want 413", w.Code)
+	}
+}
+
+func TestIdempotencyKeysArePerUser(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"productId": "` + id + `", "type": "IN", "boxes": 2, "performedBy": "יוסף"}`
+
+	for _, user := range []string{"owner", "manager"} {
+		w := s.do(user, http.MethodPost, "/movements", body, "Idempotency-Key", "key-1")
+		if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("%s: got %d (replayed %q), want a fresh 201", user, w.Code, w.Header().Get("Idempotent-Replayed"))
+		}
+	}
+	if n := movementsOf(s, id); n != 2 {
+		t.Errorf("movements of two users with the same key: got %d, want 2", n)
+	}
+}
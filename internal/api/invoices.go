@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/invoice"
+)
+
+// maxInvoiceSize limits uploaded invoice files
+const maxInvoiceSize = 5 << 20 // 5 MB
+
+// respondInvoiceError answers invoice errors; errors the rules don't
+// know are the parser's complaints about the file (422)
+func respondInvoiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(w, r, http.StatusRequestEntityTooLarge, "file_too_large", "invoice file is larger than 5 MB")
+		return
+	}
+	respondDomainErrorOr(w, r, err, http.StatusUnprocessableEntity, "invoice_error")
+}
+
+// handleIngestInvoice handles POST /invoices?format=csv|text&supplier=...&number=...&user=...
+// The body is the raw file (CSV export, or text extracted from a PDF)
+func (api *API) handleIngestInvoice(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	body := http.MaxBytesReader(w, r.Body, maxInvoiceSize)
+	draft, err := api.Invoices.Ingest(format, q.Get("supplier"), q.Get("number"), body, currentUser(r, q.Get("user")))
+	if err != nil {
+		respondInvoiceError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, draft)
+}
+
+// handleListInvoiceDrafts handles GET /invoices/drafts
+func (api *API) handleListInvoiceDrafts(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, api.Invoices.Drafts())
+}
+
+// handleGetInvoiceDraft handles GET /invoices/drafts/{id}
+func (api *API) handleGetInvoiceDraft(w http.ResponseWriter, r *http.Request) {
+	draft, err := api.Invoices.Draft(chi.URLParam(r, "id"))
+	if err != nil {
+		respondInvoiceError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, draft)
+}
+
+// handleApproveInvoice handles POST /invoices/drafts/{id}/approve
+// Body: {"approvedBy": "...", "lines": [{"number": 3, "productId": "PROD-007"}, {"number": 5, "skip": true}]}
+func (api *API) handleApproveInvoice(w http.ResponseWriter, r *http.Request) {
+	var input invoice.Approval
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	input.ApprovedBy = currentUser(r, input.ApprovedBy)
+	result, err := api.Invoices.Approve(chi.URLParam(r, "id"), input)
+	if err != nil {
+		respondInvoiceError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// handleRejectInvoice handles POST /invoices/drafts/{id}/reject
+func (api *API) handleRejectInvoice(w http.ResponseWriter, r *http.Request) {
+	if err := api.Invoices.Reject(chi.URLParam(r, "id")); err != nil {
+		respondInvoiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
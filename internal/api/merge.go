@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// MergeRequest is the body of POST /products/{id}/merge
+type MergeRequest struct {
+	From string `json:"from"` // The duplicate to fold into {id}
+}
+
+// MergeResponse says what a merge moved over
+type MergeResponse struct {
+	Product      *models.Product `json:"product"`
+	Stock        *models.Stock   `json:"stock"`
+	Movements    int             `json:"movements"`
+	Aliases      int             `json:"aliases"`
+	Barcodes     int             `json:"barcodes"`
+	SupplierSKUs int             `json:"supplierSkus"`
+}
+
+// handleMergeProduct handles POST /products/{id}/merge
+// Folds the duplicate named in the body into {id} and archives it
+func (api *API) handleMergeProduct(w http.ResponseWriter, r *http.Request) {
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.From) == "" {
+		respondDomainError(w, r, &inputError{Field: "from", Err: errRequired})
+		return
+	}
+
+	res, err := api.storeFor(r).MergeProducts(strings.TrimSpace(req.From), chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(res.Product.Version))
+	respondJSON(w, http.StatusOK, MergeResponse{
+		Product:      res.Product,
+		Stock:        res.Stock,
+		Movements:    res.Movements,
+		Aliases:      res.Aliases,
+		Barcodes:     res.Barcodes,
+		SupplierSKUs: res.SupplierSKUs,
+	})
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
+)
+
+// movementInput is the JSON body for POST /movements
+type movementInput struct {
+	ProductID   string `json:"productId"`
+	Type        string `json:"type"`
+	Boxes       int    `json:"boxes"`
+	Units       int    `json:"units"`
+	PerformedBy string `json:"performedBy"`
+	ReportedBy  string `json:"reportedBy"`
+	Reason      string `json:"reason"`
+
+	// Staff directory IDs; take precedence over the names
+	PerformedByID string `json:"performedById"`
+	ReportedByID  string `json:"reportedById"`
+}
+
+// handleCreateMovement handles POST /movements
+// Records the movement and updates stock in one step
+func (api *API) handleCreateMovement(w http.ResponseWriter, r *http.Request) {
+	var input movementInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if user := currentUser(r, ""); user != "" {
+		// Logged in: the reporter is the user, whatever the body says
+		input.ReportedBy, input.ReportedByID = user, ""
+	}
+	if input.PerformedBy == "" && input.PerformedByID != "" {
+		st, err := api.Store.GetStaff(input.PerformedByID)
+		if err != nil {
+			respondDomainError(w, r, fmt.Errorf("%w: %s", staff.ErrUnknownStaff, input.PerformedByID))
+			return
+		}
+		input.PerformedBy = st.DisplayName()
+	}
+	m, err := models.NewStockMovement(input.ProductID, input.Type, input.Boxes, input.Units, input.PerformedBy, input.ReportedBy, input.Reason)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	m.PerformedByID, m.ReportedByID = input.PerformedByID, input.ReportedByID
+	if !allowMovement(w, r, m.Type) {
+		return
+	}
+	if _, err := api.Store.RecordMovement(m); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, m)
+}
+
+// handleListMovements handles GET /movements
+// Optional query params: productId, type, staffId, from, to (RFC 3339)
+func (api *API) handleListMovements(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.MovementFilter{
+		ProductID: q.Get("productId"),
+		Type:      q.Get("type"),
+		StaffID:   q.Get("staffId"),
+	}
+	var err error
+	if filter.From, err = parseTimeParam(q.Get("from")); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_query", "from must be an RFC 3339 timestamp")
+		return
+	}
+	if filter.To, err = parseTimeParam(q.Get("to")); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_query", "to must be an RFC 3339 timestamp")
+		return
+	}
+	respondJSON(w, http.StatusOK, api.Store.ListMovements(filter))
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter
+// An empty value returns the zero time (meaning "no filter")
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
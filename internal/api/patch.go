@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/auth"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// PATCH: change some fields of a product
+// ============================================
+// PATCH /products/{id} takes a JSON Merge Patch (RFC 7386): an object with
+// only the fields to change. {"price": 7.5} changes the price and nothing
+// else. null clears a field (back to "", 0 or false), so {"brand": null}
+// drops the brand while {"name": null} fails validation like an empty name.
+
+// mergePatchType is the media type of RFC 7386; plain JSON is accepted too
+const mergePatchType = "application/merge-patch+json"
+
+// errChangeActive is why a caller may not archive or restore a product
+// by changing isActive (in a PUT, PATCH or catalog import)
+var errChangeActive = errors.New("changing isActive needs permission to delete products")
+
+// mayChangeActive returns errChangeActive unless the caller may delete
+// and restore products
+func mayChangeActive(r *http.Request) error {
+	if id, ok := auth.IdentityFrom(r.Context()); ok && !id.Can(auth.PermDeleteProducts) {
+		return errChangeActive
+	}
+	return nil
+}
+
+// productPatchFields are the fields a patch may set, by JSON name
+var productPatchFields = map[string]func(p *models.Product) interface{}{
+	"name":          func(p *models.Product) interface{} { return &p.Name },
+	"brand":         func(p *models.Product) interface{} { return &p.Brand },
+	"size":          func(p *models.Product) interface{} { return &p.Size },
+	"containerType": func(p *models.Product) interface{} { return &p.ContainerType },
+	"boxSize":       func(p *models.Product) interface{} { return &p.BoxSize },
+	"price":         func(p *models.Product) interface{} { return &p.Price },
+	"category":      func(p *models.Product) interface{} { return &p.Category },
+	"isActive":      func(p *models.Product) interface{} { return &p.IsActive },
+}
+
+// applyProductPatch merges a patch into p. Fields that aren't known or
+// don't hold the right type are all reported (errors.Join, by name); p
+// is half patched then, so patch a copy.
+func applyProductPatch(p *models.Product, patch map[string]json.RawMessage) error {
+	names := make([]string, 0, len(patch))
+	for name := range patch {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		raw := patch[name]
+		field, ok := productPatchFields[name]
+		if !ok {
+			errs = append(errs, &inputError{Field: name, Err: errReadOnly})
+			continue
+		}
+		target := field(p)
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			switch v := target.(type) {
+			case *string:
+				*v = ""
+			case *int:
+				*v = 0
+			case *float64:
+				*v = 0
+			case *bool:
+				*v = false
+			}
+			continue
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			errs = append(errs, &inputError{Field: name, Err: errWrongType})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handlePatchProduct handles PATCH /products/{id} (If-Match required)
+func (api *API) handlePatchProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		if mediaType != mergePatchType && mediaType != "application/json" {
+			respondError(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type",
+				"Send a JSON Merge Patch ("+mergePatchType+")")
+			return
+		}
+	}
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be a JSON object")
+		return
+	}
+
+	current, err := api.Store.GetProduct(id)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	product := *current
+	if err := applyProductPatch(&product, patch); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	product.Version = version
+	api.saveProduct(w, r, current, &product)
+}
+
+// saveProduct validates and stores a changed product (PUT and PATCH) and
+// answers with it. Turning a product on or off is deleting or restoring
+// it, so that takes the delete permission too.
+func (api *API) saveProduct(w http.ResponseWriter, r *http.Request, current, product *models.Product) {
+	if product.IsActive != current.IsActive {
+		if err := mayChangeActive(r); err != nil {
+			respondError(w, r, http.StatusForbidden, "forbidden", err.Error())
+			return
+		}
+	}
+	if err := product.Validate(api.Store); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	if err := api.storeFor(r).UpdateProduct(product); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(product.Version))
+	respondJSON(w, http.StatusOK, product)
+}
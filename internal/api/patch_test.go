@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// product fetches a product straight from the store
+func (s *testServer) product(id string) *models.Product {
+	s.t.Helper()
+	p, err := s.api.Store.GetProduct(id)
+	if err != nil {
+		s.t.Fatalf("GetProduct failed: %v", err)
+	}
+	return p
+}
+
+// A merge patch is built from the product as GET returns it, so every
+// patchable field must come back under the name the patch takes
+func TestProductJSONMatchesPatchKeys(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+
+	w := s.do("owner", http.MethodGet, "/products/"+id, "")
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for name := range productPatchFields {
+		if _, ok := doc[name]; !ok {
+			t.Errorf("GET has no %q; got %v", name, doc)
+		}
+	}
+
+	patch := `{"name": ` + string(doc["name"]) + `, "price": 6}`
+	if w := s.do("owner", http.MethodPatch, "/products/"+id, patch, "If-Match", w.Header().Get("ETag")); w.Code != http.StatusOK {
+		t.Errorf("patch with GET's keys: got %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+// PUT without isActive used to turn products off; it must keep the state
+func TestPutKeepsIsActiveWhenLeftOut(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	body := `{"name": "קולה זירו", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks"}`
+
+	if w := s.do("manager", http.MethodPut, "/products/"+id, body, "If-Match", `"1"`); w.Code != http.StatusOK {
+		t.Fatalf("PUT: got %d, want 200: %s", w.Code, w.Body)
+	}
+	if p := s.product(id); !p.IsActive || p.Name != "קולה זירו" {
+		t.Errorf("active product after PUT: got %+v", p)
+	}
+
+	if w := s.do("owner", http.MethodDelete, "/products/"+id, "", "If-Match", `"2"`); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d, want 204: %s", w.Code, w.Body)
+	}
+	version := s.product(id).Version
+	if w := s.do("manager", http.MethodPut, "/products/"+id, body, "If-Match", etag(version)); w.Code != http.StatusOK {
+		t.Fatalf("PUT on an archived product: got %d, want 200: %s", w.Code, w.Body)
+	}
+	if p := s.product(id); p.IsActive {
+		t.Errorf("PUT without isActive restored an archived product")
+	}
+}
+
+func TestPatchProduct(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	path := "/products/" + id
+
+	w := s.do("manager", http.MethodPatch, path, `{"price": 7.5}`, "If-Match", `"1"`, "Content-Type", mergePatchType)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH price: got %d, want 200: %s", w.Code, w.Body)
+	}
+	p := s.product(id)
+	if p.Price != 7.5 || p.Name != "קולה" || p.Brand != "Coca-Cola" || p.BoxSize != 24 || !p.IsActive || p.Version != 2 {
+		t.Errorf("PATCH must change only the price: got %+v", p)
+	}
+
+	if w := s.do("manager", http.MethodPatch, path, `{"brand": null}`, "If-Match", `"2"`); w.Code != http.StatusOK {
+		t.Fatalf("PATCH brand null: got %d, want 200: %s", w.Code, w.Body)
+	}
+	if p := s.product(id); p.Brand != "" || p.Price != 7.5 {
+		t.Errorf("null must clear the brand and nothing else: got %+v", p)
+	}
+
+	if w := s.do("manager", http.MethodPatch, path, `{"price": 8}`, "If-Match", `"2"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with a stale If-Match: got %d, want 412", w.Code)
+	}
+	if w := s.do("manager", http.MethodPatch, path, `{"price": 8}`); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("PATCH without If-Match: got %d, want 428", w.Code)
+	}
+	if w := s.do("manager", http.MethodPatch, path, `{"price": 8}`, "If-Match", `"3"`, "Content-Type", "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("PATCH as text/plain: got %d, want 415", w.Code)
+	}
+	if p := s.product(id); p.Price != 7.5 || p.Version != 3 {
+		t.Errorf("refused patches changed the product: got %+v", p)
+	}
+}
+
+func TestPatchProductReportsBadFields(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+
+	w := s.do("manager", http.MethodPatch, "/products/"+id, `{"color": "red", "price": "cheap", "version": 7, "name": "קולה זירו"}`, "If-Match", `"1"`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bad fields: got %d, want 422: %s", w.Code, w.Body)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var fields []string
+	for _, f := range resp.Fields {
+		fields = append(fields, f.Field+": "+f.Message)
+	}
+	want := []string{"color: cannot be changed", "price: has the wrong type", "version: cannot be changed"}
+	if len(fields) != len(want) {
+		t.Fatalf("fields: got %v, want %v", fields, want)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("field %d: got %q, want %q", i, fields[i], want[i])
+		}
+	}
+	if p := s.product(id); p.Name != "קולה" || p.Version != 1 {
+		t.Errorf("a refused patch changed the product: got %+v", p)
+	}
+}
+
+// Turning a product off or on is deleting or restoring it
+func TestChangingIsActiveNeedsDeletePermission(t *testing.T) {
+	s := newTestServer(t)
+	id := s.addProduct("קולה", 330)
+	path := "/products/" + id
+	put := `{"name": "קולה", "brand": "Coca-Cola", "size": 330, "containerType": "can", "boxSize": 24, "price": 5, "category": "drinks", "isActive": false}`
+
+	if w := s.do("manager", http.MethodPatch, path, `{"isActive": false}`, "If-Match", `"1"`); w.Code != http.StatusForbidden {
+		t.Errorf("manager PATCH isActive: got %d, want 403", w.Code)
+	}
+	if w := s.do("manager", http.MethodPut, path, put, "If-Match", `"1"`); w.Code != http.StatusForbidden {
+		t.Errorf("manager PUT isActive: got %d, want 403", w.Code)
+	}
+	if !s.product(id).IsActive {
+		t.Fatalf("a refused request archived the product")
+	}
+	if w := s.do("owner", http.MethodPatch, path, `{"isActive": false}`, "If-Match", `"1"`); w.Code != http.StatusOK {
+		t.Errorf("owner PATCH isActive: got %d, want 200: %s", w.Code, w.Body)
+	}
+	if s.product(id).IsActive {
+		t.Errorf("owner's patch should archive the product")
+	}
+}
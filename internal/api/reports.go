@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/service"
+)
+
+// varianceColumns are the message codes of the report line fields, by
+// JSON name
+var varianceColumns = map[string]string{
+	"productId":        "report_product_id",
+	"productName":      "report_product_name",
+	"theoreticalUnits": "report_theoretical_units",
+	"actualUnits":      "report_actual_units",
+	"actualSource":     "report_actual_source",
+	"receivedUnits":    "report_received_units",
+	"outUnits":         "report_out_units",
+	"wasteUnits":       "report_waste_units",
+	"varianceUnits":    "report_variance_units",
+	"variancePercent":  "report_variance_percent",
+	"varianceValue":    "report_variance_value",
+	"flagged":          "report_flagged",
+}
+
+// varianceResponse is the report plus column titles in the caller's
+// language, for screens and printouts that show it as a table
+type varianceResponse struct {
+	*service.VarianceReport
+	Language string            `json:"language"`
+	Headers  map[string]string `json:"headers"` // Line field → column title
+}
+
+// handleVarianceReport handles POST /reports/variance
+// Body: period, theoretical usage per product and optional counts
+func (api *API) handleVarianceReport(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		From       time.Time                  `json:"from"`
+		To         time.Time                  `json:"to"`
+		Items      []service.UsageInput       `json:"items"`
+		Thresholds service.VarianceThresholds `json:"thresholds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	report, err := api.Variance.Report(input.From, input.To, input.Items, input.Thresholds)
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	lang := requestLanguage(r)
+	headers := make(map[string]string, len(varianceColumns))
+	for field, code := range varianceColumns {
+		headers[field] = i18n.T(lang, code)
+	}
+	w.Header().Set("Content-Language", lang)
+	respondJSON(w, http.StatusOK, varianceResponse{VarianceReport: report, Language: lang, Headers: headers})
+}
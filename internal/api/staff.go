@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
+)
+
+// staffInput is the JSON body for POST /staff and PUT /staff/{id}
+type staffInput struct {
+	NameHe   string `json:"nameHe"`
+	NameEn   string `json:"nameEn"`
+	UserID   string `json:"userId"`             // Optional login account
+	IsActive *bool  `json:"isActive,omitempty"` // PUT only; default true
+}
+
+// handleListStaff handles GET /staff (?all=true includes inactive staff)
+func (api *API) handleListStaff(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "true"
+	result := []*models.Staff{}
+	for _, st := range api.Store.ListStaff() {
+		if all || st.IsActive {
+			result = append(result, st)
+		}
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// handleMatchStaff handles GET /staff/match?name=...
+// Shows who a free-text name would be linked to, best first
+func (api *API) handleMatchStaff(w http.ResponseWriter, r *http.Request) {
+	matches := api.Staff.Match(r.URL.Query().Get("name"))
+	if matches == nil {
+		matches = []staff.Match{}
+	}
+	respondJSON(w, http.StatusOK, matches)
+}
+
+// handleGetStaff handles GET /staff/{id}
+func (api *API) handleGetStaff(w http.ResponseWriter, r *http.Request) {
+	st, err := api.Store.GetStaff(chi.URLParam(r, "id"))
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, st)
+}
+
+// handleCreateStaff handles POST /staff
+// Body: {"nameHe": "יוסף", "nameEn": "Yosef", "userId": ""}
+func (api *API) handleCreateStaff(w http.ResponseWriter, r *http.Request) {
+	var input staffInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	st := &models.Staff{NameHe: input.NameHe, NameEn: input.NameEn, UserID: input.UserID, IsActive: true}
+	if _, err := api.Store.AddStaff(st); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, st)
+}
+
+// handleUpdateStaff handles PUT /staff/{id}
+// Deactivate people who left with "isActive": false - history keeps them
+func (api *API) handleUpdateStaff(w http.ResponseWriter, r *http.Request) {
+	var input staffInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	st := &models.Staff{ID: chi.URLParam(r, "id"), NameHe: input.NameHe, NameEn: input.NameEn, UserID: input.UserID, IsActive: true}
+	if input.IsActive != nil {
+		st.IsActive = *input.IsActive
+	}
+	if err := api.Store.UpdateStaff(st); err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, st)
+}
+
+// handleBackfillStaff handles POST /staff/backfill?dryRun=true
+// Links movements logged with free-text names to staff members
+func (api *API) handleBackfillStaff(w http.ResponseWriter, r *http.Request) {
+	report, err := api.Staff.Backfill(r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
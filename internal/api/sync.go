@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/service"
+)
+
+// handleSync handles POST /sync
+// Applies a batch of movements logged offline and returns what changed
+// since the device's last sync token
+func (api *API) handleSync(w http.ResponseWriter, r *http.Request) {
+	var req service.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, http.StatusBadRequest, "invalid_json", "Request body must be valid JSON")
+		return
+	}
+	if user := currentUser(r, ""); user != "" {
+		// Logged in: the reporter is the user, whatever the batch says
+		for i := range req.Movements {
+			req.Movements[i].ReportedBy, req.Movements[i].ReportedByID = user, ""
+		}
+	}
+
+	resp, err := api.Sync.Sync(req, func(m *models.StockMovement) error {
+		return movementForbidden(r, m.Type)
+	})
+	if err != nil {
+		respondDomainError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// ============================================
+// API KEYS: machine access with scopes
+// ============================================
+// A key looks like "rik_KEY-1a2b3c.<secret>" and is sent as
+// "Authorization: Bearer <key>" or "X-API-Key: <key>". It has no role;
+// it may do exactly what its scopes allow.
+
+// ErrInvalidScope is returned for a scope that doesn't exist or can't be given to a key
+var ErrInvalidScope = errors.New("invalid API key scope")
+
+// APIKeyPrefix starts every API key, so it can't be mistaken for a JWT
+const APIKeyPrefix = "rik_"
+
+// lastUsedInterval limits how often LastUsedAt is written for a busy key
+const lastUsedInterval = time.Minute
+
+// Movement scopes: which movement types a key may record
+const (
+	ScopeMovementsIn         = "movements:in"
+	ScopeMovementsOut        = "movements:out"
+	ScopeMovementsWaste      = "movements:waste"
+	ScopeMovementsAdjustment = "movements:adjustment"
+)
+
+// movementScopes maps movement types to their scope
+var movementScopes = map[string]string{
+	models.MovementIn:         ScopeMovementsIn,
+	models.MovementOut:        ScopeMovementsOut,
+	models.MovementWaste:      ScopeMovementsWaste,
+	models.MovementAdjustment: ScopeMovementsAdjustment,
+}
+
+// keyScopes lists the scopes a key can get. Managing users, keys and
+// backups, and purging products, is left to people: a leaked key can't
+// mint new keys or owners, download every password hash or erase products.
+var keyScopes = map[string]bool{
+	string(PermViewInventory):   true,
+	string(PermEditProducts):    true,
+	string(PermDeleteProducts):  true,
+	string(PermSetMinStock):     true,
+	string(PermReceiveInvoices): true,
+	string(PermViewReports):     true,
+	string(PermViewAudit):       true,
+	string(PermManageStaff):     true,
+	ScopeMovementsIn:            true,
+	ScopeMovementsOut:           true,
+	ScopeMovementsWaste:         true,
+	ScopeMovementsAdjustment:    true,
+}
+
+// KeyScopes returns the scopes a key can get, sorted
+func KeyScopes() []string {
+	scopes := make([]string, 0, len(keyScopes))
+	for scope := range keyScopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// APIKeyInfo is a key as shown by the API (never the hash)
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"` // null = never used
+	RevokedAt  *time.Time `json:"revokedAt"`  // null = active
+}
+
+// NewAPIKey is the answer to creating a key; Key is shown only this once
+type NewAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// PublicAPIKey converts a stored key to its API view
+func PublicAPIKey(k *models.APIKey) APIKeyInfo {
+	info := APIKeyInfo{ID: k.ID, Name: k.Name, Scopes: k.Scopes, CreatedBy: k.CreatedBy, CreatedAt: k.CreatedAt}
+	if !k.LastUsedAt.IsZero() {
+		info.LastUsedAt = &k.LastUsedAt
+	}
+	if !k.RevokedAt.IsZero() {
+		info.RevokedAt = &k.RevokedAt
+	}
+	return info
+}
+
+// CreateAPIKey creates a key with the given scopes for a machine
+func (s *Service) CreateAPIKey(name string, scopes []string, createdBy string) (*NewAPIKey, error) {
+	seen := map[string]bool{}
+	var clean []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !keyScopes[scope] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			clean = append(clean, scope)
+		}
+	}
+	sort.Strings(clean)
+
+	id, secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	k := &models.APIKey{
+		ID:        id,
+		Name:      name,
+		KeyHash:   hashToken(secret),
+		Scopes:    clean,
+		CreatedBy: createdBy,
+		CreatedAt: s.now(),
+	}
+	if err := s.store.CreateAPIKey(k); err != nil {
+		return nil, err
+	}
+	return &NewAPIKey{APIKeyInfo: PublicAPIKey(k), Key: APIKeyPrefix + id + "." + secret}, nil
+}
+
+// RevokeAPIKey stops a key from working; revoking twice is a no-op
+func (s *Service) RevokeAPIKey(id string) (*models.APIKey, error) {
+	k, err := s.store.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if k.IsActive() {
+		k.RevokedAt = s.now()
+		if err := s.store.UpdateAPIKey(k); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// authenticateAPIKey checks an API key and returns its identity
+func (s *Service) authenticateAPIKey(key string) (*Identity, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidToken
+	}
+	k, err := s.store.GetAPIKey(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !k.IsActive() || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(k.KeyHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	now := s.now()
+	if now.Sub(k.LastUsedAt) >= lastUsedInterval {
+		if err := s.store.TouchAPIKey(k.ID, now); err != nil {
+			// Revoked since it was read
+			if errors.Is(err, repository.ErrAPIKeyNotFound) {
+				return nil, ErrInvalidToken
+			}
+			return nil, err
+		}
+	}
+
+	scopes := make(map[string]bool, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		scopes[scope] = true
+	}
+	return &Identity{DisplayName: k.Name, APIKeyID: k.ID, Scopes: scopes}, nil
+}
+
+// newAPIKeySecret returns a random key ID and secret
+func newAPIKeySecret() (id, secret string, err error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	s := make([]byte, 32)
+	if _, err := rand.Read(s); err != nil {
+		return "", "", err
+	}
+	return "KEY-" + hex.EncodeToString(b), base64.RawURLEncoding.EncodeToString(s), nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	if _, err := s.CreateAPIKey("POS", []string{"users:manage"}, ""); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("users:manage: expected ErrInvalidScope, got %v", err)
+	}
+	if _, err := s.CreateAPIKey("POS", []string{"stock:fly"}, ""); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("unknown scope: expected ErrInvalidScope, got %v", err)
+	}
+	if _, err := s.CreateAPIKey("POS", nil, ""); !errors.Is(err, models.ErrAPIKeyNoScopes) {
+		t.Errorf("no scopes: expected ErrAPIKeyNoScopes, got %v", err)
+	}
+	if _, err := s.CreateAPIKey(" ", []string{ScopeMovementsOut}, ""); !errors.Is(err, models.ErrAPIKeyNameRequired) {
+		t.Errorf("no name: expected ErrAPIKeyNameRequired, got %v", err)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	s, _ := newTestService(t)
+	key, err := s.CreateAPIKey("POS", []string{"movements:out", " Inventory:View ", "movements:out"}, "")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(key.Key, APIKeyPrefix) || len(key.Scopes) != 2 {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+	stored, _ := s.store.GetAPIKey(key.ID)
+	if strings.Contains(key.Key, stored.KeyHash) {
+		t.Errorf("key hash must not be part of the key")
+	}
+
+	id, err := s.Authenticate(key.Key)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if !id.IsAPIKey() || id.UserID != "" || id.DisplayName != "POS" {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+	if !id.Can(PermViewInventory) || id.Can(PermEditProducts) || id.Can(PermManageUsers) {
+		t.Errorf("permissions don't follow scopes: %+v", id.Scopes)
+	}
+	if !id.CanRecordMovement(models.MovementOut) || id.CanRecordMovement(models.MovementIn) {
+		t.Errorf("movement types don't follow scopes: %+v", id.Scopes)
+	}
+
+	if _, err := s.Authenticate(key.Key + "x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tampered key: expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := s.Authenticate(APIKeyPrefix + "KEY-missing.secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown key: expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestAPIKeyLastUsedAndRevoke(t *testing.T) {
+	s, now := newTestService(t)
+	key, err := s.CreateAPIKey("bar tablet", []string{ScopeMovementsOut}, "")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if key.LastUsedAt != nil {
+		t.Errorf("new key already used: %v", key.LastUsedAt)
+	}
+
+	first := *now
+	if _, err := s.Authenticate(key.Key); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	*now = now.Add(10 * time.Second)
+	if _, err := s.Authenticate(key.Key); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if k, _ := s.store.GetAPIKey(key.ID); !k.LastUsedAt.Equal(first) {
+		t.Errorf("LastUsedAt = %v, want %v (written at most once a minute)", k.LastUsedAt, first)
+	}
+	*now = now.Add(time.Minute)
+	s.Authenticate(key.Key)
+	if k, _ := s.store.GetAPIKey(key.ID); !k.LastUsedAt.Equal(*now) {
+		t.Errorf("LastUsedAt = %v, want %v", k.LastUsedAt, *now)
+	}
+
+	if _, err := s.RevokeAPIKey(key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, err := s.Authenticate(key.Key); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("revoked key: expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// revokingStore revokes every key right after handing out a copy of it,
+// like an owner revoking the key while a request is being checked
+type revokingStore struct {
+	*repository.MemoryStore
+}
+
+func (s revokingStore) GetAPIKey(id string) (*models.APIKey, error) {
+	k, err := s.MemoryStore.GetAPIKey(id)
+	if err == nil {
+		revoked := *k
+		revoked.RevokedAt = time.Now()
+		s.MemoryStore.UpdateAPIKey(&revoked)
+	}
+	return k, err
+}
+
+func TestAPIKeyRevokedDuringAuthenticateStaysRevoked(t *testing.T) {
+	s, now := newTestService(t)
+	key, err := s.CreateAPIKey("bar tablet", []string{ScopeMovementsOut}, "")
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, err := s.Authenticate(key.Key); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	// The next check reads the key, then the revoke lands before the
+	// last-used time is written
+	memory := s.store.(*repository.MemoryStore)
+	s.store = revokingStore{memory}
+	*now = now.Add(time.Minute)
+	if _, err := s.Authenticate(key.Key); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+
+	s.store = memory
+	if k, _ := memory.GetAPIKey(key.ID); k.IsActive() {
+		t.Fatalf("revoke was undone: %+v", k)
+	}
+	if _, err := s.Authenticate(key.Key); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("revoked key: expected ErrInvalidToken, got %v", err)
+	}
+}
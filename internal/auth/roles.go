@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// PERMISSIONS: what each role may do
+// ============================================
+// Owner    - everything
+// Manager  - products and IN/OUT movements; no deletes, adjustments or users
+// Employee - views stock and records what they took (OUT)
+
+// ErrForbidden is returned when a role may not perform an action
+var ErrForbidden = errors.New("your role is not allowed to do this")
+
+// Permission is one action guarded by role
+type Permission string
+
+// Permissions checked by the API
+const (
+	PermViewInventory   Permission = "inventory:view"   // Products, stock, movements, chat
+	PermEditProducts    Permission = "products:edit"    // Create/update products, aliases, barcodes, categories
+	PermDeleteProducts  Permission = "products:delete"  // Archive and restore products, delete categories
+	PermPurgeProducts   Permission = "products:purge"   // Delete archived products for good
+	PermSetMinStock     Permission = "stock:min"        // Change low-stock thresholds
+	PermReceiveInvoices Permission = "invoices:receive" // Ingest and approve supplier invoices
+	PermViewReports     Permission = "reports:view"     // Variance report
+	PermViewAudit       Permission = "audit:view"       // Who changed products and settings
+	PermManageStaff     Permission = "staff:manage"     // Add, rename and deactivate staff
+	PermManageUsers     Permission = "users:manage"     // Add users, change roles, relink old movements
+	PermManageAPIKeys   Permission = "apikeys:manage"   // Create and revoke API keys
+	PermManageBackups   Permission = "backups:manage"   // Download a full backup, restore one
+)
+
+// rolePermissions lists the permissions of each role
+var rolePermissions = map[string]map[Permission]bool{
+	models.RoleOwner: {
+		PermViewInventory:   true,
+		PermEditProducts:    true,
+		PermDeleteProducts:  true,
+		PermPurgeProducts:   true,
+		PermSetMinStock:     true,
+		PermReceiveInvoices: true,
+		PermViewReports:     true,
+		PermViewAudit:       true,
+		PermManageStaff:     true,
+		PermManageUsers:     true,
+		PermManageAPIKeys:   true,
+		PermManageBackups:   true,
+	},
+	models.RoleManager: {
+		PermViewInventory:   true,
+		PermEditProducts:    true,
+		PermReceiveInvoices: true,
+		PermViewReports:     true,
+		PermViewAudit:       true,
+		PermManageStaff:     true,
+	},
+	models.RoleEmployee: {
+		PermViewInventory: true,
+	},
+}
+
+// roleMovements lists the movement types each role may record
+var roleMovements = map[string]map[string]bool{
+	models.RoleOwner: models.ValidMovementTypes,
+	models.RoleManager: {
+		models.MovementIn:  true,
+		models.MovementOut: true,
+	},
+	models.RoleEmployee: {
+		models.MovementOut: true,
+	},
+}
+
+// Can reports whether a role has a permission
+func Can(role string, p Permission) bool {
+	return rolePermissions[role][p]
+}
+
+// CanRecordMovement reports whether a role may record a movement type
+func CanRecordMovement(role, movementType string) bool {
+	return roleMovements[role][movementType]
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{models.RoleOwner, PermDeleteProducts, true},
+		{models.RoleOwner, PermManageUsers, true},
+		{models.RoleManager, PermEditProducts, true},
+		{models.RoleManager, PermDeleteProducts, false},
+		{models.RoleManager, PermSetMinStock, false},
+		{models.RoleOwner, PermManageBackups, true},
+		{models.RoleManager, PermManageBackups, false},
+		{models.RoleOwner, PermPurgeProducts, true},
+		{models.RoleManager, PermPurgeProducts, false},
+		{models.RoleEmployee, PermViewInventory, true},
+		{models.RoleEmployee, PermEditProducts, false},
+		{models.RoleEmployee, PermDeleteProducts, false},
+		{"intern", PermViewInventory, false},
+	}
+	for _, tt := range tests {
+		if got := Can(tt.role, tt.perm); got != tt.want {
+			t.Errorf("Can(%s, %s) = %v, want %v", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestRoleMovements(t *testing.T) {
+	tests := []struct {
+		role, movement string
+		want           bool
+	}{
+		{models.RoleOwner, models.MovementAdjustment, true},
+		{models.RoleManager, models.MovementIn, true},
+		{models.RoleManager, models.MovementOut, true},
+		{models.RoleManager, models.MovementAdjustment, false},
+		{models.RoleEmployee, models.MovementOut, true},
+		{models.RoleEmployee, models.MovementIn, false},
+		{models.RoleEmployee, models.MovementAdjustment, false},
+	}
+	for _, tt := range tests {
+		if got := CanRecordMovement(tt.role, tt.movement); got != tt.want {
+			t.Errorf("CanRecordMovement(%s, %s) = %v, want %v", tt.role, tt.movement, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterRolesAndSetRole(t *testing.T) {
+	s, _ := newTestService(t)
+
+	owner, err := s.store.GetUserByUsername("dani")
+	if err != nil || owner.Role != models.RoleOwner {
+		t.Fatalf("first user: got %+v, err %v; want owner", owner, err)
+	}
+	yosef, err := s.Register("yosef", "", "long-enough-pw", "")
+	if err != nil || yosef.Role != models.RoleEmployee {
+		t.Fatalf("second user: got %+v, err %v; want employee", yosef, err)
+	}
+	if _, err := s.Register("rina", "", "long-enough-pw", "chef"); !errors.Is(err, models.ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+
+	if _, err := s.SetRole(owner.ID, models.RoleManager); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("expected ErrLastOwner, got %v", err)
+	}
+	if _, err := s.SetRole(yosef.ID, models.RoleOwner); err != nil {
+		t.Fatalf("SetRole failed: %v", err)
+	}
+	if _, err := s.SetRole(owner.ID, models.RoleManager); err != nil {
+		t.Fatalf("demoting one of two owners failed: %v", err)
+	}
+
+	// The new role applies to existing tokens at once
+	pair, err := s.Login("dani", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, err := s.SetRole(owner.ID, models.RoleEmployee); err != nil {
+		t.Fatalf("SetRole failed: %v", err)
+	}
+	id, err := s.Authenticate(pair.AccessToken)
+	if err != nil || id.Role != models.RoleEmployee {
+		t.Errorf("Authenticate: got %+v, err %v; want employee", id, err)
+	}
+}
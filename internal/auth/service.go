@@ -0,0 +1,467 @@
+// Package auth handles user accounts, password login and tokens.
+// Login returns a short-lived JWT access token plus a refresh token.
+// Every access token belongs to a session, so logout takes effect at
+// once: the middleware rejects tokens of revoked sessions.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWeakSecret         = errors.New("JWT secret must be at least 32 bytes")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountLocked      = errors.New("account is locked after too many failed logins")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrLastOwner          = errors.New("the last owner can't lose the owner role")
+	ErrSetupDone          = errors.New("the first user already exists")
+)
+
+// Options configure the auth service; zero values get sane defaults
+type Options struct {
+	Secret          []byte        // HMAC key for access tokens (>= 32 bytes)
+	AccessTTL       time.Duration // Access token lifetime (default 15m)
+	RefreshTTL      time.Duration // Refresh token lifetime, renewed on use (default 30 days)
+	MaxFailedLogins int           // Failed logins before lockout (default 5)
+	LockDuration    time.Duration // How long a lockout lasts (default 15m)
+	BcryptCost      int           // Default bcrypt.DefaultCost
+}
+
+// Identity is the authenticated user (or API key) of a request
+type Identity struct {
+	UserID      string
+	Username    string
+	DisplayName string // Key name for API keys
+	Role        string
+	SessionID   string
+	Language    string // The user's preferred language, "" = none
+
+	// Set for API keys instead of the user fields above
+	APIKeyID string
+	Scopes   map[string]bool
+}
+
+// IsAPIKey reports whether the request came with an API key
+func (id *Identity) IsAPIKey() bool {
+	return id.APIKeyID != ""
+}
+
+// Can reports whether the caller has a permission: by role for users,
+// by scope for API keys
+func (id *Identity) Can(p Permission) bool {
+	if id.IsAPIKey() {
+		return id.Scopes[string(p)]
+	}
+	return Can(id.Role, p)
+}
+
+// CanRecordMovement reports whether the caller may record a movement type
+func (id *Identity) CanRecordMovement(movementType string) bool {
+	if id.IsAPIKey() {
+		return id.Scopes[movementScopes[movementType]]
+	}
+	return CanRecordMovement(id.Role, movementType)
+}
+
+// UserInfo is a user as shown by the API (never the password hash)
+type UserInfo struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Role        string `json:"role"`
+	IsActive    bool   `json:"isActive"`
+	Language    string `json:"language"` // "" = from Accept-Language
+}
+
+// TokenPair is the answer to login and refresh
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	TokenType        string    `json:"tokenType"` // Always "Bearer"
+	ExpiresIn        int       `json:"expiresIn"` // Access token lifetime in seconds
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+	User             UserInfo  `json:"user"`
+}
+
+// Service logs users in and checks their tokens
+type Service struct {
+	store repository.Repository
+	opts  Options
+	now   func() time.Time
+
+	// mu serializes changes to users (failed-login counts, roles, the
+	// first owner), so parallel requests can't slip past the lockout or
+	// overwrite each other. Never hold it across bcrypt.
+	mu sync.Mutex
+
+	// dummyHash is compared for unknown users, so a wrong username
+	// takes as long as a wrong password
+	dummyHash string
+}
+
+// NewService creates an auth service
+func NewService(store repository.Repository, opts Options) (*Service, error) {
+	if len(opts.Secret) < 32 {
+		return nil, ErrWeakSecret
+	}
+	if opts.AccessTTL <= 0 {
+		opts.AccessTTL = 15 * time.Minute
+	}
+	if opts.RefreshTTL <= 0 {
+		opts.RefreshTTL = 30 * 24 * time.Hour
+	}
+	if opts.MaxFailedLogins <= 0 {
+		opts.MaxFailedLogins = 5
+	}
+	if opts.LockDuration <= 0 {
+		opts.LockDuration = 15 * time.Minute
+	}
+	if opts.BcryptCost == 0 {
+		opts.BcryptCost = bcrypt.DefaultCost
+	}
+
+	s := &Service{store: store, opts: opts, now: time.Now}
+	hash, err := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), opts.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	s.dummyHash = string(hash)
+	return s, nil
+}
+
+// PublicUser converts a stored user to its API view
+func PublicUser(u *models.User) UserInfo {
+	return UserInfo{ID: u.ID, Username: u.Username, DisplayName: u.DisplayName, Role: u.Role, IsActive: u.IsActive, Language: u.Language}
+}
+
+// NeedsSetup reports whether no user exists yet (first run)
+func (s *Service) NeedsSetup() bool {
+	return s.store.CountUsers() == 0
+}
+
+// Register creates an active user with a hashed password.
+// The first user is always the owner; later users default to employee.
+func (s *Service) Register(username, displayName, password, role string) (*models.User, error) {
+	return s.register(username, displayName, password, role, false)
+}
+
+// Setup creates the first user, the owner, for callers without a token.
+// Once any user exists it fails with ErrSetupDone.
+func (s *Service) Setup(username, displayName, password string) (*models.User, error) {
+	return s.register(username, displayName, password, models.RoleOwner, true)
+}
+
+// register counts the users and creates the new one under s.mu, so two
+// first-run requests can't both become the owner
+func (s *Service) register(username, displayName, password, role string, setup bool) (*models.User, error) {
+	hash, err := HashPassword(password, s.opts.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := s.NeedsSetup()
+	if setup && !first {
+		return nil, ErrSetupDone
+	}
+	if first {
+		role = models.RoleOwner
+	} else if role == "" {
+		role = models.RoleEmployee
+	}
+	if !models.ValidRoles[role] {
+		return nil, fmt.Errorf("%w: %q", models.ErrInvalidRole, role)
+	}
+	u := &models.User{
+		Username:     username,
+		DisplayName:  strings.TrimSpace(displayName),
+		PasswordHash: hash,
+		Role:         role,
+		IsActive:     true,
+	}
+	if _, err := s.store.CreateUser(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetRole changes the role of a user; it takes effect on their next request.
+// The last owner can't be demoted, so the restaurant is never left
+// without someone who can manage users.
+func (s *Service) SetRole(userID, role string) (*models.User, error) {
+	if !models.ValidRoles[role] {
+		return nil, fmt.Errorf("%w: %q", models.ErrInvalidRole, role)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.store.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == role {
+		return u, nil
+	}
+	if u.Role == models.RoleOwner {
+		owners := 0
+		for _, other := range s.store.ListUsers() {
+			if other.Role == models.RoleOwner && other.IsActive {
+				owners++
+			}
+		}
+		if owners <= 1 {
+			return nil, ErrLastOwner
+		}
+	}
+	u.Role = role
+	if err := s.store.UpdateUser(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetLanguage saves the language a user wants API messages in;
+// "" goes back to the Accept-Language header
+func (s *Service) SetLanguage(userID, lang string) (*models.User, error) {
+	if lang != "" && !i18n.Supported(lang) {
+		return nil, fmt.Errorf("%w: %q", models.ErrInvalidLanguage, lang)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.store.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	u.Language = lang
+	if err := s.store.UpdateUser(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Login checks the password and starts a new session.
+// After MaxFailedLogins wrong passwords in a row the account is locked
+// for LockDuration; a successful login resets the counter.
+func (s *Service) Login(username, password string) (*TokenPair, error) {
+	u, err := s.store.GetUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			CheckPassword(s.dummyHash, password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if u.IsLocked(s.now()) {
+		return nil, lockedError(u)
+	}
+	// bcrypt is slow on purpose: check without the lock, so logins of
+	// different users run in parallel
+	ok := CheckPassword(u.PasswordHash, password)
+	if !u.IsActive {
+		return nil, ErrInvalidCredentials
+	}
+	if u, err = s.countLogin(u.ID, ok); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	refresh, hash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sess := &models.Session{
+		ID:          sessionID,
+		UserID:      u.ID,
+		RefreshHash: hash,
+		ExpiresAt:   now.Add(s.opts.RefreshTTL),
+		CreatedAt:   now,
+	}
+	if err := s.store.CreateSession(sess); err != nil {
+		return nil, err
+	}
+	return s.tokenPair(u, sess, refresh, now)
+}
+
+// countLogin counts a failed login, locking the account after
+// MaxFailedLogins, or clears the count after a good one. It reads the
+// user again under s.mu, so parallel logins count every failure and
+// don't write back a stale copy of the user.
+func (s *Service) countLogin(userID string, ok bool) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	u, err := s.store.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	// Locked by a parallel guess while the password was checked
+	if u.IsLocked(now) {
+		return nil, lockedError(u)
+	}
+	if ok {
+		if !u.IsActive {
+			return nil, ErrInvalidCredentials
+		}
+		if u.FailedLogins != 0 || !u.LockedUntil.IsZero() {
+			u.FailedLogins = 0
+			u.LockedUntil = time.Time{}
+			if err := s.store.UpdateUser(u); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	}
+
+	u.FailedLogins++
+	locked := u.FailedLogins >= s.opts.MaxFailedLogins
+	if locked {
+		u.FailedLogins = 0
+		u.LockedUntil = now.Add(s.opts.LockDuration)
+	}
+	if err := s.store.UpdateUser(u); err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, lockedError(u)
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// lockedError tells a locked user when to try again
+func lockedError(u *models.User) error {
+	return fmt.Errorf("%w: try again after %s", ErrAccountLocked, u.LockedUntil.Format(time.RFC3339))
+}
+
+// Refresh swaps a refresh token for a new access + refresh token pair.
+// Refresh tokens are single use: presenting an old one again means it
+// was copied, so the whole session is revoked.
+func (s *Service) Refresh(refreshToken string) (*TokenPair, error) {
+	sessionID, ok := splitRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	now := s.now()
+	sess, err := s.store.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !sess.IsActive(now) {
+		return nil, ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(refreshToken)), []byte(sess.RefreshHash)) != 1 {
+		sess.RevokedAt = now
+		if err := s.store.UpdateSession(sess); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+
+	u, err := s.store.GetUser(sess.UserID)
+	if err != nil || !u.IsActive {
+		return nil, ErrInvalidToken
+	}
+
+	refresh, hash, err := newRefreshToken(sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	sess.RefreshHash = hash
+	sess.ExpiresAt = now.Add(s.opts.RefreshTTL)
+	if err := s.store.UpdateSession(sess); err != nil {
+		return nil, err
+	}
+	return s.tokenPair(u, sess, refresh, now)
+}
+
+// Logout revokes a session; its access and refresh tokens stop working
+func (s *Service) Logout(sessionID string) error {
+	sess, err := s.store.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if !sess.RevokedAt.IsZero() {
+		return nil
+	}
+	sess.RevokedAt = s.now()
+	return s.store.UpdateSession(sess)
+}
+
+// Authenticate checks an access token or API key and returns who it belongs to
+func (s *Service) Authenticate(accessToken string) (*Identity, error) {
+	if strings.HasPrefix(accessToken, APIKeyPrefix) {
+		return s.authenticateAPIKey(accessToken)
+	}
+	c, err := s.parseAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.store.GetSession(c.SessionID)
+	if err != nil || sess.UserID != c.Subject || !sess.IsActive(s.now()) {
+		return nil, ErrInvalidToken
+	}
+	u, err := s.store.GetUser(c.Subject)
+	if err != nil || !u.IsActive {
+		return nil, ErrInvalidToken
+	}
+	// Role is read from the user on every request, so a role change
+	// applies at once instead of when the token expires
+	return &Identity{UserID: u.ID, Username: u.Username, DisplayName: u.DisplayName, Role: u.Role, SessionID: sess.ID, Language: u.Language}, nil
+}
+
+// tokenPair signs an access token for the session
+func (s *Service) tokenPair(u *models.User, sess *models.Session, refresh string, now time.Time) (*TokenPair, error) {
+	access, err := s.signAccessToken(u.ID, u.Username, sess.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.opts.AccessTTL / time.Second),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: sess.ExpiresAt,
+		User:             PublicUser(u),
+	}, nil
+}
+
+// ============================================
+// REQUEST CONTEXT
+// ============================================
+
+type contextKey struct{}
+
+// WithIdentity returns a context carrying the authenticated user
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// IdentityFrom returns the authenticated user of a request, if any
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestService returns a service with one user "dani" / "correct-horse"
+// and a clock the test can move forward
+func newTestService(t *testing.T) (*Service, *time.Time) {
+	t.Helper()
+	s, err := NewService(repository.NewMemoryStore(), Options{Secret: testSecret, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	if !s.NeedsSetup() {
+		t.Fatalf("expected NeedsSetup on an empty store")
+	}
+	if _, err := s.Register("Dani", "Dani Cohen", "correct-horse", ""); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	return s, &now
+}
+
+func TestNewServiceRejectsWeakSecret(t *testing.T) {
+	if _, err := NewService(repository.NewMemoryStore(), Options{Secret: []byte("short")}); !errors.Is(err, ErrWeakSecret) {
+		t.Fatalf("expected ErrWeakSecret, got %v", err)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	if _, err := s.Register("dani", "", "another-password", ""); err == nil {
+		t.Errorf("expected error for duplicate username")
+	}
+	if _, err := s.Register("yosef", "", "short", ""); !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("expected ErrPasswordTooShort, got %v", err)
+	}
+	if _, err := s.Register("no spaces", "", "long-enough-pw", ""); err == nil {
+		t.Errorf("expected error for invalid username")
+	}
+}
+
+func TestSetupMakesOnlyOneOwner(t *testing.T) {
+	s, err := NewService(repository.NewMemoryStore(), Options{Secret: testSecret, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	// Parallel first-run requests: one becomes the owner, the rest are refused
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.Setup(fmt.Sprintf("user%d", i), "", "long-enough-pw")
+		}(i)
+	}
+	wg.Wait()
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrSetupDone):
+			t.Fatalf("expected ErrSetupDone, got %v", err)
+		}
+	}
+	if created != 1 || len(s.store.ListUsers()) != 1 {
+		t.Fatalf("expected exactly one owner, got %d", created)
+	}
+}
+
+func TestLoginAndAuthenticate(t *testing.T) {
+	s, _ := newTestService(t)
+
+	pair, err := s.Login("DANI", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if pair.TokenType != "Bearer" || pair.User.Username != "dani" {
+		t.Errorf("unexpected token pair: %+v", pair)
+	}
+	id, err := s.Authenticate(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if id.Username != "dani" || id.DisplayName != "Dani Cohen" {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+
+	if _, err := s.Login("dani", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := s.Login("nobody", "correct-horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+}
+
+func TestAuthenticateRejectsBadTokens(t *testing.T) {
+	s, now := newTestService(t)
+	pair, err := s.Login("dani", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	tampered := pair.AccessToken[:len(pair.AccessToken)-2] + "xx"
+	if _, err := s.Authenticate(tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for tampered token, got %v", err)
+	}
+	if _, err := s.Authenticate(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for refresh token used as access token, got %v", err)
+	}
+
+	*now = now.Add(16 * time.Minute)
+	if _, err := s.Authenticate(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken for expired token, got %v", err)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	s, now := newTestService(t)
+	for i := 0; i < 4; i++ {
+		if _, err := s.Login("dani", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+	// The fifth failure locks the account
+	if _, err := s.Login("dani", "wrong-password"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("expected ErrAccountLocked on attempt 5, got %v", err)
+	}
+	// Locked even with the right password
+	if _, err := s.Login("dani", "correct-horse"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("expected ErrAccountLocked, got %v", err)
+	}
+
+	*now = now.Add(16 * time.Minute)
+	if _, err := s.Login("dani", "correct-horse"); err != nil {
+		t.Fatalf("Login after lock expired failed: %v", err)
+	}
+	// A successful login resets the counter
+	if _, err := s.Login("dani", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := s.Login("dani", "correct-horse"); err != nil {
+		t.Fatalf("Login after one failure failed: %v", err)
+	}
+}
+
+func TestParallelLoginsCountEveryFailure(t *testing.T) {
+	s, _ := newTestService(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Login("dani", "wrong-password")
+		}()
+	}
+	wg.Wait()
+	if _, err := s.Login("dani", "correct-horse"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("expected five parallel failures to lock the account, got %v", err)
+	}
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	s, _ := newTestService(t)
+	first, err := s.Login("dani", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a new refresh token after Refresh")
+	}
+	if _, err := s.Authenticate(second.AccessToken); err != nil {
+		t.Fatalf("Authenticate with refreshed token failed: %v", err)
+	}
+
+	// Replaying the old token revokes the whole session
+	if _, err := s.Refresh(first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken on reuse, got %v", err)
+	}
+	if _, err := s.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected session revoked after reuse, got %v", err)
+	}
+	if _, err := s.Authenticate(second.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected access token rejected after reuse, got %v", err)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	s, _ := newTestService(t)
+	pair, err := s.Login("dani", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	id, err := s.Authenticate(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if err := s.Logout(id.SessionID); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := s.Authenticate(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken after logout, got %v", err)
+	}
+	if _, err := s.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected refresh to fail after logout, got %v", err)
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	s, _ := newTestService(t)
+	pair, err := s.Login("dani", "correct-horse")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, err := s.SetLanguage(pair.User.ID, "fr"); !errors.Is(err, models.ErrInvalidLanguage) {
+		t.Errorf("expected ErrInvalidLanguage, got %v", err)
+	}
+	u, err := s.SetLanguage(pair.User.ID, "he")
+	if err != nil {
+		t.Fatalf("SetLanguage failed: %v", err)
+	}
+	if PublicUser(u).Language != "he" {
+		t.Errorf("Language = %q, want he", u.Language)
+	}
+	// Applies to tokens issued before the change
+	id, err := s.Authenticate(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if id.Language != "he" {
+		t.Errorf("identity Language = %q, want he", id.Language)
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// issuer is the "iss" claim of our access tokens
+const issuer = "restaurant-inventory"
+
+// claims are the fields inside an access token
+type claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+	Username  string `json:"usr"`
+}
+
+// signAccessToken creates an HS256 access token for a session
+func (s *Service) signAccessToken(userID, username, sessionID string, now time.Time) (string, error) {
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.opts.AccessTTL)),
+		},
+		SessionID: sessionID,
+		Username:  username,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(s.opts.Secret)
+}
+
+// parseAccessToken checks signature, algorithm, issuer and expiry
+func (s *Service) parseAccessToken(token string) (*claims, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (interface{}, error) {
+		return s.opts.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if c.Subject == "" || c.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+	return &c, nil
+}
+
+// newRefreshToken returns "<sessionID>.<random secret>" and the hash we store
+func newRefreshToken(sessionID string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = sessionID + "." + base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// splitRefreshToken returns the session ID part of a refresh token
+func splitRefreshToken(token string) (string, bool) {
+	sessionID, secret, ok := strings.Cut(token, ".")
+	return sessionID, ok && sessionID != "" && secret != ""
+}
+
+// hashToken is the SHA-256 (hex) we keep instead of the token itself
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSessionID returns a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "SES-" + hex.EncodeToString(b), nil
+}
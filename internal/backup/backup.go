@@ -0,0 +1,165 @@
+// Package backup writes the whole inventory to one file and reads it back.
+// A backup is gzipped JSON: a manifest (format version, time, row counts,
+// SHA-256 of the data) and the data, a repository.Snapshot. It restores
+// into PostgreSQL or the in-memory store alike, so a nightly backup from
+// the server can be loaded onto a laptop.
+package backup
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+var (
+	ErrNotBackup          = errors.New("not an inventory backup")
+	ErrUnsupportedVersion = errors.New("backup format is newer than this program")
+	ErrChecksumMismatch   = errors.New("backup is damaged: checksum does not match")
+)
+
+// formatName marks a file as one of our backups
+const formatName = "restaurant-inventory-backup"
+
+// FormatVersion is the version written; Read accepts it and older ones.
+// Bump it when Snapshot changes in a way older programs can't read.
+const FormatVersion = 1
+
+// Extension ends the name of backup files
+const Extension = ".backup.json.gz"
+
+// Manifest describes a backup
+type Manifest struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	SHA256    string         `json:"sha256"` // Of the data, as written (hex)
+	Counts    map[string]int `json:"counts"` // Rows per table
+}
+
+// archive is the JSON inside the gzip
+type archive struct {
+	Manifest Manifest        `json:"manifest"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Create snapshots the store and writes it to w
+func Create(w io.Writer, store repository.Repository) (*Manifest, error) {
+	snap, err := store.Snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	return Write(w, snap, time.Now())
+}
+
+// Write writes snap as a backup taken at createdAt
+func Write(w io.Writer, snap *repository.Snapshot, createdAt time.Time) (*Manifest, error) {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	a := archive{
+		Manifest: Manifest{
+			Format:    formatName,
+			Version:   FormatVersion,
+			CreatedAt: createdAt.UTC(),
+			SHA256:    hex.EncodeToString(sum[:]),
+			Counts:    snap.Counts(),
+		},
+		Data: data,
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(a); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &a.Manifest, nil
+}
+
+// Read reads a backup and checks its format, version and checksum
+func Read(r io.Reader) (*Manifest, *repository.Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotBackup, err)
+	}
+	defer zr.Close()
+
+	var a archive
+	if err := json.NewDecoder(zr).Decode(&a); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotBackup, err)
+	}
+	if a.Manifest.Format != formatName || a.Manifest.Version < 1 {
+		return nil, nil, ErrNotBackup
+	}
+	if a.Manifest.Version > FormatVersion {
+		return nil, nil, fmt.Errorf("%w: version %d, this program reads up to %d",
+			ErrUnsupportedVersion, a.Manifest.Version, FormatVersion)
+	}
+	sum := sha256.Sum256(a.Data)
+	if hex.EncodeToString(sum[:]) != a.Manifest.SHA256 {
+		return nil, nil, ErrChecksumMismatch
+	}
+
+	var snap repository.Snapshot
+	if err := json.Unmarshal(a.Data, &snap); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotBackup, err)
+	}
+	return &a.Manifest, &snap, nil
+}
+
+// Restore reads a backup and replaces everything in the store with it
+func Restore(r io.Reader, store repository.Repository) (*Manifest, error) {
+	manifest, snap, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Restore(snap); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// FileName names a backup taken at t: "inventory-20261018-0300.backup.json.gz"
+func FileName(t time.Time) string {
+	return "inventory-" + t.Format("20060102-1504") + Extension
+}
+
+// Prune deletes all but the newest keep backups in dir (by file name,
+// which sorts by time) and returns the paths it deleted
+func Prune(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "inventory-") && strings.HasSuffix(e.Name(), Extension) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var deleted []string
+	for i := 0; i < len(names)-keep; i++ {
+		path := filepath.Join(dir, names[i])
+		if err := os.Remove(path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, path)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,163 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func newStore(t *testing.T) *repository.MemoryStore {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	id, err := store.AddProduct(&models.Product{Name: "קוקה קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	m, _ := models.NewStockMovement(id, models.MovementIn, 3, 4, "Yosef", "", "delivery <&>")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	return store
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	var buf bytes.Buffer
+	manifest, err := Create(&buf, newStore(t))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if manifest.Version != FormatVersion || manifest.Counts["products"] != 1 || manifest.Counts["movements"] != 1 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	target := repository.NewMemoryStore()
+	got, err := Restore(bytes.NewReader(buf.Bytes()), target)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got.SHA256 != manifest.SHA256 {
+		t.Errorf("checksum changed: %s, want %s", got.SHA256, manifest.SHA256)
+	}
+	st, err := target.GetStock("PROD-001")
+	if err != nil || st.QuantityBoxes != 3 || st.QuantityUnits != 4 {
+		t.Errorf("restored stock: got %+v, %v", st, err)
+	}
+	if moves := target.ListMovements(repository.MovementFilter{}); len(moves) != 1 || moves[0].Reason != "delivery <&>" {
+		t.Errorf("restored movements: got %+v", moves)
+	}
+}
+
+// rewrite unpacks a backup, lets change edit it and packs it again
+func rewrite(t *testing.T, data []byte, change func(a *archive)) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a archive
+	if err := json.NewDecoder(zr).Decode(&a); err != nil {
+		t.Fatal(err)
+	}
+	change(&a)
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	if err := json.NewEncoder(zw).Encode(a); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return out.Bytes()
+}
+
+// Backups from before products had JSON names spell the product keys
+// like the Go fields ("Name", "IsActive"); they must still restore
+func TestRestoreOldProductKeys(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Create(&buf, newStore(t)); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	old := rewrite(t, buf.Bytes(), func(a *archive) {
+		for _, key := range []string{"id", "name", "brand", "size", "containerType", "boxSize", "price", "category", "isActive", "version", "updatedAt"} {
+			a.Data = bytes.ReplaceAll(a.Data, []byte(`"`+key+`":`), []byte(`"`+strings.ToUpper(key[:1])+key[1:]+`":`))
+		}
+		sum := sha256.Sum256(a.Data)
+		a.Manifest.SHA256 = hex.EncodeToString(sum[:])
+	})
+
+	target := repository.NewMemoryStore()
+	if _, err := Restore(bytes.NewReader(old), target); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	p, err := target.GetProduct("PROD-001")
+	if err != nil || p.Name != "קוקה קולה" || p.BoxSize != 24 || !p.IsActive || p.Version != 1 {
+		t.Errorf("restored product: got %+v, %v", p, err)
+	}
+}
+
+func TestReadRejectsBadFiles(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Create(&buf, newStore(t)); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	good := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not gzip", []byte("id,name\n"), ErrNotBackup},
+		{"other format", rewrite(t, good, func(a *archive) { a.Manifest.Format = "something-else" }), ErrNotBackup},
+		{"newer version", rewrite(t, good, func(a *archive) { a.Manifest.Version = FormatVersion + 1 }), ErrUnsupportedVersion},
+		{"edited data", rewrite(t, good, func(a *archive) {
+			a.Data = bytes.Replace(a.Data, []byte(`"QuantityBoxes":3`), []byte(`"QuantityBoxes":30`), 1)
+		}), ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := repository.NewMemoryStore()
+			if _, err := Restore(bytes.NewReader(tt.data), target); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if len(target.ListProducts()) != 0 {
+				t.Error("a rejected backup changed the store")
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC)
+	for day := 0; day < 5; day++ {
+		path := filepath.Join(dir, FileName(start.AddDate(0, 0, day)))
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(deleted) != 3 || filepath.Base(deleted[0]) != "inventory-20261001-0300"+Extension {
+		t.Errorf("deleted %v, want the 3 oldest", deleted)
+	}
+	left, _ := os.ReadDir(dir)
+	if len(left) != 3 { // 2 backups + notes.txt
+		t.Errorf("%d files left, want 3", len(left))
+	}
+}
@@ -0,0 +1,266 @@
+// Package catalog imports and exports the product catalog in bulk, as CSV
+// (the way Excel saves it: BOM, Hebrew, any column order) or JSON. Rows are
+// matched to existing products by brand + size + container, so importing
+// the same file twice changes nothing the second time.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown catalog format (use csv or json)")
+	ErrNoRows        = errors.New("catalog file has no product rows")
+	ErrMissingColumn = errors.New("catalog CSV is missing a required column")
+	ErrTooManyRows   = fmt.Errorf("at most %d products per import", MaxRows)
+)
+
+// Catalog file formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// MaxRows is the most products one import may carry
+const MaxRows = 5000
+
+// Row is one product in an import or export file
+type Row struct {
+	Line          int     `json:"-"`            // Line in the file (CSV) or position (JSON), from 1
+	ID            string  `json:"id,omitempty"` // Export only; imports match by brand + size + container
+	Name          string  `json:"name"`
+	Brand         string  `json:"brand"`
+	Size          int     `json:"size"`
+	ContainerType string  `json:"containerType"`
+	BoxSize       int     `json:"boxSize"`
+	Price         float64 `json:"price"`
+	Category      string  `json:"category"`
+	IsActive      *bool   `json:"isActive,omitempty"` // Empty on import = keep as is (new products are active)
+
+	problems []string // Cells that couldn't be read
+}
+
+// csvHeader is the header row of exported CSV files
+var csvHeader = []string{"id", "name", "brand", "size", "container_type", "box_size", "price", "category", "is_active"}
+
+// csvColumns lists header names per field (compared lowercase, trimmed)
+var csvColumns = map[string][]string{
+	"id":            {"id", "product id", "מזהה"},
+	"name":          {"name", "product", "שם", "שם מוצר", "מוצר"},
+	"brand":         {"brand", "מותג", "יצרן"},
+	"size":          {"size", "גודל", "נפח", "משקל"},
+	"containerType": {"container_type", "containertype", "container type", "container", "אריזה", "סוג אריזה"},
+	"boxSize":       {"box_size", "boxsize", "box size", "units per box", "יחידות בקרטון", "גודל קרטון"},
+	"price":         {"price", "unit price", "מחיר", "מחיר יחידה"},
+	"category":      {"category", "קטגוריה"},
+	"isActive":      {"is_active", "isactive", "active", "פעיל"},
+}
+
+// Read parses a catalog file. An empty format is detected from the data:
+// JSON starts with "[", anything else is CSV.
+func Read(format string, r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Excel adds a BOM
+	if format == "" {
+		format = FormatCSV
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			format = FormatJSON
+		}
+	}
+
+	var rows []Row
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		rows, err = readCSV(data)
+	case FormatJSON:
+		rows, err = readJSON(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+	if len(rows) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+	return rows, nil
+}
+
+// readJSON reads an array of rows
+func readJSON(data []byte) ([]Row, error) {
+	var rows []Row
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("read json: %w", err)
+	}
+	for i := range rows {
+		rows[i].Line = i + 1
+	}
+	return rows, nil
+}
+
+// readCSV reads rows by header name; cells that aren't numbers where
+// numbers belong are kept as problems of their row
+func readCSV(data []byte) ([]Row, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	cols := mapColumns(header)
+	for _, field := range []string{"name", "size"} {
+		if _, ok := cols[field]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, field)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0) // Blank lines are skipped, so count from the reader
+		get := func(field string) string {
+			if idx, ok := cols[field]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // Empty row
+		}
+
+		row := Row{
+			Line:          line,
+			ID:            get("id"),
+			Name:          get("name"),
+			Brand:         get("brand"),
+			ContainerType: get("containerType"),
+			Category:      get("category"),
+		}
+		row.Size = row.parseInt("size", get("size"))
+		row.BoxSize = row.parseInt("boxSize", get("boxSize"))
+		if v := get("price"); v != "" {
+			price, err := strconv.ParseFloat(strings.TrimSpace(strings.Trim(v, "₪")), 64)
+			if err != nil {
+				row.problems = append(row.problems, fmt.Sprintf("price: %q is not a number", v))
+			}
+			row.Price = price
+		}
+		if v := get("isActive"); v != "" {
+			active, ok := parseBool(v)
+			if !ok {
+				row.problems = append(row.problems, fmt.Sprintf("isActive: %q is not yes/no", v))
+			} else {
+				row.IsActive = &active
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseInt reads a whole-number cell; empty means 0
+func (row *Row) parseInt(field, v string) int {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		row.problems = append(row.problems, fmt.Sprintf("%s: %q is not a whole number", field, v))
+	}
+	return n
+}
+
+// parseBool reads yes/no cells in English or Hebrew
+func parseBool(v string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "yes", "y", "1", "כן":
+		return true, true
+	case "false", "no", "n", "0", "לא":
+		return false, true
+	}
+	return false, false
+}
+
+// detectDelimiter picks the most common of , ; and tab in the header line
+func detectDelimiter(data []byte) rune {
+	header, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := strings.Count(header, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// mapColumns finds the index of each known field in the header row
+func mapColumns(header []string) map[string]int {
+	cols := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		for field, names := range csvColumns {
+			if _, done := cols[field]; done {
+				continue
+			}
+			for _, name := range names {
+				if h == name {
+					cols[field] = i
+				}
+			}
+		}
+	}
+	return cols
+}
+
+// WriteCSV writes rows as CSV with a BOM, so Excel shows the Hebrew
+func WriteCSV(w io.Writer, rows []Row) error {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		active := ""
+		if row.IsActive != nil {
+			active = strconv.FormatBool(*row.IsActive)
+		}
+		record := []string{
+			row.ID, row.Name, row.Brand, strconv.Itoa(row.Size), row.ContainerType,
+			strconv.Itoa(row.BoxSize), strconv.FormatFloat(row.Price, 'f', -1, 64), row.Category, active,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,273 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// excelCSV is a catalog as Excel saves it: BOM, Hebrew headers, semicolons
+const excelCSV = "\xef\xbb\xbfשם;מותג;גודל;אריזה;יחידות בקרטון;מחיר;קטגוריה\n" +
+	"קוקה קולה 330 מ״ל פחית;Coca Cola;330;can;24;5.50;drinks\n" +
+	"\n" +
+	"חומוס 400 גרם;עשי;400;can;12;₪8;canned\n"
+
+func TestReadCSV(t *testing.T) {
+	rows, err := Read("", strings.NewReader(excelCSV))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	cola, hummus := rows[0], rows[1]
+	if cola.Name != "קוקה קולה 330 מ״ל פחית" || cola.Size != 330 || cola.BoxSize != 24 || cola.Price != 5.5 || cola.Line != 2 {
+		t.Errorf("unexpected cola row: %+v", cola)
+	}
+	if hummus.Price != 8 || hummus.Line != 4 {
+		t.Errorf("unexpected hummus row: %+v", hummus)
+	}
+
+	if _, err := Read(FormatCSV, strings.NewReader("brand,price\nX,1\n")); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("no name column: expected ErrMissingColumn, got %v", err)
+	}
+	if _, err := Read("xml", strings.NewReader("<x/>")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("xml: expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestImportDryRunReportsErrorsPerRow(t *testing.T) {
+	store := repository.NewMemoryStore()
+	data := "name,brand,size,container_type,price,category\n" +
+		"קולה,Coca Cola,330,can,5.5,drinks\n" +
+		",Fanta,330,can,5.5,drinks\n" +
+		"ספרייט,Sprite,big,can,5.5,drinks\n" +
+		"פלפל,ירקות,1000,kg,12,furniture\n" +
+		"קולה שוב,coca cola,330,CAN,5.5,drinks\n"
+	rows, err := Read(FormatCSV, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	report, err := NewService(store).Import(rows, true, nil)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	want := []string{ActionCreate, ActionError, ActionError, ActionError, ActionError}
+	for i, action := range want {
+		if got := report.Rows[i]; got.Action != action {
+			t.Errorf("line %d: action %s, want %s (%v)", got.Line, got.Action, action, got.Errors)
+		}
+	}
+	if errs := report.Rows[1].Errors; len(errs) != 1 || !strings.Contains(errs[0], models.ErrProductNameRequired.Error()) {
+		t.Errorf("missing name should fail Product.Validate: %v", errs)
+	}
+	if errs := report.Rows[4].Errors; len(errs) != 1 || !strings.Contains(errs[0], "line 2") {
+		t.Errorf("duplicate key should point at line 2: %v", errs)
+	}
+	if report.Applied || len(store.ListProducts()) != 0 {
+		t.Errorf("dry run wrote products")
+	}
+
+	// Not a dry run, but rows have errors: still nothing written
+	report, _ = NewService(store).Import(rows, false, nil)
+	if report.Applied || len(store.ListProducts()) != 0 {
+		t.Errorf("import with errors wrote products")
+	}
+}
+
+func TestImportUpsertsByBrandSizeContainer(t *testing.T) {
+	store := repository.NewMemoryStore()
+	colaID, _ := store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	rows, err := Read("", strings.NewReader(excelCSV))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	svc := NewService(store.WithActor("dana"))
+	report, err := svc.Import(rows, false, nil)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !report.Applied || report.Updated != 1 || report.Created != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if row := report.Rows[0]; row.ProductID != colaID || strings.Join(row.Changed, ",") != "Name,Price" {
+		t.Errorf("cola should be updated in place: %+v", row)
+	}
+	cola, _ := store.GetProduct(colaID)
+	if cola.Price != 5.5 || cola.Version != 2 {
+		t.Errorf("cola not updated: %+v", cola)
+	}
+	if entries := store.ListAudit(repository.AuditFilter{Actor: "dana"}); len(entries) != 2 {
+		t.Errorf("import should be audited as dana, got %d entries", len(entries))
+	}
+
+	// The same file again changes nothing
+	report, _ = svc.Import(rows, false, nil)
+	if report.Unchanged != 2 || report.Created+report.Updated != 0 {
+		t.Errorf("second import should change nothing: %+v", report)
+	}
+	if len(store.ListProducts()) != 2 {
+		t.Errorf("got %d products, want 2", len(store.ListProducts()))
+	}
+}
+
+// unreadableStore fails to list the catalog, like a database that is down
+type unreadableStore struct {
+	*repository.MemoryStore
+}
+
+var errCatalogDown = errors.New("database is down")
+
+func (unreadableStore) ListProductsChangedSince(time.Time) ([]*models.Product, error) {
+	return nil, errCatalogDown
+}
+
+func TestImportStopsWhenCatalogCantBeRead(t *testing.T) {
+	store := repository.NewMemoryStore()
+	rows, err := Read("", strings.NewReader(excelCSV))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	// Otherwise every row would be created again as a new product
+	if _, err := NewService(unreadableStore{store}).Import(rows, false, nil); !errors.Is(err, errCatalogDown) {
+		t.Fatalf("expected the store error, got %v", err)
+	}
+	if n := len(store.ListProducts()); n != 0 {
+		t.Errorf("nothing should be written, got %d products", n)
+	}
+}
+
+// failingWriteStore fails to add one brand, like a database that goes
+// away in the middle of an import
+type failingWriteStore struct {
+	*repository.MemoryStore
+	brand string
+}
+
+func (s failingWriteStore) AddProduct(p *models.Product) (string, error) {
+	if p.Brand == s.brand {
+		return "", errCatalogDown
+	}
+	return s.MemoryStore.AddProduct(p)
+}
+
+func TestImportReportsRowsWrittenBeforeFailure(t *testing.T) {
+	store := repository.NewMemoryStore()
+	data := "name,brand,size,container_type,price,category\n" +
+		"קולה,Coca Cola,330,can,5.5,drinks\n" +
+		"ספרייט,Sprite,330,can,5.5,drinks\n" +
+		"מים,Neviot,500,bottle,3,drinks\n"
+	rows, err := Read(FormatCSV, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	report, err := NewService(failingWriteStore{store, "Sprite"}).Import(rows, false, nil)
+	if !errors.Is(err, errCatalogDown) || report == nil {
+		t.Fatalf("expected the store error with a report, got %+v, %v", report, err)
+	}
+	if report.Applied || report.StoppedAt != rows[1].Line {
+		t.Errorf("report should stop at line %d: %+v", rows[1].Line, report)
+	}
+	for i, want := range []bool{true, false, false} {
+		if got := report.Rows[i].Written; got != want {
+			t.Errorf("line %d: written %v, want %v", report.Rows[i].Line, got, want)
+		}
+	}
+	if n := len(store.ListProducts()); n != 1 {
+		t.Errorf("got %d products, want only the one before the failure", n)
+	}
+}
+
+var errNoArchive = errors.New("may not archive or restore")
+
+func TestImportChecksArchiveAndRestore(t *testing.T) {
+	store := repository.NewMemoryStore()
+	store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", Price: 5, Category: "drinks"})
+	fantaID, _ := store.AddProduct(&models.Product{Name: "פנטה", Brand: "Fanta", Size: 330, ContainerType: "can", Price: 5, Category: "drinks"})
+	store.DeleteProduct(fantaID, 0)
+	data := `[
+		{"name": "קולה", "brand": "Coca Cola", "size": 330, "containerType": "can", "price": 6, "category": "drinks", "isActive": false},
+		{"name": "פנטה", "brand": "Fanta", "size": 330, "containerType": "can", "price": 5, "category": "drinks", "isActive": true},
+		{"name": "ספרייט", "brand": "Sprite", "size": 330, "containerType": "can", "price": 5, "category": "drinks", "isActive": false},
+		{"name": "מים", "brand": "Neviot", "size": 500, "containerType": "bottle", "price": 3, "category": "drinks"}
+	]`
+	rows, err := Read(FormatJSON, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	deny := func(*models.Product) error { return errNoArchive }
+	report, err := NewService(store).Import(rows, false, deny)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	want := []string{ActionError, ActionError, ActionError, ActionCreate}
+	for i, action := range want {
+		if got := report.Rows[i]; got.Action != action {
+			t.Errorf("line %d: action %s, want %s (%v)", got.Line, got.Action, action, got.Errors)
+		}
+	}
+	if errs := report.Rows[0].Errors; len(errs) != 1 || errs[0] != errNoArchive.Error() {
+		t.Errorf("archiving cola should fail the check: %v", errs)
+	}
+	if report.Applied || len(store.ListProducts()) != 1 {
+		t.Errorf("a denied import wrote products")
+	}
+
+	// Keeping the state as it is needs no permission
+	report, err = NewService(store).Import(rows[3:], false, deny)
+	if err != nil || !report.Applied {
+		t.Fatalf("import without state changes: %+v, %v", report, err)
+	}
+
+	report, err = NewService(store).Import(rows[:3], false, nil)
+	if err != nil || !report.Applied {
+		t.Fatalf("allowed import: %+v, %v", report, err)
+	}
+	if fanta, _ := store.GetProduct(fantaID); !fanta.IsActive {
+		t.Errorf("fanta should be restored")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	store := repository.NewMemoryStore()
+	store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"})
+	goneID, _ := store.AddProduct(&models.Product{Name: "פנטה", Brand: "Fanta", Size: 330, ContainerType: "can", Price: 5, Category: "drinks"})
+	store.DeleteProduct(goneID, 0)
+	svc := NewService(store)
+
+	if rows, _ := svc.Export(false); len(rows) != 1 {
+		t.Errorf("export without deleted: %d rows, want 1", len(rows))
+	}
+	all, err := svc.Export(true)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, all); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\xef\xbb\xbf")) {
+		t.Errorf("CSV export should start with a BOM for Excel")
+	}
+
+	rows, err := Read("", &buf)
+	if err != nil {
+		t.Fatalf("Read of export failed: %v", err)
+	}
+	report, err := svc.Import(rows, true, nil)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Unchanged != 2 {
+		t.Errorf("re-importing an export should change nothing: %+v", report.Rows)
+	}
+}
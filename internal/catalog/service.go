@@ -0,0 +1,232 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// What an import does with a row
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionError     = "error" // See Errors; nothing is written while any row has errors
+)
+
+// RowResult is what happened (or, in a dry run, would happen) to one row
+type RowResult struct {
+	Line      int      `json:"line"`
+	Action    string   `json:"action"`
+	ProductID string   `json:"productId,omitempty"`
+	Name      string   `json:"name"`
+	Changed   []string `json:"changed,omitempty"` // Fields an update changes
+	Errors    []string `json:"errors,omitempty"`
+	Written   bool     `json:"written,omitempty"` // Stored (each row is written on its own)
+}
+
+// Report summarizes an import
+type Report struct {
+	DryRun    bool        `json:"dryRun"`
+	Applied   bool        `json:"applied"`             // False in a dry run, when rows have errors or when writing stopped
+	StoppedAt int         `json:"stoppedAt,omitempty"` // Line whose write failed; the rows marked written stay written
+	Rows      []RowResult `json:"rows"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Errors    int         `json:"errors"`
+}
+
+// ActiveCheck says whether the caller may archive or restore a product
+// through an import; nil = yes
+type ActiveCheck func(p *models.Product) error
+
+// Service imports and exports the catalog through a store. Give it a
+// store from WithActor so the audit trail shows who imported.
+type Service struct {
+	store repository.Repository
+}
+
+// NewService creates a catalog service
+func NewService(store repository.Repository) *Service {
+	return &Service{store: store}
+}
+
+// productKey is what makes a product unique: brand + size + container
+func productKey(brand string, size int, containerType string) string {
+	return fmt.Sprintf("%s|%d|%s", strings.ToLower(strings.TrimSpace(brand)), size,
+		strings.ToLower(strings.TrimSpace(containerType)))
+}
+
+// Import checks every row with Product.Validate and upserts the products
+// by brand + size + container. With errors in any row, or with dryRun,
+// nothing is written; the report tells what would happen to each row.
+// Rows are written one by one, not in one transaction: when the store
+// fails on a row, the error comes back with the report, whose Written
+// rows stay written and whose StoppedAt is the failed line.
+// A row that would archive or restore a product is an error unless check
+// allows it (nil allows everything).
+func (s *Service) Import(rows []Row, dryRun bool, check ActiveCheck) (*Report, error) {
+	// A failed read must stop the import: every row would look new
+	all, err := s.store.ListProductsChangedSince(time.Time{}) // Deleted ones too
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]*models.Product{}
+	for _, p := range all {
+		existing[productKey(p.Brand, p.Size, p.ContainerType)] = p
+	}
+
+	report := &Report{DryRun: dryRun, Rows: make([]RowResult, 0, len(rows))}
+	planned := make([]*models.Product, len(rows)) // nil = nothing to write
+	seen := map[string]int{}                      // key → line
+	for i, row := range rows {
+		p := row.product()
+		res := RowResult{Line: row.Line, Name: p.Name, Errors: row.problems}
+		if err := p.Validate(s.store); err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		if p.BoxSize < 0 {
+			res.Errors = append(res.Errors, "box size cannot be negative")
+		}
+		key := productKey(p.Brand, p.Size, p.ContainerType)
+		if line, dup := seen[key]; dup {
+			res.Errors = append(res.Errors, fmt.Sprintf("same brand, size and container as line %d", line))
+		}
+		seen[key] = row.Line
+
+		// A new product that starts inactive is archived right away
+		current := existing[key]
+		changesActive := (current == nil && !p.IsActive) ||
+			(current != nil && row.IsActive != nil && *row.IsActive != current.IsActive)
+		if changesActive && check != nil {
+			if err := check(p); err != nil {
+				res.Errors = append(res.Errors, err.Error())
+			}
+		}
+
+		switch {
+		case len(res.Errors) > 0:
+			res.Action = ActionError
+			report.Errors++
+		case current == nil:
+			res.Action = ActionCreate
+			planned[i] = p
+			report.Created++
+		default:
+			p.ID, p.Version = current.ID, current.Version
+			if row.IsActive == nil {
+				p.IsActive = current.IsActive
+			}
+			res.ProductID = current.ID
+			for _, c := range models.DiffFields(current, p) {
+				res.Changed = append(res.Changed, c.Field)
+			}
+			if len(res.Changed) == 0 {
+				res.Action = ActionUnchanged
+				report.Unchanged++
+			} else {
+				res.Action = ActionUpdate
+				planned[i] = p
+				report.Updated++
+			}
+		}
+		report.Rows = append(report.Rows, res)
+	}
+	if dryRun || report.Errors > 0 {
+		return report, nil
+	}
+
+	for i, p := range planned {
+		if p == nil {
+			continue
+		}
+		if err := s.write(p); err != nil {
+			report.StoppedAt = rows[i].Line
+			return report, fmt.Errorf("line %d: %w", rows[i].Line, err)
+		}
+		report.Rows[i].ProductID, report.Rows[i].Written = p.ID, true
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// write creates or updates one product
+func (s *Service) write(p *models.Product) error {
+	if p.ID != "" {
+		return s.store.UpdateProduct(p)
+	}
+	active := p.IsActive
+	p.IsActive = true
+	id, err := s.store.AddProduct(p)
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	if !active {
+		return s.store.DeleteProduct(id, 0)
+	}
+	return nil
+}
+
+// product turns a row into a product (trimmed; active unless it says no)
+func (row Row) product() *models.Product {
+	p := &models.Product{
+		Name:          strings.TrimSpace(row.Name),
+		Brand:         strings.TrimSpace(row.Brand),
+		Size:          row.Size,
+		ContainerType: strings.TrimSpace(row.ContainerType),
+		BoxSize:       row.BoxSize,
+		Price:         row.Price,
+		Category:      strings.ToLower(strings.TrimSpace(row.Category)),
+		IsActive:      true,
+	}
+	if row.IsActive != nil {
+		p.IsActive = *row.IsActive
+	}
+	return p
+}
+
+// Export returns the catalog as rows, by category and name.
+// includeInactive adds deleted products.
+func (s *Service) Export(includeInactive bool) ([]Row, error) {
+	var products []*models.Product
+	if includeInactive {
+		var err error
+		if products, err = s.store.ListProductsChangedSince(time.Time{}); err != nil {
+			return nil, err
+		}
+	} else {
+		products = s.store.ListProducts()
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Category != products[j].Category {
+			return products[i].Category < products[j].Category
+		}
+		if products[i].Name != products[j].Name {
+			return products[i].Name < products[j].Name
+		}
+		return products[i].ID < products[j].ID
+	})
+
+	rows := make([]Row, 0, len(products))
+	for _, p := range products {
+		active := p.IsActive
+		rows = append(rows, Row{
+			ID:            p.ID,
+			Name:          p.Name,
+			Brand:         p.Brand,
+			Size:          p.Size,
+			ContainerType: p.ContainerType,
+			BoxSize:       p.BoxSize,
+			Price:         p.Price,
+			Category:      p.Category,
+			IsActive:      &active,
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider talks to any OpenAI-compatible /chat/completions API.
+// That covers OpenAI itself and a local Ollama (http://localhost:11434/v1).
+type OpenAIProvider struct {
+	BaseURL string // e.g. "https://api.openai.com/v1"
+	APIKey  string // Empty for Ollama
+	Model   string // e.g. "gpt-4o-mini", "llama3.1"
+	Client  *http.Client
+}
+
+// NewOpenAIProvider creates a provider with a sensible HTTP timeout
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Wire format of the OpenAI chat completions API
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded as a string
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function Tool   `json:"function"`
+}
+
+type openAIRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Complete sends the conversation and returns the model's next message
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (Message, error) {
+	body := openAIRequest{Model: p.Model}
+	for _, m := range req.Messages {
+		om := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, tc := range m.ToolCalls {
+			otc := openAIToolCall{ID: tc.ID, Type: "function"}
+			otc.Function.Name = tc.Name
+			otc.Function.Arguments = string(tc.Arguments)
+			om.ToolCalls = append(om.ToolCalls, otc)
+		}
+		body.Messages = append(body.Messages, om)
+	}
+	for _, t := range req.Tools {
+		body.Tools = append(body.Tools, openAITool{Type: "function", Function: t})
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Message{}, fmt.Errorf("encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return Message{}, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := p.Client.Do(httpReq)
+	if err != nil {
+		return Message{}, fmt.Errorf("call provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Message{}, fmt.Errorf("decode response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := resp.Status
+		if out.Error != nil {
+			msg = out.Error.Message
+		}
+		return Message{}, fmt.Errorf("provider error: %s", msg)
+	}
+	if len(out.Choices) == 0 {
+		return Message{}, fmt.Errorf("provider returned no choices")
+	}
+
+	om := out.Choices[0].Message
+	msg := Message{Role: RoleAssistant, Content: om.Content}
+	for _, tc := range om.ToolCalls {
+		args := json.RawMessage(tc.Function.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: args})
+	}
+	return msg, nil
+}
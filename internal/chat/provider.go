@@ -0,0 +1,53 @@
+// Package chat implements the AI assistant behind POST /chat.
+// The assistant talks to an LLM through the Provider interface, can look
+// up products and stock through tools, and may only PROPOSE movements.
+// Nothing is written until a person confirms the draft.
+package chat
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Message roles, same names the common LLM APIs use
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one turn of the conversation
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`  // Assistant asks to run tools
+	ToolCallID string     `json:"toolCallId,omitempty"` // Tool result answers this call
+}
+
+// ToolCall is the model asking us to run one tool
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Tool describes a function the model may call
+// Parameters is a JSON schema object
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// Request is everything a provider needs for one completion
+type Request struct {
+	Messages []Message
+	Tools    []Tool
+}
+
+// Provider is an LLM backend (OpenAI, Ollama, a scripted fake...)
+// It returns the next assistant message: text, tool calls, or both.
+type Provider interface {
+	Complete(ctx context.Context, req Request) (Message, error)
+}
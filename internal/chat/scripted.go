@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrScriptExhausted is returned when a ScriptedProvider has no replies left
+var ErrScriptExhausted = errors.New("scripted provider has no more replies")
+
+// ScriptedProvider replays a fixed list of assistant messages in order.
+// It needs no model or network, which makes it ideal for tests and demos.
+type ScriptedProvider struct {
+	mu       sync.Mutex
+	replies  []Message
+	requests []Request // Every request received, for assertions
+}
+
+// NewScriptedProvider creates a provider that returns replies one by one
+func NewScriptedProvider(replies ...Message) *ScriptedProvider {
+	return &ScriptedProvider{replies: replies}
+}
+
+// Complete returns the next scripted reply
+func (p *ScriptedProvider) Complete(ctx context.Context, req Request) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	if len(p.replies) == 0 {
+		return Message{}, ErrScriptExhausted
+	}
+	next := p.replies[0]
+	p.replies = p.replies[1:]
+	return next, nil
+}
+
+// Requests returns the requests received so far
+func (p *ScriptedProvider) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Request(nil), p.requests...)
+}
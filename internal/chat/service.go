@@ -0,0 +1,302 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+var (
+	ErrEmptyMessage    = errors.New("message is required")
+	ErrUserRequired    = errors.New("user is required")
+	ErrSessionNotFound = errors.New("chat session not found")
+	ErrDraftNotFound   = errors.New("draft not found")
+	ErrDraftExpired    = errors.New("draft expired")
+	ErrTooManySteps    = errors.New("assistant did not finish answering")
+	ErrProductInactive = repository.ErrProductInactive
+	ErrMovementType    = errors.New("assistant may only propose IN, OUT or WASTE movements")
+)
+
+const (
+	// maxToolSteps stops a model that keeps calling tools forever
+	maxToolSteps = 6
+	// DraftTTL is how long a proposed movement waits for confirmation
+	DraftTTL = 15 * time.Minute
+	// sessionTTL drops idle conversations
+	sessionTTL = 2 * time.Hour
+)
+
+// systemPrompt carries the domain rules from the project instructions
+const systemPrompt = `You are the inventory assistant of a pizza & falafel restaurant.
+Staff write in Hebrew or English; always answer in the language of the user.
+Rules:
+- Use search_products and get_stock to look things up. Never invent product ids.
+- NEVER change stock yourself. Use propose_movement; the user must confirm the draft.
+- Only propose when product, movement type and quantity are 100% clear.
+  If anything is ambiguous (several matching products, boxes vs units), ASK.
+- Every movement needs WHO did it. If no name is mentioned, the user did it.
+- Stock is counted in full boxes and loose units separately.`
+
+// Draft is a movement proposed by the assistant, waiting for confirmation
+type Draft struct {
+	ID          string               `json:"id"`
+	SessionID   string               `json:"sessionId"`
+	Movement    models.StockMovement `json:"movement"`
+	ProductName string               `json:"productName"`
+	Summary     string               `json:"summary"`
+	User        string               `json:"user"` // Who has to confirm it
+	ExpiresAt   time.Time            `json:"expiresAt"`
+}
+
+// Reply is the assistant's answer to one user message
+type Reply struct {
+	SessionID string  `json:"sessionId"`
+	Message   string  `json:"message"`
+	Drafts    []Draft `json:"drafts,omitempty"` // Proposals the user must confirm
+}
+
+// session is one conversation's history
+type session struct {
+	mu       sync.Mutex
+	user     string
+	messages []Message
+	lastUsed time.Time
+}
+
+// Service runs conversations and keeps drafts until they are confirmed
+type Service struct {
+	store    repository.Repository
+	provider Provider
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	drafts   map[string]*Draft
+}
+
+// NewService creates a chat service using the given store and LLM provider
+func NewService(store repository.Repository, provider Provider) *Service {
+	return &Service{
+		store:    store,
+		provider: provider,
+		sessions: make(map[string]*session),
+		drafts:   make(map[string]*Draft),
+	}
+}
+
+// newID returns a random, hard to guess ID with a readable prefix
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + "-" + hex.EncodeToString(b)
+}
+
+// Chat sends one user message and runs the tool loop until the model answers.
+// An empty sessionID starts a new conversation. When the turn fails after
+// the model proposed movements, the reply still comes back with the
+// error, so the user gets the draft IDs to confirm or cancel.
+func (s *Service) Chat(ctx context.Context, sessionID, user, text string) (*Reply, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyMessage
+	}
+	if user == "" {
+		return nil, ErrUserRequired
+	}
+
+	sessionID, sess, err := s.session(sessionID, user)
+	if err != nil {
+		return nil, err
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.messages = append(sess.messages, Message{Role: RoleUser, Content: text})
+	reply := &Reply{SessionID: sessionID}
+
+	for step := 0; step < maxToolSteps; step++ {
+		msgs := append([]Message{{Role: RoleSystem, Content: systemPrompt}}, sess.messages...)
+		msg, err := s.provider.Complete(ctx, Request{Messages: msgs, Tools: tools})
+		if err != nil {
+			return partial(reply), fmt.Errorf("assistant: %w", err)
+		}
+		msg.Role = RoleAssistant
+		sess.messages = append(sess.messages, msg)
+
+		if len(msg.ToolCalls) == 0 {
+			reply.Message = msg.Content
+			return reply, nil
+		}
+
+		for _, call := range msg.ToolCalls {
+			result, draft := s.runTool(call, sessionID, user)
+			sess.messages = append(sess.messages, Message{Role: RoleTool, Content: result, ToolCallID: call.ID})
+			if draft != nil {
+				reply.Drafts = append(reply.Drafts, *draft)
+			}
+		}
+	}
+
+	return partial(reply), ErrTooManySteps
+}
+
+// partial is the reply of a failed turn: nil unless it made drafts
+func partial(reply *Reply) *Reply {
+	if len(reply.Drafts) == 0 {
+		return nil
+	}
+	return reply
+}
+
+// session returns an existing conversation of this user or starts a new one
+func (s *Service) session(id, user string) (string, *session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+
+	if id == "" {
+		id = newID("CHAT")
+		s.sessions[id] = &session{user: user, lastUsed: time.Now()}
+	}
+	sess, ok := s.sessions[id]
+	if !ok || sess.user != user {
+		return "", nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	sess.lastUsed = time.Now()
+	return id, sess, nil
+}
+
+// pruneLocked drops idle sessions and expired drafts (caller holds s.mu)
+func (s *Service) pruneLocked() {
+	now := time.Now()
+	for id, sess := range s.sessions {
+		if now.Sub(sess.lastUsed) > sessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+	for id, d := range s.drafts {
+		if now.After(d.ExpiresAt) {
+			delete(s.drafts, id)
+		}
+	}
+}
+
+// propose validates a movement suggested by the model and stores it as a draft
+func (s *Service) propose(sessionID, user, productID, movementType string, boxes, units int, performedBy, reason string) (*Draft, error) {
+	switch movementType {
+	case models.MovementIn, models.MovementOut, models.MovementWaste:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrMovementType, movementType)
+	}
+
+	product, err := s.store.GetProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, fmt.Errorf("%w: %s", ErrProductInactive, productID)
+	}
+
+	// Nobody named = the user did it (self-reported)
+	if performedBy == "" {
+		performedBy = user
+	}
+	m, err := models.NewStockMovement(productID, movementType, boxes, units, performedBy, user, reason)
+	if err != nil {
+		return nil, err
+	}
+	m.Source = models.SourceAssistant
+
+	draft := &Draft{
+		ID:          newID("DRAFT"),
+		SessionID:   sessionID,
+		Movement:    *m,
+		ProductName: product.Name,
+		Summary:     fmt.Sprintf("%s %d boxes + %d units of %s (%s), performed by %s", m.Type, m.Boxes, m.Units, product.Name, product.ID, m.PerformedBy),
+		User:        user,
+		ExpiresAt:   time.Now().Add(DraftTTL),
+	}
+
+	s.mu.Lock()
+	s.drafts[draft.ID] = draft
+	s.mu.Unlock()
+
+	return draft, nil
+}
+
+// Draft returns a pending draft of the given user
+func (s *Service) Draft(id, user string) (*Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.draftLocked(id, user)
+	if err != nil {
+		return nil, err
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// draftLocked finds a live draft owned by user (caller holds s.mu)
+func (s *Service) draftLocked(id, user string) (*Draft, error) {
+	d, ok := s.drafts[id]
+	if !ok || d.User != user {
+		return nil, fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	if time.Now().After(d.ExpiresAt) {
+		delete(s.drafts, id)
+		return nil, fmt.Errorf("%w: %s", ErrDraftExpired, id)
+	}
+	return d, nil
+}
+
+// Confirm records a draft as a real movement.
+// The movement keeps Source=assistant and ReportedBy=the confirming user,
+// so the audit trail shows the change came through the assistant.
+// The draft is taken out while the movement is written, so a slow store
+// doesn't hold up other conversations and two confirms can't both write.
+func (s *Service) Confirm(id, user string) (*models.StockMovement, error) {
+	s.mu.Lock()
+	d, err := s.draftLocked(id, user)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	delete(s.drafts, id)
+	s.mu.Unlock()
+
+	m := d.Movement
+	m.ID = ""
+	m.ReportedBy = user
+	m.Source = models.SourceAssistant
+	m.CreatedAt = time.Now()
+	if _, err := s.store.RecordMovement(&m); err != nil {
+		// Put the draft back so the user can retry (e.g. after a delivery arrives)
+		s.mu.Lock()
+		s.drafts[id] = d
+		s.mu.Unlock()
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Cancel throws a draft away without touching stock
+func (s *Service) Cancel(id, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.draftLocked(id, user); err != nil {
+		return err
+	}
+	delete(s.drafts, id)
+	return nil
+}
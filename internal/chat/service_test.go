@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func TestChatProposeAndConfirm(t *testing.T) {
+	store := repository.NewMemoryStore()
+	id, err := store.AddProduct(&models.Product{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	if err := store.UpdateStock(id, 5, 0); err != nil {
+		t.Fatalf("UpdateStock failed: %v", err)
+	}
+
+	provider := NewScriptedProvider(
+		Message{ToolCalls: []ToolCall{{ID: "c1", Name: ToolSearchProducts, Arguments: json.RawMessage(`{"query":"קולה"}`)}}},
+		Message{ToolCalls: []ToolCall{{ID: "c2", Name: ToolProposeMovement, Arguments: json.RawMessage(`{"productId":"` + id + `","type":"OUT","boxes":2,"performedBy":"יוסף"}`)}}},
+		Message{Content: "לאשר הוצאה של 2 ארגזים קולה ע״י יוסף?"},
+	)
+	svc := NewService(store, provider)
+
+	reply, err := svc.Chat(context.Background(), "", "Dani", "יוסף לקח 2 ארגזים קולה")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if reply.SessionID == "" || len(reply.Drafts) != 1 {
+		t.Fatalf("expected a session and one draft, got %+v", reply)
+	}
+
+	// Tool results were fed back to the model
+	reqs := provider.Requests()
+	last := reqs[len(reqs)-1].Messages
+	if last[len(last)-1].Role != RoleTool || last[len(last)-1].ToolCallID != "c2" {
+		t.Fatalf("expected last message to be the propose_movement result, got %+v", last[len(last)-1])
+	}
+
+	// Nothing written before confirmation
+	st, _ := store.GetStock(id)
+	if st.QuantityBoxes != 5 || len(store.ListMovements(repository.MovementFilter{})) != 0 {
+		t.Fatalf("stock changed before confirmation: %+v", st)
+	}
+
+	draft := reply.Drafts[0]
+	if _, err := svc.Confirm(draft.ID, "Moshe"); !errors.Is(err, ErrDraftNotFound) {
+		t.Fatalf("expected another user to be refused, got %v", err)
+	}
+
+	m, err := svc.Confirm(draft.ID, "Dani")
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if m.Source != models.SourceAssistant || m.ReportedBy != "Dani" || m.PerformedBy != "יוסף" {
+		t.Fatalf("unexpected audit fields: %+v", m)
+	}
+	st, _ = store.GetStock(id)
+	if st.QuantityBoxes != 3 {
+		t.Fatalf("expected 3 boxes after confirm, got %+v", st)
+	}
+
+	// A draft can only be used once
+	if _, err := svc.Confirm(draft.ID, "Dani"); !errors.Is(err, ErrDraftNotFound) {
+		t.Fatalf("expected second confirm to fail, got %v", err)
+	}
+}
+
+func TestChatToolErrorsGoBackToModel(t *testing.T) {
+	provider := NewScriptedProvider(
+		Message{ToolCalls: []ToolCall{{ID: "c1", Name: ToolProposeMovement, Arguments: json.RawMessage(`{"productId":"PROD-404","type":"ADJUSTMENT","units":1}`)}}},
+		Message{Content: "לא מצאתי את המוצר"},
+	)
+	svc := NewService(repository.NewMemoryStore(), provider)
+
+	reply, err := svc.Chat(context.Background(), "", "Dani", "תתקן מלאי")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(reply.Drafts) != 0 {
+		t.Fatalf("expected no drafts, got %+v", reply.Drafts)
+	}
+	msgs := provider.Requests()[1].Messages
+	var result map[string]string
+	if err := json.Unmarshal([]byte(msgs[len(msgs)-1].Content), &result); err != nil || result["error"] == "" {
+		t.Fatalf("expected tool error for the model, got %q", msgs[len(msgs)-1].Content)
+	}
+
+	if _, err := svc.Chat(context.Background(), reply.SessionID, "Moshe", "hi"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected other user's session to be hidden, got %v", err)
+	}
+}
+
+func TestChatUnfinishedTurnKeepsDrafts(t *testing.T) {
+	store := repository.NewMemoryStore()
+	id, err := store.AddProduct(&models.Product{Name: "Cola", Size: 330, BoxSize: 24, Price: 5.5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	propose := Message{ToolCalls: []ToolCall{{ID: "c1", Name: ToolProposeMovement, Arguments: json.RawMessage(`{"productId":"` + id + `","type":"IN","boxes":1}`)}}}
+	var replies []Message
+	for i := 0; i < maxToolSteps; i++ {
+		replies = append(replies, propose)
+	}
+	svc := NewService(store, NewScriptedProvider(replies...))
+
+	reply, err := svc.Chat(context.Background(), "", "Dani", "got cola")
+	if !errors.Is(err, ErrTooManySteps) {
+		t.Fatalf("expected ErrTooManySteps, got %v", err)
+	}
+	if reply == nil || len(reply.Drafts) != maxToolSteps {
+		t.Fatalf("expected the drafts of the broken turn, got %+v", reply)
+	}
+	if _, err := svc.Confirm(reply.Drafts[0].ID, "Dani"); err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+}
+
+func TestChatConfirmFailureKeepsDraft(t *testing.T) {
+	store := repository.NewMemoryStore()
+	id, err := store.AddProduct(&models.Product{Name: "Cola", Size: 330, BoxSize: 24, Price: 5.5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	provider := NewScriptedProvider(
+		Message{ToolCalls: []ToolCall{{ID: "c1", Name: ToolProposeMovement, Arguments: json.RawMessage(`{"productId":"` + id + `","type":"OUT","boxes":2}`)}}},
+		Message{Content: "ok?"},
+	)
+	svc := NewService(store, provider)
+	reply, err := svc.Chat(context.Background(), "", "Dani", "took 2 cola")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	draft := reply.Drafts[0]
+
+	// No stock yet: the write fails and the draft stays
+	if _, err := svc.Confirm(draft.ID, "Dani"); !errors.Is(err, repository.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+	if err := store.UpdateStock(id, 5, 0); err != nil {
+		t.Fatalf("UpdateStock failed: %v", err)
+	}
+	if _, err := svc.Confirm(draft.ID, "Dani"); err != nil {
+		t.Fatalf("retry after failure failed: %v", err)
+	}
+}
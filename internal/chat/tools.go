@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// Tool names the model can call
+const (
+	ToolSearchProducts  = "search_products"
+	ToolGetStock        = "get_stock"
+	ToolProposeMovement = "propose_movement"
+)
+
+// tools are offered to the model on every request.
+// Only read tools and propose_movement exist - the model has no way
+// to write to the database by itself.
+var tools = []Tool{
+	{
+		Name:        ToolSearchProducts,
+		Description: "Search active products by Hebrew or English name or brand. Returns id, name, brand, box size and price.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "Words from the product name or brand"},
+			},
+			"required": []string{"query"},
+		},
+	},
+	{
+		Name:        ToolGetStock,
+		Description: "Get current stock (boxes, loose units, total units, minimum) for one product id.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"productId": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"productId"},
+		},
+	},
+	{
+		Name: ToolProposeMovement,
+		Description: "Propose a stock movement for the user to confirm. Does NOT change stock. " +
+			"Only call it when product, type and quantity are certain.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"productId":   map[string]interface{}{"type": "string"},
+				"type":        map[string]interface{}{"type": "string", "enum": []string{models.MovementIn, models.MovementOut, models.MovementWaste}},
+				"boxes":       map[string]interface{}{"type": "integer", "minimum": 0},
+				"units":       map[string]interface{}{"type": "integer", "minimum": 0},
+				"performedBy": map[string]interface{}{"type": "string", "description": "Who did it; empty if the user did it themselves"},
+				"reason":      map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"productId", "type"},
+		},
+	},
+}
+
+// productSummary is what the model sees about a product
+type productSummary struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Brand    string  `json:"brand"`
+	BoxSize  int     `json:"boxSize"`
+	Price    float64 `json:"price"`
+	Category string  `json:"category"`
+}
+
+// runTool executes one tool call and returns the JSON result for the model.
+// Errors are returned to the model as {"error": ...} so it can recover
+// (e.g. ask the user) instead of failing the whole chat.
+func (s *Service) runTool(call ToolCall, sessionID, user string) (string, *Draft) {
+	result, draft, err := s.dispatchTool(call, sessionID, user)
+	if err != nil {
+		result = map[string]string{"error": err.Error()}
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return `{"error":"cannot encode tool result"}`, nil
+	}
+	return string(out), draft
+}
+
+// dispatchTool routes a call to its implementation
+func (s *Service) dispatchTool(call ToolCall, sessionID, user string) (interface{}, *Draft, error) {
+	switch call.Name {
+	case ToolSearchProducts:
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		found := s.store.SearchProducts(args.Query)
+		res := make([]productSummary, 0, len(found))
+		for _, p := range found {
+			res = append(res, productSummary{ID: p.ID, Name: p.Name, Brand: p.Brand, BoxSize: p.BoxSize, Price: p.Price, Category: p.Category})
+		}
+		return res, nil, nil
+
+	case ToolGetStock:
+		var args struct {
+			ProductID string `json:"productId"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		product, err := s.store.GetProduct(args.ProductID)
+		if err != nil {
+			return nil, nil, err
+		}
+		stock, err := s.store.GetStock(args.ProductID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return map[string]interface{}{
+			"productId":  stock.ProductID,
+			"name":       product.Name,
+			"boxes":      stock.QuantityBoxes,
+			"units":      stock.QuantityUnits,
+			"totalUnits": stock.TotalUnits(product.BoxSize),
+			"minStock":   stock.MinStock,
+			"isLow":      stock.IsLowStock(product.BoxSize),
+		}, nil, nil
+
+	case ToolProposeMovement:
+		var args struct {
+			ProductID   string `json:"productId"`
+			Type        string `json:"type"`
+			Boxes       int    `json:"boxes"`
+			Units       int    `json:"units"`
+			PerformedBy string `json:"performedBy"`
+			Reason      string `json:"reason"`
+		}
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return nil, nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		draft, err := s.propose(sessionID, user, args.ProductID, args.Type, args.Boxes, args.Units, args.PerformedBy, args.Reason)
+		if err != nil {
+			return nil, nil, err
+		}
+		return map[string]interface{}{
+			"draftId": draft.ID,
+			"summary": draft.Summary,
+			"status":  "waiting for user confirmation - stock NOT changed yet",
+		}, draft, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown tool %q", call.Name)
+	}
+}
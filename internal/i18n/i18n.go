@@ -0,0 +1,157 @@
+// Package i18n holds the Hebrew and English texts of API messages.
+// Every message has a stable code ("product_not_found") that clients can
+// match on; the text shown to people is picked by language.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages
+const (
+	Hebrew  = "he"
+	English = "en"
+)
+
+// Default is the language used when the request doesn't ask for one.
+// Error texts were English before they were translated, so clients that
+// never send Accept-Language keep getting the same messages.
+const Default = English
+
+// Supported reports whether lang is a language we have texts for
+func Supported(lang string) bool {
+	return lang == Hebrew || lang == English
+}
+
+// Message is one catalog entry
+type Message struct {
+	En string
+	He string
+}
+
+// byEnglish finds a code by its English text, so errors that only carry
+// their text (err.Error()) can still be translated
+var byEnglish = func() map[string]string {
+	m := make(map[string]string, len(messages))
+	for code, msg := range messages {
+		m[msg.En] = code
+	}
+	return m
+}()
+
+// T returns the text of a message code in lang. A missing translation
+// falls back to English, an unknown code to the code itself.
+func T(lang, code string) string {
+	msg, ok := messages[code]
+	if !ok {
+		return code
+	}
+	if lang == Hebrew && msg.He != "" {
+		return msg.He
+	}
+	return msg.En
+}
+
+// Localize translates an English error text into lang. Wrapped errors
+// read "outer: inner: detail"; every part found in the catalog is
+// translated and the rest (IDs, names) is kept. code is the code of the
+// innermost known part, the most specific one, or "" if none is known.
+func Localize(lang, text string) (code, localized string) {
+	var parts []string
+	rest := text
+	for rest != "" {
+		if c, tail, ok := matchPrefix(rest); ok {
+			code = c
+			parts = append(parts, T(lang, c))
+			rest = tail
+			continue
+		}
+		// Unknown part: keep it up to the next separator
+		i := strings.Index(rest, ": ")
+		if i < 0 {
+			parts = append(parts, rest)
+			break
+		}
+		parts = append(parts, rest[:i])
+		rest = rest[i+2:]
+	}
+	return code, strings.Join(parts, ": ")
+}
+
+// LocalizeCode is Localize for a text whose error is known by code: the
+// part of text that is own (the error's English message) becomes the
+// text of code in lang without being looked up by its English text, so a
+// reworded error keeps its code and translation. The rest of text is
+// translated as Localize does.
+func LocalizeCode(lang, code, own, text string) string {
+	i := strings.Index(text, own)
+	if own == "" || i < 0 {
+		_, localized := Localize(lang, text)
+		return localized
+	}
+	var parts []string
+	if before := strings.TrimSuffix(text[:i], ": "); before != "" {
+		_, localized := Localize(lang, before)
+		parts = append(parts, localized)
+	}
+	parts = append(parts, T(lang, code))
+	after := text[i+len(own):]
+	if rest, ok := strings.CutPrefix(after, ": "); ok {
+		_, localized := Localize(lang, rest)
+		parts = append(parts, localized)
+	} else {
+		parts[len(parts)-1] += after
+	}
+	return strings.Join(parts, ": ")
+}
+
+// matchPrefix finds the longest catalog text that s starts with, ending at
+// the end of s or at a ": " separator
+func matchPrefix(s string) (code, tail string, ok bool) {
+	if c, ok := byEnglish[s]; ok {
+		return c, "", true
+	}
+	for i := strings.LastIndex(s, ": "); i > 0; i = strings.LastIndex(s[:i], ": ") {
+		if c, ok := byEnglish[s[:i]]; ok {
+			return c, s[i+2:], true
+		}
+	}
+	return "", "", false
+}
+
+// Negotiate picks the best supported language from an Accept-Language
+// header ("he-IL,he;q=0.9,en;q=0.8"). It returns "" when the header asks
+// for nothing we have.
+func Negotiate(acceptLanguage string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "iw" { // Old code for Hebrew, still sent by some devices
+			primary = Hebrew
+		}
+		if Supported(primary) && q > 0 {
+			choices = append(choices, choice{primary, q})
+		}
+	}
+	if len(choices) == 0 {
+		return ""
+	}
+	// Stable: equal weights keep the order of the header
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
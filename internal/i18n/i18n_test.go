@@ -0,0 +1,86 @@
+package i18n
+
+import "testing"
+
+func TestCatalog(t *testing.T) {
+	seen := map[string]string{}
+	for code, msg := range messages {
+		if msg.En == "" || msg.He == "" {
+			t.Errorf("%s: needs both an English and a Hebrew text", code)
+		}
+		if other, ok := seen[msg.En]; ok {
+			t.Errorf("%s and %s have the same English text %q", code, other, msg.En)
+		}
+		seen[msg.En] = code
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(Hebrew, "product_not_found"); got != "המוצר לא נמצא" {
+		t.Errorf("T(he) = %q", got)
+	}
+	if got := T(English, "product_not_found"); got != "product not found" {
+		t.Errorf("T(en) = %q", got)
+	}
+	if got := T(Hebrew, "no_such_code"); got != "no_such_code" {
+		t.Errorf("unknown code = %q, want the code", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		lang, text, code, want string
+	}{
+		{Hebrew, "product not found: PROD-001", "product_not_found", "המוצר לא נמצא: PROD-001"},
+		{Hebrew, "validation failed: invalid product category: nope", "product_invalid_category", "האימות נכשל: קטגוריית מוצר לא תקינה: nope"},
+		{Hebrew, "user USR-1: user already exists", "user_exists", "user USR-1: המשתמש כבר קיים"},
+		{Hebrew, "backup is damaged: checksum does not match", "backup_checksum", "הגיבוי פגום: סכום הביקורת לא תואם"},
+		{Hebrew, "something else", "", "something else"},
+		{English, "validation failed: product name is required", "product_name_required", "validation failed: product name is required"},
+	}
+	for _, tt := range tests {
+		code, got := Localize(tt.lang, tt.text)
+		if code != tt.code || got != tt.want {
+			t.Errorf("Localize(%s, %q) = %q, %q; want %q, %q", tt.lang, tt.text, code, got, tt.code, tt.want)
+		}
+	}
+}
+
+// The code decides the text, even when the error was reworded and its
+// English text no longer matches the catalog
+func TestLocalizeCode(t *testing.T) {
+	tests := []struct {
+		lang, code, own, text, want string
+	}{
+		{Hebrew, "product_not_found", "product not found", "product not found: PROD-001", "המוצר לא נמצא: PROD-001"},
+		{Hebrew, "product_invalid_price", "price must not be negative", "validation failed: price must not be negative", "האימות נכשל: מחיר המוצר לא יכול להיות שלילי"},
+		{English, "product_invalid_price", "price must not be negative", "validation failed: price must not be negative: -1", "validation failed: product price cannot be negative: -1"},
+		{Hebrew, "backup_checksum", "backup is damaged: checksum does not match", "backup is damaged: checksum does not match", "הגיבוי פגום: סכום הביקורת לא תואם"},
+		{Hebrew, "user_exists", "not in the text", "user USR-1: user already exists", "user USR-1: המשתמש כבר קיים"},
+	}
+	for _, tt := range tests {
+		if got := LocalizeCode(tt.lang, tt.code, tt.own, tt.text); got != tt.want {
+			t.Errorf("LocalizeCode(%s, %s, %q) = %q; want %q", tt.lang, tt.code, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"he":                      Hebrew,
+		"he-IL,he;q=0.9,en;q=0.8": Hebrew,
+		"en-US,en;q=0.9,he;q=0.8": English,
+		"fr-FR, en;q=0.5":         English,
+		"iw":                      Hebrew,
+		"en;q=0.2, he;q=0.7":      Hebrew,
+		"he;q=0, en":              English,
+		"fr, de":                  "",
+		"*":                       "",
+	}
+	for header, want := range tests {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package i18n
+
+// messages is the catalog, by code. Errors the API maps carry their code
+// (api errorRules); other texts are found by En, so En must be the exact
+// text of the Go error or of the message the handler sends. Change both
+// together (a test in api checks the mapped errors).
+var messages = map[string]Message{
+	// Request errors (api)
+	"internal_error":           {En: "something went wrong on the server, please try again", He: "משהו השתבש בשרת, נא לנסות שוב"},
+	"invalid_fields":           {En: "some fields are not valid", He: "חלק מהשדות אינם תקינים"},
+	"field_required":           {En: "is required", He: "שדה חובה"},
+	"field_negative":           {En: "cannot be negative", He: "לא יכול להיות שלילי"},
+	"field_read_only":          {En: "cannot be changed", He: "לא ניתן לשנות"},
+	"field_wrong_type":         {En: "has the wrong type", He: "מסוג שגוי"},
+	"invalid_json":             {En: "Request body must be valid JSON", He: "גוף הבקשה חייב להיות JSON תקין"},
+	"invalid_json_object":      {En: "Request body must be a JSON object", He: "גוף הבקשה חייב להיות אובייקט JSON"},
+	"invalid_body":             {En: "could not read request body", He: "לא ניתן לקרוא את גוף הבקשה"},
+	"is_active_forbidden":      {En: "changing isActive needs permission to delete products", He: "שינוי isActive דורש הרשאה למחיקת מוצרים"},
+	"precondition_required":    {En: "If-Match header with the ETag from GET is required", He: "חובה לשלוח כותרת If-Match עם ה-ETag שהתקבל ב-GET"},
+	"if_match_invalid":         {En: "If-Match must be a single ETag from GET", He: "If-Match חייב להכיל ETag יחיד שהתקבל ב-GET"},
+	"product_no_longer_active": {En: "product is no longer active", He: "המוצר כבר אינו פעיל"},
+	"file_too_large":           {En: "invoice file is larger than 5 MB", He: "קובץ החשבונית גדול מ-5MB"},
+	"auth_disabled":            {En: "authentication is disabled", He: "ההזדהות כבויה"},
+	"chat_disabled":            {En: "AI assistant is not configured", He: "העוזר החכם לא הוגדר"},
+	"api_key_not_allowed":      {En: "not available for API keys", He: "לא זמין למפתחות API"},
+	"missing_bearer_token":     {En: "missing bearer token", He: "חסר אסימון גישה"},
+	"idempotency_key_too_long": {En: "Idempotency-Key is too long", He: "Idempotency-Key ארוך מדי"},
+	"idempotency_in_progress":  {En: "a request with this Idempotency-Key is still running", He: "בקשה עם Idempotency-Key זה עדיין מתבצעת"},
+	"idempotency_too_large":    {En: "request body is too large for Idempotency-Key (at most 10 MB)", He: "גוף הבקשה גדול מדי עבור Idempotency-Key (עד 10MB)"},
+	"idempotency_key_reused":   {En: "Idempotency-Key was already used for a different request", He: "Idempotency-Key כבר שימש לבקשה אחרת"},
+	"invalid_from":             {En: "from must be an RFC 3339 timestamp", He: "from חייב להיות חותמת זמן בפורמט RFC 3339"},
+	"invalid_to":               {En: "to must be an RFC 3339 timestamp", He: "to חייב להיות חותמת זמן בפורמט RFC 3339"},
+	"invalid_limit":            {En: "limit must be a number ≥ 0", He: "limit חייב להיות מספר ≥ 0"},
+	"invalid_entity":           {En: "entity must be product", He: "entity חייב להיות product"},
+	"validation_failed":        {En: "validation failed", He: "האימות נכשל"},
+
+	// Products, stock and movements (models, repository)
+	"product_name_required":    {En: "product name is required", He: "חובה למלא שם מוצר"},
+	"product_invalid_size":     {En: "product size must be positive", He: "גודל המוצר חייב להיות חיובי"},
+	"product_invalid_price":    {En: "product price cannot be negative", He: "מחיר המוצר לא יכול להיות שלילי"},
+	"product_invalid_category": {En: "invalid product category", He: "קטגוריית מוצר לא תקינה"},
+	"product_not_found":        {En: "product not found", He: "המוצר לא נמצא"},
+	"product_exists":           {En: "product already exists", He: "המוצר כבר קיים"},
+	"product_inactive":         {En: "product is not active", He: "המוצר אינו פעיל"},
+	"product_active":           {En: "product is active (archive it first)", He: "המוצר פעיל (יש להעביר אותו לארכיון קודם)"},
+	"product_in_use":           {En: "product is still in use", He: "המוצר עדיין בשימוש"},
+	"invalid_merge":            {En: "cannot merge these products", He: "לא ניתן למזג את המוצרים האלה"},
+	"invalid_product_query":    {En: "invalid product query", He: "שאילתת מוצרים לא תקינה"},
+	"invalid_cursor":           {En: "invalid cursor (start again from the first page)", He: "סמן לא תקין (יש להתחיל שוב מהעמוד הראשון)"},
+	"version_conflict":         {En: "changed by someone else since you loaded it", He: "מישהו אחר שינה את הרשומה מאז שנטענה"},
+	"stock_not_found":          {En: "stock not found", He: "המלאי לא נמצא"},
+	"stock_negative":           {En: "stock cannot be negative", He: "המלאי לא יכול להיות שלילי"},
+	"stock_product_required":   {En: "product ID is required for stock", He: "חובה לציין מזהה מוצר למלאי"},
+	"insufficient_stock":       {En: "insufficient stock", He: "אין מספיק מלאי"},
+	"movement_not_found":       {En: "movement not found", He: "התנועה לא נמצאה"},
+	"movement_exists":          {En: "movement already recorded", He: "התנועה כבר נרשמה"},
+	"invalid_movement":         {En: "invalid movement", He: "תנועה לא תקינה"},
+	"movement_invalid_type":    {En: "invalid movement type", He: "סוג תנועה לא תקין"},
+	"movement_no_quantity":     {En: "movement must have boxes or units", He: "לתנועה חייבים להיות ארגזים או יחידות"},
+	"movement_no_performer":    {En: "performed_by is required", He: "חובה לציין מי ביצע"},
+	"movement_client_id":       {En: "client ID must be at most 100 characters", He: "מזהה הלקוח יכול להכיל עד 100 תווים"},
+	"alias_not_found":          {En: "alias not found", He: "הכינוי לא נמצא"},
+	"alias_exists":             {En: "alias already exists", He: "הכינוי כבר קיים"},
+	"alias_required":           {En: "alias is required", He: "חובה למלא כינוי"},
+	"alias_too_long":           {En: "alias must be at most 100 characters", He: "כינוי יכול להכיל עד 100 תווים"},
+	"alias_product_required":   {En: "product ID is required for alias", He: "חובה לציין מזהה מוצר לכינוי"},
+	"barcode_not_found":        {En: "barcode not found", He: "הברקוד לא נמצא"},
+	"barcode_exists":           {En: "barcode already exists", He: "הברקוד כבר קיים"},
+	"barcode_invalid":          {En: "barcode must be 8, 12, 13 or 14 digits", He: "ברקוד חייב להכיל 8, 12, 13 או 14 ספרות"},
+	"barcode_check_digit":      {En: "barcode check digit is wrong", He: "ספרת הביקורת של הברקוד שגויה"},
+	"invalid_pack_level":       {En: "pack level must be unit or case", He: "רמת האריזה חייבת להיות unit או case"},
+	"barcode_case_needs_box":   {En: "case barcode needs a product with a box size", He: "ברקוד של ארגז דורש מוצר עם גודל ארגז"},
+	"supplier_sku_not_found":   {En: "supplier SKU not found", He: "המק\"ט של הספק לא נמצא"},
+	"supplier_required":        {En: "supplier is required", He: "חובה לציין ספק"},
+	"sku_required":             {En: "supplier SKU is required", He: "חובה לציין מק\"ט ספק"},
+	"sku_product_required":     {En: "product ID is required for supplier SKU", He: "חובה לציין מזהה מוצר למק\"ט הספק"},
+
+	// Categories
+	"category_not_found":     {En: "category not found", He: "הקטגוריה לא נמצאה"},
+	"category_exists":        {En: "category already exists", He: "הקטגוריה כבר קיימת"},
+	"category_in_use":        {En: "category is still in use", He: "הקטגוריה עדיין בשימוש"},
+	"category_parent":        {En: "invalid parent category", He: "קטגוריית אב לא תקינה"},
+	"category_invalid_id":    {En: "category ID must be 1-50 lowercase letters, digits or _ (e.g. dry_goods)", He: "מזהה קטגוריה חייב להכיל 1-50 אותיות לטיניות קטנות, ספרות או _ (למשל dry_goods)"},
+	"category_name_required": {En: "category needs a Hebrew or English name", He: "לקטגוריה חייב להיות שם בעברית או באנגלית"},
+	"category_name_too_long": {En: "category name must be at most 100 characters", He: "שם קטגוריה יכול להכיל עד 100 תווים"},
+	"category_min_stock":     {En: "category default min stock cannot be negative", He: "מלאי המינימום של הקטגוריה לא יכול להיות שלילי"},
+	"category_own_parent":    {En: "category cannot be its own parent", He: "קטגוריה לא יכולה להיות האב של עצמה"},
+
+	// Users, logins and API keys
+	"forbidden":             {En: "your role is not allowed to do this", He: "לתפקיד שלך אין הרשאה לפעולה הזו"},
+	"invalid_credentials":   {En: "invalid username or password", He: "שם משתמש או סיסמה שגויים"},
+	"account_locked":        {En: "account is locked after too many failed logins", He: "החשבון ננעל אחרי יותר מדי ניסיונות כניסה כושלים"},
+	"invalid_token":         {En: "invalid or expired token", He: "אסימון לא תקין או שפג תוקפו"},
+	"last_owner":            {En: "the last owner can't lose the owner role", He: "לא ניתן להסיר את תפקיד הבעלים מהבעלים האחרון"},
+	"password_too_short":    {En: "password must be at least 8 characters", He: "הסיסמה חייבת להכיל לפחות 8 תווים"},
+	"password_too_long":     {En: "password must be at most 72 bytes", He: "הסיסמה יכולה להכיל עד 72 בתים"},
+	"username_invalid":      {En: "username must be 3-50 letters, digits, '.', '_' or '-'", He: "שם המשתמש חייב להכיל 3-50 אותיות, ספרות, '.', '_' או '-'"},
+	"invalid_role":          {En: "role must be owner, manager or employee", He: "התפקיד חייב להיות owner, manager או employee"},
+	"language_invalid":      {En: "language must be he or en", He: "השפה חייבת להיות he או en"},
+	"user_not_found":        {En: "user not found", He: "המשתמש לא נמצא"},
+	"user_exists":           {En: "user already exists", He: "המשתמש כבר קיים"},
+	"session_not_found":     {En: "session not found", He: "ההתחברות לא נמצאה"},
+	"invalid_scope":         {En: "invalid API key scope", He: "הרשאת מפתח API לא תקינה"},
+	"api_key_not_found":     {En: "API key not found", He: "מפתח ה-API לא נמצא"},
+	"api_key_name_required": {En: "API key name is required", He: "חובה לתת שם למפתח API"},
+	"api_key_name_too_long": {En: "API key name must be at most 100 characters", He: "שם מפתח API יכול להכיל עד 100 תווים"},
+	"api_key_no_scopes":     {En: "API key needs at least one scope", He: "מפתח API צריך לפחות הרשאה אחת"},
+
+	// Staff
+	"staff_not_found":     {En: "staff member not found", He: "העובד לא נמצא"},
+	"staff_user_linked":   {En: "login account is already linked to another staff member", He: "חשבון הכניסה כבר מקושר לעובד אחר"},
+	"staff_name_required": {En: "staff member needs a Hebrew or English name", He: "לעובד חייב להיות שם בעברית או באנגלית"},
+	"staff_name_too_long": {En: "staff name must be at most 100 characters", He: "שם עובד יכול להכיל עד 100 תווים"},
+	"staff_unknown":       {En: "no staff member matches this name", He: "אין עובד בשם הזה"},
+	"staff_ambiguous":     {En: "name matches more than one staff member", He: "השם מתאים ליותר מעובד אחד"},
+	"staff_inactive":      {En: "staff member is no longer active", He: "העובד כבר אינו פעיל"},
+
+	// Invoices, catalog files, reports, sync and backups
+	"invoice_unknown_format":  {En: "unknown invoice format", He: "פורמט חשבונית לא מוכר"},
+	"invoice_no_lines":        {En: "invoice has no item lines", He: "בחשבונית אין שורות פריטים"},
+	"invoice_missing_column":  {En: "invoice is missing a required column", He: "בחשבונית חסרה עמודת חובה"},
+	"invoice_draft_not_found": {En: "invoice draft not found", He: "טיוטת החשבונית לא נמצאה"},
+	"invoice_unknown_line":    {En: "no such line in invoice draft", He: "אין שורה כזו בטיוטת החשבונית"},
+	"invoice_unresolved":      {En: "invoice lines need a product or must be skipped", He: "יש לשייך מוצר לשורות החשבונית או לדלג עליהן"},
+	"user_required":           {En: "user is required", He: "חובה לציין משתמש"},
+	"catalog_unknown_format":  {En: "unknown catalog format (use csv or json)", He: "פורמט קטלוג לא מוכר (יש להשתמש ב-csv או json)"},
+	"catalog_no_rows":         {En: "catalog file has no product rows", He: "בקובץ הקטלוג אין שורות מוצרים"},
+	"catalog_missing_column":  {En: "catalog CSV is missing a required column", He: "בקובץ ה-CSV של הקטלוג חסרה עמודת חובה"},
+	"invalid_period":          {En: "period end must be after period start", He: "סוף התקופה חייב להיות אחרי תחילתה"},
+	"no_usage_input":          {En: "at least one product usage is required", He: "חובה לציין צריכה של מוצר אחד לפחות"},
+	"negative_usage":          {En: "theoretical usage cannot be negative", He: "הצריכה התיאורטית לא יכולה להיות שלילית"},
+	"counts_mismatch":         {En: "opening and closing counts must be given together", He: "יש למסור ספירת פתיחה וספירת סגירה יחד"},
+	"invalid_sync_token":      {En: "invalid sync token", He: "אסימון סנכרון לא תקין"},
+	"sync_batch_too_large":    {En: "at most 500 movements per sync", He: "עד 500 תנועות בכל סנכרון"},
+	"invalid_snapshot":        {En: "invalid snapshot", He: "גיבוי לא תקין"},
+	"not_backup":              {En: "not an inventory backup", He: "זה לא גיבוי של המלאי"},
+	"backup_unsupported":      {En: "backup format is newer than this program", He: "פורמט הגיבוי חדש יותר מהתוכנה"},
+	"backup_checksum":         {En: "backup is damaged: checksum does not match", He: "הגיבוי פגום: סכום הביקורת לא תואם"},
+
+	// Chat assistant
+	"chat_empty_message":     {En: "message is required", He: "חובה לכתוב הודעה"},
+	"chat_session_not_found": {En: "chat session not found", He: "שיחת הצ'אט לא נמצאה"},
+	"chat_draft_not_found":   {En: "draft not found", He: "הטיוטה לא נמצאה"},
+	"chat_draft_expired":     {En: "draft expired", He: "תוקף הטיוטה פג"},
+	"chat_too_many_steps":    {En: "assistant did not finish answering", He: "העוזר לא סיים לענות"},
+	"chat_movement_type":     {En: "assistant may only propose IN, OUT or WASTE movements", He: "העוזר יכול להציע רק תנועות IN, OUT או WASTE"},
+
+	// Variance report columns
+	"report_product_id":        {En: "Product ID", He: "מזהה מוצר"},
+	"report_product_name":      {En: "Product", He: "מוצר"},
+	"report_theoretical_units": {En: "Theoretical units", He: "יחידות תיאורטיות"},
+	"report_actual_units":      {En: "Actual units", He: "יחידות בפועל"},
+	"report_actual_source":     {En: "Actual from", He: "מקור הכמות בפועל"},
+	"report_received_units":    {En: "Received", He: "התקבלו"},
+	"report_out_units":         {En: "Used", He: "נוצלו"},
+	"report_waste_units":       {En: "Waste", He: "פחת"},
+	"report_variance_units":    {En: "Variance (units)", He: "פער (יחידות)"},
+	"report_variance_percent":  {En: "Variance (%)", He: "פער (%)"},
+	"report_variance_value":    {En: "Variance (NIS)", He: "פער (₪)"},
+	"report_flagged":           {En: "Flagged", He: "חריגה"},
+}
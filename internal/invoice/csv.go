@@ -0,0 +1,133 @@
+package invoice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVParser reads invoices exported as CSV (comma, semicolon or tab).
+// The first row is a header; columns are found by name in Hebrew or
+// English, so column order doesn't matter.
+type CSVParser struct{}
+
+// csvColumns lists header names per field (compared lowercase, trimmed)
+var csvColumns = map[string][]string{
+	"sku":         {"sku", "supplier sku", "item code", "code", "catalog", "מקט", "מק\"ט", "מק״ט", "קוד", "קוד פריט"},
+	"barcode":     {"barcode", "ean", "upc", "gtin", "ברקוד"},
+	"description": {"description", "name", "item", "product", "תיאור", "שם", "שם פריט", "פריט", "תאור"},
+	"quantity":    {"quantity", "qty", "כמות"},
+	"unit":        {"unit", "uom", "יחידה", "יח' מידה", "יחידת מידה"},
+	"price":       {"unit price", "price", "מחיר", "מחיר יחידה"},
+	"total":       {"total", "line total", "amount", "סהכ", "סה\"כ", "סה״כ"},
+}
+
+// Parse implements Parser
+func (CSVParser) Parse(r io.Reader) (*Invoice, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Excel adds a BOM
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoLines
+	}
+
+	cols := mapColumns(rows[0])
+	if _, ok := cols["quantity"]; !ok {
+		return nil, fmt.Errorf("%w: quantity", ErrMissingColumn)
+	}
+	_, hasDesc := cols["description"]
+	_, hasSKU := cols["sku"]
+	_, hasBarcode := cols["barcode"]
+	if !hasDesc && !hasSKU && !hasBarcode {
+		return nil, fmt.Errorf("%w: description, sku or barcode", ErrMissingColumn)
+	}
+
+	inv := &Invoice{}
+	for i, row := range rows[1:] {
+		lineNo := i + 2 // Header is line 1
+		get := func(field string) string {
+			if idx, ok := cols[field]; ok && idx < len(row) {
+				return strings.TrimSpace(row[idx])
+			}
+			return ""
+		}
+		if get("quantity") == "" && get("description") == "" {
+			continue // Empty or separator row
+		}
+
+		line := Line{
+			Number:      lineNo,
+			SKU:         get("sku"),
+			Barcode:     get("barcode"),
+			Description: get("description"),
+			Unit:        parseUnit(get("unit")),
+		}
+		if line.Quantity, err = parseNumber(get("quantity")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid quantity %q", lineNo, get("quantity"))
+		}
+		if p := get("price"); p != "" {
+			if line.UnitPrice, err = parseNumber(p); err != nil {
+				return nil, fmt.Errorf("line %d: invalid price %q", lineNo, p)
+			}
+		} else if t := get("total"); t != "" && line.Quantity != 0 {
+			total, err := parseNumber(t)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid total %q", lineNo, t)
+			}
+			line.UnitPrice = total / line.Quantity
+		}
+		inv.Lines = append(inv.Lines, line)
+	}
+
+	if len(inv.Lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return inv, nil
+}
+
+// detectDelimiter picks the most common of , ; and tab in the header line
+func detectDelimiter(data []byte) rune {
+	header, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := strings.Count(header, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// mapColumns finds the index of each known field in the header row
+func mapColumns(header []string) map[string]int {
+	cols := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		for field, names := range csvColumns {
+			if _, done := cols[field]; done {
+				continue
+			}
+			for _, name := range names {
+				if h == name {
+					cols[field] = i
+				}
+			}
+		}
+	}
+	return cols
+}
@@ -0,0 +1,135 @@
+// Package invoice turns supplier invoices and delivery notes into draft
+// receipts. A Parser reads one file format into an Invoice, the Service
+// matches every line to a product (barcode, supplier SKU, then name) and
+// keeps a Draft of IN movements and price updates until the owner
+// approves it. Nothing touches stock before approval.
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown invoice format")
+	ErrNoLines       = errors.New("invoice has no item lines")
+	ErrMissingColumn = errors.New("invoice is missing a required column")
+)
+
+// Line is one item line as printed on the invoice
+type Line struct {
+	Number      int     `json:"number"` // Line number in the file (1-based)
+	SKU         string  `json:"sku,omitempty"`
+	Barcode     string  `json:"barcode,omitempty"`
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit,omitempty"` // models.PackUnit, models.PackCase or "" (not printed)
+	UnitPrice   float64 `json:"unitPrice"`      // Price per Unit before VAT (per case if Unit is case)
+}
+
+// Invoice is a parsed supplier document
+type Invoice struct {
+	Supplier string `json:"supplier,omitempty"`
+	Number   string `json:"number,omitempty"`
+	Lines    []Line `json:"lines"`
+}
+
+// Parser reads one file format into an Invoice.
+// Implementations only parse; matching happens in the Service.
+type Parser interface {
+	Parse(r io.Reader) (*Invoice, error)
+}
+
+// ============================================
+// PARSER REGISTRY
+// ============================================
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]Parser{
+		"csv":  CSVParser{},
+		"text": TextParser{}, // Plain text extracted from a PDF
+	}
+)
+
+// Register adds or replaces the parser for a format name
+func Register(format string, p Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[strings.ToLower(format)] = p
+}
+
+// ParserFor returns the parser registered for a format
+func ParserFor(format string) (Parser, error) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	p, ok := parsers[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (known: %s)", ErrUnknownFormat, format, strings.Join(formatsLocked(), ", "))
+	}
+	return p, nil
+}
+
+// formatsLocked lists registered format names (caller holds parsersMu)
+func formatsLocked() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ============================================
+// SHARED PARSING HELPERS
+// ============================================
+
+// caseWords mark a line as counted in cases (boxes) rather than units
+var caseWords = map[string]bool{
+	"case": true, "cases": true, "cs": true, "ctn": true, "carton": true, "box": true, "boxes": true,
+	"קרטון": true, "קרטונים": true, "ארגז": true, "ארגזים": true, "מארז": true, "מארזים": true,
+}
+
+// unitWords mark a line as counted in single units
+var unitWords = map[string]bool{
+	"unit": true, "units": true, "pc": true, "pcs": true, "ea": true, "each": true,
+	"יח": true, "יחידה": true, "יחידות": true,
+}
+
+// parseUnit maps a printed unit of measure to a pack level ("" if unknown)
+func parseUnit(s string) string {
+	s = strings.Trim(strings.ToLower(strings.TrimSpace(s)), `.'"׳`)
+	switch {
+	case caseWords[s]:
+		return models.PackCase
+	case unitWords[s]:
+		return models.PackUnit
+	}
+	return ""
+}
+
+// parseNumber reads prices and quantities as printed on invoices:
+// "1,234.50", "12.5", "₪ 8.90", "3,5" (comma decimal)
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	for _, cut := range []string{"₪", "nis", "NIS", "ש\"ח", "ש״ח", " "} {
+		s = strings.ReplaceAll(s, cut, "")
+	}
+	if strings.Contains(s, ",") {
+		if strings.Contains(s, ".") {
+			s = strings.ReplaceAll(s, ",", "") // Thousands separator
+		} else if i := strings.LastIndex(s, ","); len(s)-i-1 == 3 {
+			s = strings.ReplaceAll(s, ",", "") // "1,234"
+		} else {
+			s = strings.Replace(s, ",", ".", 1) // "3,5"
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
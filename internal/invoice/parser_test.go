@@ -0,0 +1,86 @@
+package invoice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+func TestCSVParser(t *testing.T) {
+	data := "\xef\xbb\xbfמק\"ט;ברקוד;תיאור;יחידה;כמות;מחיר\n" +
+		"1001;;קוקה קולה פחית;קרטון;2;120,00\n" +
+		";4006381333931;חומוס 400 גרם;יח';12;7.5\n" +
+		";;;;;\n"
+	inv, err := CSVParser{}.Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(inv.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %+v", inv.Lines)
+	}
+	first := inv.Lines[0]
+	if first.Number != 2 || first.SKU != "1001" || first.Unit != models.PackCase || first.Quantity != 2 || first.UnitPrice != 120 {
+		t.Fatalf("unexpected first line: %+v", first)
+	}
+	second := inv.Lines[1]
+	if second.Barcode != "4006381333931" || second.Unit != models.PackUnit || second.Quantity != 12 || second.UnitPrice != 7.5 {
+		t.Fatalf("unexpected second line: %+v", second)
+	}
+
+	// Price from the line total when there is no price column
+	inv, err = CSVParser{}.Parse(strings.NewReader("description,qty,total\nPita,10,\"1,234.50\"\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := inv.Lines[0].UnitPrice; got != 123.45 {
+		t.Fatalf("expected unit price 123.45 from total, got %v", got)
+	}
+
+	if _, err := (CSVParser{}).Parse(strings.NewReader("description,price\nPita,3\n")); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn, got %v", err)
+	}
+}
+
+func TestTextParser(t *testing.T) {
+	text := `ספק: תנובה
+חשבונית מס 55123
+תאריך 01/10/2026
+
+4006381333931 חומוס 400 גרם 12 7.50 90.00
+TN-55 חלב 3% 1 ליטר קרטון 2 60.00 120.00
+Cola 330 24 2.10
+סה"כ 210.00 35.70
+`
+	inv, err := TextParser{}.Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if inv.Supplier != "תנובה" || inv.Number != "55123" {
+		t.Fatalf("unexpected header: supplier %q number %q", inv.Supplier, inv.Number)
+	}
+	if len(inv.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %+v", inv.Lines)
+	}
+
+	want := []Line{
+		{Number: 5, Barcode: "4006381333931", Description: "חומוס 400 גרם", Quantity: 12, UnitPrice: 7.5},
+		{Number: 6, SKU: "TN-55", Description: "חלב 3% 1 ליטר", Unit: models.PackCase, Quantity: 2, UnitPrice: 60},
+		{Number: 7, Description: "Cola 330", Quantity: 24, UnitPrice: 2.1},
+	}
+	for i, w := range want {
+		if inv.Lines[i] != w {
+			t.Errorf("line %d: got %+v, want %+v", i, inv.Lines[i], w)
+		}
+	}
+}
+
+func TestParserFor(t *testing.T) {
+	if _, err := ParserFor("CSV"); err != nil {
+		t.Fatalf("csv parser missing: %v", err)
+	}
+	if _, err := ParserFor("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+}
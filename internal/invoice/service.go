@@ -0,0 +1,440 @@
+package invoice
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+var (
+	ErrUserRequired    = errors.New("user is required")
+	ErrDraftNotFound   = errors.New("invoice draft not found")
+	ErrUnknownLine     = errors.New("no such line in invoice draft")
+	ErrUnresolvedLines = errors.New("invoice lines need a product or must be skipped")
+)
+
+// How a line was matched to a product
+const (
+	MatchBarcode = "barcode" // Printed barcode is one of the product's barcodes
+	MatchSKU     = "sku"     // Supplier SKU seen (and approved) before
+	MatchName    = "name"    // Description matched by product search
+	MatchManual  = "manual"  // Chosen by the owner while approving
+)
+
+// nameMatchScore is the search score a description needs to be matched
+// automatically; weaker matches are only offered as candidates
+const nameMatchScore = 0.8
+
+// Candidate is a possible product for a line that wasn't matched
+type Candidate struct {
+	ProductID string  `json:"productId"`
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"`
+}
+
+// DraftLine is one invoice line with its proposed receipt and price update
+type DraftLine struct {
+	Line         Line        `json:"line"`
+	ProductID    string      `json:"productId,omitempty"`
+	ProductName  string      `json:"productName,omitempty"`
+	MatchedBy    string      `json:"matchedBy,omitempty"`
+	Candidates   []Candidate `json:"candidates,omitempty"`
+	PackLevel    string      `json:"packLevel,omitempty"`
+	Boxes        int         `json:"boxes"`
+	Units        int         `json:"units"`
+	OldPrice     float64     `json:"oldPrice"`     // Current price per unit
+	NewPrice     float64     `json:"newPrice"`     // Price per unit on the invoice
+	PriceChanged bool        `json:"priceChanged"` // Approving updates the product price
+	Problem      string      `json:"problem,omitempty"`
+	Skipped      bool        `json:"skipped,omitempty"`
+
+	// A failed approval can stop halfway through a line; these say which
+	// steps a retry must not repeat
+	MovementApplied bool `json:"movementApplied,omitempty"` // Stock received
+	PriceApplied    bool `json:"priceApplied,omitempty"`    // Price updated
+	Applied         bool `json:"applied,omitempty"`         // Every step done
+}
+
+// Draft is a parsed invoice waiting for the owner's approval
+type Draft struct {
+	ID            string      `json:"id"`
+	Supplier      string      `json:"supplier"`
+	InvoiceNumber string      `json:"invoiceNumber,omitempty"`
+	Format        string      `json:"format"`
+	CreatedBy     string      `json:"createdBy"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	Lines         []DraftLine `json:"lines"`
+}
+
+// LineDecision is the owner's answer for one line when approving
+type LineDecision struct {
+	Number    int    `json:"number"`              // Line.Number
+	ProductID string `json:"productId,omitempty"` // Use this product instead of the match
+	PackLevel string `json:"packLevel,omitempty"` // Quantity is in units or cases
+	Skip      bool   `json:"skip,omitempty"`      // Don't receive this line
+	KeepPrice bool   `json:"keepPrice,omitempty"` // Receive, but don't update the price
+}
+
+// Approval confirms a draft
+type Approval struct {
+	ApprovedBy string         `json:"approvedBy"`           // Logged as ReportedBy
+	ReceivedBy string         `json:"receivedBy,omitempty"` // Who took the delivery; defaults to ApprovedBy
+	Lines      []LineDecision `json:"lines,omitempty"`
+}
+
+// PriceUpdate is one product price changed by an approved invoice
+type PriceUpdate struct {
+	ProductID string  `json:"productId"`
+	OldPrice  float64 `json:"oldPrice"`
+	NewPrice  float64 `json:"newPrice"`
+}
+
+// ApprovalResult lists what an approval changed
+type ApprovalResult struct {
+	Movements    []*models.StockMovement `json:"movements"`
+	PriceUpdates []PriceUpdate           `json:"priceUpdates"`
+	Skipped      []int                   `json:"skipped,omitempty"` // Line numbers
+}
+
+// Service parses invoices into drafts and applies approved drafts.
+// Drafts live in memory: after a restart, upload the file again.
+type Service struct {
+	store repository.Repository
+
+	mu     sync.Mutex
+	drafts map[string]*Draft
+}
+
+// NewService creates an invoice service using the given store
+func NewService(store repository.Repository) *Service {
+	return &Service{store: store, drafts: make(map[string]*Draft)}
+}
+
+// newID returns a random, hard to guess ID with a readable prefix
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + "-" + hex.EncodeToString(b)
+}
+
+// Ingest parses a file and matches its lines into a new draft.
+// supplier and number override what the parser found in the file.
+func (s *Service) Ingest(format, supplier, number string, r io.Reader, user string) (*Draft, error) {
+	if user == "" {
+		return nil, ErrUserRequired
+	}
+	parser, err := ParserFor(format)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := parser.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if supplier = strings.TrimSpace(supplier); supplier == "" {
+		supplier = strings.TrimSpace(inv.Supplier)
+	}
+	if supplier == "" {
+		return nil, models.ErrSupplierRequired
+	}
+	if number == "" {
+		number = inv.Number
+	}
+
+	d := &Draft{
+		ID:            newID("INV"),
+		Supplier:      supplier,
+		InvoiceNumber: number,
+		Format:        strings.ToLower(format),
+		CreatedBy:     user,
+		CreatedAt:     time.Now(),
+	}
+	for _, l := range inv.Lines {
+		d.Lines = append(d.Lines, s.matchLine(supplier, l))
+	}
+
+	s.mu.Lock()
+	s.drafts[d.ID] = d
+	s.mu.Unlock()
+
+	return copyDraft(d), nil
+}
+
+// matchLine finds the product for one line: barcode, then SKU, then name
+func (s *Service) matchLine(supplier string, l Line) DraftLine {
+	dl := DraftLine{Line: l}
+
+	if l.Barcode != "" {
+		if product, barcode, err := s.store.GetProductByBarcode(l.Barcode); err == nil {
+			dl.MatchedBy = MatchBarcode
+			s.resolve(&dl, product, barcode.PackLevel)
+			return dl
+		}
+	}
+	if l.SKU != "" {
+		if m, err := s.store.GetSupplierSKU(supplier, l.SKU); err == nil {
+			if product, err := s.store.GetProduct(m.ProductID); err == nil {
+				dl.MatchedBy = MatchSKU
+				s.resolve(&dl, product, m.PackLevel)
+				return dl
+			}
+		}
+	}
+	if l.Description != "" {
+		matches := s.store.SearchProductsRanked(l.Description)
+		top := matches
+		if len(top) > 3 {
+			top = top[:3]
+		}
+		for _, m := range top {
+			dl.Candidates = append(dl.Candidates, Candidate{ProductID: m.Product.ID, Name: m.Product.Name, Score: math.Round(m.Score*100) / 100})
+		}
+		// Only a clear winner is matched automatically
+		if len(matches) > 0 && matches[0].Score >= nameMatchScore &&
+			(len(matches) == 1 || matches[1].Score < matches[0].Score) {
+			dl.MatchedBy = MatchName
+			s.resolve(&dl, matches[0].Product, models.PackUnit)
+			return dl
+		}
+	}
+
+	dl.Problem = "no matching product"
+	return dl
+}
+
+// resolve fills product, quantity and price for a line matched to product.
+// A unit printed on the line wins over the pack level of the match.
+func (s *Service) resolve(dl *DraftLine, product *models.Product, pack string) {
+	dl.ProductID = product.ID
+	dl.ProductName = product.Name
+	dl.Problem = ""
+	dl.Boxes, dl.Units = 0, 0
+	if dl.Line.Unit != "" {
+		pack = dl.Line.Unit
+	}
+	dl.PackLevel = pack
+
+	qty := dl.Line.Quantity
+	switch {
+	case !product.IsActive:
+		dl.Problem = "product is not active"
+	case qty <= 0 || qty != math.Trunc(qty):
+		dl.Problem = "quantity must be a whole positive number"
+	case pack == models.PackCase && product.BoxSize == 0:
+		dl.Problem = "product is not sold in boxes"
+	case pack == models.PackCase:
+		dl.Boxes = int(qty)
+	default:
+		dl.Units = int(qty)
+	}
+
+	dl.OldPrice = product.Price
+	dl.NewPrice = dl.Line.UnitPrice
+	if pack == models.PackCase && product.BoxSize > 0 {
+		dl.NewPrice = dl.Line.UnitPrice / float64(product.BoxSize)
+	}
+	dl.NewPrice = math.Round(dl.NewPrice*100) / 100
+	dl.PriceChanged = dl.Line.UnitPrice > 0 && math.Abs(dl.NewPrice-dl.OldPrice) >= 0.005
+}
+
+// Drafts returns all pending drafts, oldest first
+func (s *Service) Drafts() []*Draft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]*Draft, 0, len(s.drafts))
+	for _, d := range s.drafts {
+		res = append(res, copyDraft(d))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res
+}
+
+// Draft returns one pending draft
+func (s *Service) Draft(id string) (*Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.drafts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	return copyDraft(d), nil
+}
+
+// Reject throws a draft away without touching stock or prices
+func (s *Service) Reject(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.drafts[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	delete(s.drafts, id)
+	return nil
+}
+
+// Approve applies the owner's decisions, then records one IN movement per
+// line and updates changed prices. Supplier SKUs of received lines are
+// remembered, so the next invoice from this supplier matches by SKU.
+// If recording fails halfway, every step done (stock, price) is marked
+// on its line and a retry continues with the rest.
+func (s *Service) Approve(id string, a Approval) (*ApprovalResult, error) {
+	if a.ApprovedBy == "" {
+		return nil, ErrUserRequired
+	}
+	if a.ReceivedBy == "" {
+		a.ReceivedBy = a.ApprovedBy
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.drafts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+
+	// 1) Apply decisions to a copy; keep the draft unchanged if one is invalid
+	lines := append([]DraftLine(nil), d.Lines...)
+	for _, dec := range a.Lines {
+		i := lineIndex(lines, dec.Number)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownLine, dec.Number)
+		}
+		if lines[i].Applied {
+			continue
+		}
+		if lines[i].MovementApplied {
+			// Stock is in; only the price can still be declined
+			if dec.KeepPrice {
+				lines[i].PriceChanged = false
+			}
+			continue
+		}
+		if dec.Skip {
+			lines[i].Skipped = true
+			continue
+		}
+		lines[i].Skipped = false
+		if dec.ProductID != "" || dec.PackLevel != "" {
+			productID := dec.ProductID
+			if productID == "" {
+				productID = lines[i].ProductID
+			}
+			product, err := s.store.GetProduct(productID)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", dec.Number, err)
+			}
+			pack := dec.PackLevel
+			if pack == "" {
+				pack = lines[i].PackLevel
+			}
+			if pack != models.PackUnit && pack != models.PackCase {
+				pack = models.PackUnit
+			}
+			if dec.ProductID != "" {
+				lines[i].MatchedBy = MatchManual
+			}
+			lines[i].Line.Unit = "" // The owner's pack level wins
+			s.resolve(&lines[i], product, pack)
+		}
+		if dec.KeepPrice {
+			lines[i].PriceChanged = false
+		}
+	}
+
+	// 2) Every line must be skipped or ready
+	var unresolved []string
+	for _, l := range lines {
+		if !l.Skipped && !l.MovementApplied && (l.ProductID == "" || l.Problem != "") {
+			unresolved = append(unresolved, fmt.Sprint(l.Line.Number))
+		}
+	}
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("%w: lines %s", ErrUnresolvedLines, strings.Join(unresolved, ", "))
+	}
+	d.Lines = lines
+
+	// 3) Record movements and prices line by line
+	res := &ApprovalResult{Movements: []*models.StockMovement{}, PriceUpdates: []PriceUpdate{}}
+	reason := strings.TrimSpace("invoice " + d.Supplier + " " + d.InvoiceNumber)
+	for i := range d.Lines {
+		l := &d.Lines[i]
+		if l.Skipped {
+			res.Skipped = append(res.Skipped, l.Line.Number)
+			continue
+		}
+		if l.Applied {
+			continue
+		}
+
+		if !l.MovementApplied {
+			m, err := models.NewStockMovement(l.ProductID, models.MovementIn, l.Boxes, l.Units, a.ReceivedBy, a.ApprovedBy, reason)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", l.Line.Number, err)
+			}
+			m.Source = models.SourceInvoice
+			if _, err := s.store.RecordMovement(m); err != nil {
+				return nil, fmt.Errorf("line %d: %w", l.Line.Number, err)
+			}
+			l.MovementApplied = true
+			res.Movements = append(res.Movements, m)
+		}
+
+		if l.PriceChanged && !l.PriceApplied {
+			product, err := s.store.GetProduct(l.ProductID)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", l.Line.Number, err)
+			}
+			updated := *product
+			updated.Price = l.NewPrice
+			if err := s.store.WithActor(a.ApprovedBy).UpdateProduct(&updated); err != nil {
+				return nil, fmt.Errorf("line %d: %w", l.Line.Number, err)
+			}
+			l.PriceApplied = true
+			res.PriceUpdates = append(res.PriceUpdates, PriceUpdate{ProductID: l.ProductID, OldPrice: product.Price, NewPrice: l.NewPrice})
+		}
+
+		if l.Line.SKU != "" && l.MatchedBy != MatchSKU {
+			sku := &models.SupplierSKU{Supplier: d.Supplier, SKU: l.Line.SKU, ProductID: l.ProductID, PackLevel: l.PackLevel}
+			if err := s.store.SetSupplierSKU(sku); err != nil {
+				return nil, fmt.Errorf("line %d: remember SKU: %w", l.Line.Number, err)
+			}
+		}
+		l.Applied = true
+	}
+
+	delete(s.drafts, id)
+	return res, nil
+}
+
+// lineIndex finds a line by its number, -1 if missing
+func lineIndex(lines []DraftLine, number int) int {
+	for i, l := range lines {
+		if l.Line.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyDraft returns a copy callers can't use to change the stored draft
+func copyDraft(d *Draft) *Draft {
+	copied := *d
+	copied.Lines = append([]DraftLine(nil), d.Lines...)
+	return &copied
+}
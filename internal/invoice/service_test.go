@@ -0,0 +1,208 @@
+package invoice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// newTestStore returns a store with cola (box of 24, case barcode),
+// hummus (unit barcode) and pita (sold loose)
+func newTestStore(t *testing.T) *repository.MemoryStore {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	products := []*models.Product{
+		{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 2, Category: "drinks"},
+		{Name: "חומוס 400 גרם", Brand: "עשי", Size: 400, ContainerType: "can", BoxSize: 12, Price: 7, Category: "canned"},
+		{Name: "פיתה", Brand: "מאפיית הכפר", Size: 100, ContainerType: "piece", BoxSize: 0, Price: 1, Category: "dry_goods"},
+	}
+	for _, p := range products {
+		if _, err := store.AddProduct(p); err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+	}
+	if _, err := store.AddBarcode("PROD-001", "10012345678902", models.PackCase); err != nil {
+		t.Fatalf("AddBarcode failed: %v", err)
+	}
+	if _, err := store.AddBarcode("PROD-002", "4006381333931", models.PackUnit); err != nil {
+		t.Fatalf("AddBarcode failed: %v", err)
+	}
+	return store
+}
+
+const testCSV = `sku,barcode,description,qty,price
+C-1,10012345678902,Cola cans,2,52.80
+H-7,,חומוס 400 גרם,12,7
+P-3,,פיתות טריות,50,0.9
+X-9,,something unknown,1,10
+`
+
+func TestIngestMatchesLines(t *testing.T) {
+	svc := NewService(newTestStore(t))
+	d, err := svc.Ingest("csv", "Supplier A", "INV-1", strings.NewReader(testCSV), "owner")
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if len(d.Lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(d.Lines))
+	}
+
+	cola := d.Lines[0]
+	if cola.ProductID != "PROD-001" || cola.MatchedBy != MatchBarcode || cola.Boxes != 2 || cola.Units != 0 {
+		t.Fatalf("cola: %+v", cola)
+	}
+	if cola.NewPrice != 2.2 || !cola.PriceChanged {
+		t.Fatalf("cola price per unit: got %v changed=%v, want 2.2 changed", cola.NewPrice, cola.PriceChanged)
+	}
+
+	hummus := d.Lines[1]
+	if hummus.ProductID != "PROD-002" || hummus.MatchedBy != MatchName || hummus.Units != 12 || hummus.PriceChanged {
+		t.Fatalf("hummus: %+v", hummus)
+	}
+
+	// "פיתות" is not close enough to "פיתה" to be trusted, but is offered
+	for _, l := range d.Lines[2:] {
+		if l.ProductID != "" || l.Problem == "" {
+			t.Fatalf("line %d should be unmatched: %+v", l.Line.Number, l)
+		}
+	}
+
+	if _, err := svc.Ingest("csv", "", "", strings.NewReader(testCSV), "owner"); !errors.Is(err, models.ErrSupplierRequired) {
+		t.Fatalf("expected ErrSupplierRequired, got %v", err)
+	}
+}
+
+func TestApproveRecordsMovementsAndLearnsSKUs(t *testing.T) {
+	store := newTestStore(t)
+	svc := NewService(store)
+	d, err := svc.Ingest("csv", "Supplier A", "INV-1", strings.NewReader(testCSV), "owner")
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	// Unresolved lines block approval and change nothing
+	if _, err := svc.Approve(d.ID, Approval{ApprovedBy: "owner"}); !errors.Is(err, ErrUnresolvedLines) {
+		t.Fatalf("expected ErrUnresolvedLines, got %v", err)
+	}
+	if n := len(store.ListMovements(repository.MovementFilter{})); n != 0 {
+		t.Fatalf("expected no movements before approval, got %d", n)
+	}
+
+	res, err := svc.Approve(d.ID, Approval{
+		ApprovedBy: "owner",
+		ReceivedBy: "yossi",
+		Lines: []LineDecision{
+			{Number: 4, ProductID: "PROD-003", KeepPrice: true}, // Pita, chosen by hand
+			{Number: 5, Skip: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if len(res.Movements) != 3 || len(res.Skipped) != 1 {
+		t.Fatalf("expected 3 movements and 1 skipped, got %+v", res)
+	}
+	for _, m := range res.Movements {
+		if m.Type != models.MovementIn || m.Source != models.SourceInvoice || m.PerformedBy != "yossi" || m.ReportedBy != "owner" {
+			t.Fatalf("unexpected movement: %+v", m)
+		}
+	}
+	if len(res.PriceUpdates) != 1 || res.PriceUpdates[0].ProductID != "PROD-001" {
+		t.Fatalf("expected only the cola price to change, got %+v", res.PriceUpdates)
+	}
+
+	stock, _ := store.GetStock("PROD-001")
+	if stock.QuantityBoxes != 2 {
+		t.Fatalf("expected 2 boxes of cola, got %+v", stock)
+	}
+	pita, _ := store.GetProduct("PROD-003")
+	if pita.Price != 1 {
+		t.Fatalf("pita price should be kept, got %v", pita.Price)
+	}
+	if _, err := svc.Draft(d.ID); !errors.Is(err, ErrDraftNotFound) {
+		t.Fatalf("approved draft should be gone, got %v", err)
+	}
+
+	// The next invoice matches pita by the learned SKU
+	d2, err := svc.Ingest("csv", "Supplier A", "INV-2", strings.NewReader("sku,description,qty,price\nP-3,פיתות,40,1\n"), "owner")
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if l := d2.Lines[0]; l.ProductID != "PROD-003" || l.MatchedBy != MatchSKU || l.Units != 40 {
+		t.Fatalf("expected SKU match for pita, got %+v", l)
+	}
+}
+
+func TestRejectDraft(t *testing.T) {
+	svc := NewService(newTestStore(t))
+	d, err := svc.Ingest("csv", "Supplier A", "", strings.NewReader(testCSV), "owner")
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if err := svc.Reject(d.ID); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if len(svc.Drafts()) != 0 {
+		t.Fatalf("expected no drafts after reject")
+	}
+}
+
+// failingPriceStore fails the next n product updates, like a version
+// conflict with someone editing the product at the same time
+type failingPriceStore struct {
+	*repository.MemoryStore
+	fail int
+}
+
+func (s *failingPriceStore) WithActor(string) repository.Repository { return s }
+
+func (s *failingPriceStore) UpdateProduct(p *models.Product) error {
+	if s.fail > 0 {
+		s.fail--
+		return repository.ErrVersionConflict
+	}
+	return s.MemoryStore.UpdateProduct(p)
+}
+
+func TestApproveRetryAppliesPriceAfterFailure(t *testing.T) {
+	store := &failingPriceStore{MemoryStore: newTestStore(t), fail: 1}
+	svc := NewService(store)
+	d, err := svc.Ingest("csv", "Supplier A", "INV-1", strings.NewReader(testCSV), "owner")
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	approval := Approval{ApprovedBy: "owner", Lines: []LineDecision{{Number: 4, Skip: true}, {Number: 5, Skip: true}}}
+
+	if _, err := svc.Approve(d.ID, approval); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected the price update to fail, got %v", err)
+	}
+	pending, err := svc.Draft(d.ID)
+	if err != nil {
+		t.Fatalf("Draft failed: %v", err)
+	}
+	if cola := pending.Lines[0]; !cola.MovementApplied || cola.PriceApplied || cola.Applied {
+		t.Fatalf("cola should have its stock but not its price: %+v", cola)
+	}
+
+	res, err := svc.Approve(d.ID, approval)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if len(res.PriceUpdates) != 1 || res.PriceUpdates[0].ProductID != "PROD-001" {
+		t.Fatalf("retry should update the cola price, got %+v", res.PriceUpdates)
+	}
+	if len(res.Movements) != 1 {
+		t.Fatalf("retry should only receive hummus, got %d movements", len(res.Movements))
+	}
+	cola, _ := store.GetProduct("PROD-001")
+	if cola.Price != 2.2 {
+		t.Fatalf("expected cola price 2.2, got %v", cola.Price)
+	}
+	stock, _ := store.GetStock("PROD-001")
+	if stock.QuantityBoxes != 2 {
+		t.Fatalf("cola must be received once, got %+v", stock)
+	}
+}
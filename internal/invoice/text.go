@@ -0,0 +1,161 @@
+package invoice
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// TextParser reads the plain text extracted from a PDF invoice
+// (pdftotext -layout, or copy-paste). Item lines look like:
+//
+//	[barcode or SKU] description [unit] quantity unit-price [line-total]
+//
+// Lines without two numbers at the end (headers, addresses, totals) are skipped.
+type TextParser struct{}
+
+var (
+	// invoiceNumberRe finds "Invoice No: 123", "חשבונית מס 123", "תעודת משלוח 55"
+	invoiceNumberRe = regexp.MustCompile(`(?i)(?:invoice|delivery note|חשבונית|תעודת משלוח)[^0-9\n]*?(\d[\w/-]*)`)
+	// supplierRe finds "Supplier: Tnuva" or "ספק: תנובה"
+	supplierRe = regexp.MustCompile(`(?i)^\s*(?:supplier|vendor|ספק)\s*[:：]\s*(.+?)\s*$`)
+)
+
+// summaryWords start lines that are totals, not items
+var summaryWords = []string{"total", "subtotal", "vat", "discount", "balance", "סהכ", "סה\"כ", "סה״כ", "מעמ", "מע\"מ", "מע״מ", "הנחה", "לתשלום", "יתרה"}
+
+// Parse implements Parser
+func (TextParser) Parse(r io.Reader) (*Invoice, error) {
+	inv := &Invoice{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if inv.Supplier == "" {
+			if m := supplierRe.FindStringSubmatch(text); m != nil {
+				inv.Supplier = m[1]
+				continue
+			}
+		}
+		if inv.Number == "" {
+			if m := invoiceNumberRe.FindStringSubmatch(text); m != nil {
+				inv.Number = m[1]
+				continue
+			}
+		}
+		if line, ok := parseTextLine(text); ok {
+			line.Number = lineNo
+			inv.Lines = append(inv.Lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(inv.Lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return inv, nil
+}
+
+// parseTextLine reads one item line; ok is false for anything else
+func parseTextLine(text string) (Line, bool) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 || isSummary(fields[0]) {
+		return Line{}, false
+	}
+
+	// Trailing numbers: quantity, price and maybe a line total
+	var nums []float64
+	end := len(fields)
+	for end > 0 && len(nums) < 3 {
+		n, err := parseNumber(fields[end-1])
+		if err != nil {
+			break
+		}
+		nums = append([]float64{n}, nums...)
+		end--
+	}
+	if len(nums) < 2 {
+		return Line{}, false
+	}
+	if len(nums) == 3 && !closeTo(nums[0]*nums[1], nums[2]) {
+		// The first number belongs to the description ("Cola 330 24 2.10")
+		nums = nums[1:]
+		end++
+	}
+
+	line := Line{Quantity: nums[0], UnitPrice: nums[1]}
+	words := fields[:end]
+
+	// Leading code: a valid barcode, otherwise a SKU-looking token
+	if len(words) > 1 {
+		if code, err := models.NormalizeBarcode(words[0]); err == nil {
+			line.Barcode = code
+			words = words[1:]
+		} else if looksLikeSKU(words[0]) {
+			line.SKU = words[0]
+			words = words[1:]
+		}
+	}
+
+	// A unit of measure anywhere in the description
+	desc := make([]string, 0, len(words))
+	for _, w := range words {
+		if u := parseUnit(w); u != "" && line.Unit == "" {
+			line.Unit = u
+			continue
+		}
+		desc = append(desc, w)
+	}
+	line.Description = strings.Join(desc, " ")
+	if line.Description == "" && line.Barcode == "" && line.SKU == "" {
+		return Line{}, false
+	}
+	return line, true
+}
+
+// isSummary reports whether a word starts a totals line
+func isSummary(word string) bool {
+	word = strings.ToLower(strings.Trim(word, ":"))
+	for _, s := range summaryWords {
+		if word == s {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeSKU accepts item codes like "1001", "TN-55", "A12B"
+// but not quantities or sizes written in the description ("3%", "1L")
+func looksLikeSKU(word string) bool {
+	if len([]rune(word)) < 3 || strings.HasSuffix(word, "%") {
+		return false
+	}
+	hasDigit := false
+	for _, r := range word {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case r == '-' || r == '/' || (r < unicode.MaxASCII && unicode.IsLetter(r)):
+		default:
+			return false
+		}
+	}
+	return hasDigit
+}
+
+// closeTo compares money amounts with 1% (at least 1 agora) tolerance
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= math.Max(0.01, math.Abs(b)*0.01)
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ============================================
+// JSON SCHEMA FROM GO TYPES
+// ============================================
+// Tool schemas are generated from the same structs we encode/decode,
+// so the schema can never drift from the real model types.
+//
+// Struct tags used:
+//   json:"name,omitempty"  - property name; omitempty = optional
+//   desc:"..."             - property description
+//   enum:"IN,OUT,WASTE"    - allowed string values
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor returns a JSON schema object describing v's type
+func SchemaFor(v interface{}) map[string]interface{} {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+// schemaForType converts one Go type to a JSON schema
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaForType(t.Elem())}
+	case reflect.Struct:
+		return schemaForStruct(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// schemaForStruct lists exported fields as properties
+func schemaForStruct(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, optional, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		prop := schemaForType(f.Type)
+		if d := f.Tag.Get("desc"); d != "" {
+			prop["description"] = d
+		}
+		if e := f.Tag.Get("enum"); e != "" {
+			prop["enum"] = strings.Split(e, ",")
+		}
+		props[name] = prop
+		if !optional {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonName reads the json tag the same way encoding/json does
+func jsonName(f reflect.StructField) (name string, optional, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			optional = true
+		}
+	}
+	return name, optional, false
+}
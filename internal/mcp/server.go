@@ -0,0 +1,258 @@
+// Package mcp implements a Model Context Protocol server over stdio.
+// AI assistants (Claude Desktop, VS Code, ...) start the binary, send
+// JSON-RPC 2.0 messages line by line on stdin and read answers on stdout.
+// Tools are backed directly by repository.Repository.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// ProtocolVersion is the newest MCP revision this server speaks
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are revisions we can answer in, newest first
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Options configure the server
+type Options struct {
+	ReadOnly bool   // Hide tools that change data
+	Reporter string // ReportedBy for recorded movements
+	Version  string // Reported in serverInfo
+}
+
+// Server answers MCP requests using the repository
+type Server struct {
+	store    repository.Repository
+	readOnly bool
+	reporter string
+	version  string
+	tools    []*tool
+}
+
+// NewServer creates an MCP server for the given store
+func NewServer(store repository.Repository, opts Options) *Server {
+	if opts.Reporter == "" {
+		opts.Reporter = "mcp"
+	}
+	if opts.Version == "" {
+		opts.Version = "dev"
+	}
+	s := &Server{store: store, readOnly: opts.ReadOnly, reporter: opts.Reporter, version: opts.Version}
+	s.tools = s.buildTools()
+	return s
+}
+
+// request is an incoming JSON-RPC message (request or notification)
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Missing = notification
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC message
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads newline-delimited JSON-RPC messages until EOF or ctx is done
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	enc := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handle(line); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("write response: %w", err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// handle processes one message; returns nil for notifications
+func (s *Server) handle(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return &response{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{codeInvalidRequest, "invalid request"}}
+	}
+
+	// Notifications (no id) never get an answer
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	result, rerr := s.dispatch(req.Method, req.Params)
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if rerr != nil {
+		resp.Error = rerr
+	} else {
+		resp.Result = result
+	}
+	return resp
+}
+
+// idOrNull returns the request id, or JSON null when it is missing
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// dispatch routes a method to its handler
+func (s *Server) dispatch(method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(params)
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + method}
+	}
+}
+
+// initialize agrees on a protocol version and announces capabilities
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid initialize params"}
+		}
+	}
+
+	version := ProtocolVersion
+	for _, v := range supportedVersions {
+		if v == p.ProtocolVersion {
+			version = v
+			break
+		}
+	}
+
+	instructions := "Restaurant inventory. Stock is counted in full boxes plus loose units."
+	if s.readOnly {
+		instructions += " This server is read-only."
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{"listChanged": false},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "restaurant-inventory",
+			"version": s.version,
+		},
+		"instructions": instructions,
+	}, nil
+}
+
+// visibleTools hides write tools in read-only mode
+func (s *Server) visibleTools() []*tool {
+	var res []*tool
+	for _, t := range s.tools {
+		if t.Writes && s.readOnly {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+// listTools answers tools/list
+func (s *Server) listTools() interface{} {
+	list := make([]map[string]interface{}, 0, len(s.tools))
+	for _, t := range s.visibleTools() {
+		list = append(list, map[string]interface{}{
+			"name":         t.Name,
+			"description":  t.Description,
+			"inputSchema":  t.inputSchema,
+			"outputSchema": t.outputSchema,
+		})
+	}
+	return map[string]interface{}{"tools": list}
+}
+
+// callTool answers tools/call. Tool failures are results with isError,
+// so the model can read the message; only protocol problems are RPC errors.
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, "invalid tools/call params"}
+	}
+
+	var target *tool
+	for _, t := range s.visibleTools() {
+		if t.Name == p.Name {
+			target = t
+			break
+		}
+	}
+	if target == nil {
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + p.Name}
+	}
+
+	result, err := target.Handler(p.Arguments)
+	if err != nil {
+		log.Printf("mcp: tool %s failed: %v", p.Name, err)
+		return map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+
+	text, err := json.Marshal(result)
+	if err != nil {
+		return nil, &rpcError{codeInternalError, "cannot encode result"}
+	}
+	return map[string]interface{}{
+		"content":           []map[string]string{{"type": "text", "text": string(text)}},
+		"structuredContent": result,
+		"isError":           false,
+	}, nil
+}
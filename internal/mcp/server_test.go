@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// roundTrip sends messages to a server and decodes every response line
+func roundTrip(t *testing.T, s *Server, lines ...string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	var res []map[string]interface{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("bad response: %v", err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// toolNames lists the names in a tools/list result
+func toolNames(resp map[string]interface{}) []string {
+	var names []string
+	for _, tl := range resp["result"].(map[string]interface{})["tools"].([]interface{}) {
+		names = append(names, tl.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+func TestServer(t *testing.T) {
+	store := repository.NewMemoryStore()
+	id, err := store.AddProduct(&models.Product{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+
+	resps := roundTrip(t, NewServer(store, Options{Reporter: "laptop"}),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"record_movement","arguments":{"productId":"`+id+`","type":"IN","boxes":2,"performedBy":"Yosef"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_stock","arguments":{"productId":"`+id+`"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_stock","arguments":{"productId":"PROD-404"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"nope"}`,
+		`not json`,
+	)
+	if len(resps) != 7 {
+		t.Fatalf("expected 7 responses (notification has none), got %d", len(resps))
+	}
+
+	init := resps[0]["result"].(map[string]interface{})
+	if init["protocolVersion"] != "2025-03-26" {
+		t.Fatalf("expected negotiated version, got %v", init["protocolVersion"])
+	}
+	if names := toolNames(resps[1]); len(names) != 4 {
+		t.Fatalf("expected 4 tools, got %v", names)
+	}
+
+	rec := resps[2]["result"].(map[string]interface{})
+	if rec["isError"] != false {
+		t.Fatalf("record_movement failed: %v", rec)
+	}
+	movement := rec["structuredContent"].(map[string]interface{})
+	if movement["ReportedBy"] != "laptop" || movement["Source"] != models.SourceAssistant {
+		t.Fatalf("unexpected audit fields: %v", movement)
+	}
+
+	stock := resps[3]["result"].(map[string]interface{})["structuredContent"].(map[string]interface{})
+	if stock["totalUnits"].(float64) != 48 {
+		t.Fatalf("expected 48 units in stock, got %v", stock)
+	}
+	if resps[4]["result"].(map[string]interface{})["isError"] != true {
+		t.Fatalf("expected tool error for unknown product, got %v", resps[4])
+	}
+	if resps[5]["error"].(map[string]interface{})["code"].(float64) != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %v", resps[5])
+	}
+	if resps[6]["error"].(map[string]interface{})["code"].(float64) != codeParseError {
+		t.Fatalf("expected parse error, got %v", resps[6])
+	}
+}
+
+func TestServerReadOnly(t *testing.T) {
+	s := NewServer(repository.NewMemoryStore(), Options{ReadOnly: true})
+	resps := roundTrip(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"record_movement","arguments":{}}}`,
+	)
+	for _, name := range toolNames(resps[0]) {
+		if name == "record_movement" {
+			t.Fatalf("record_movement must be hidden in read-only mode")
+		}
+	}
+	if resps[1]["error"] == nil {
+		t.Fatalf("expected record_movement call to be refused, got %v", resps[1])
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(recordMovementArgs{})
+	props := schema["properties"].(map[string]interface{})
+	if props["boxes"].(map[string]interface{})["type"] != "integer" {
+		t.Fatalf("expected boxes to be an integer, got %v", props["boxes"])
+	}
+	if enum := props["type"].(map[string]interface{})["enum"].([]string); len(enum) != 4 {
+		t.Fatalf("expected 4 movement types, got %v", enum)
+	}
+	required := strings.Join(schema["required"].([]string), ",")
+	if required != "productId,type,performedBy" {
+		t.Fatalf("unexpected required fields: %s", required)
+	}
+	created := SchemaFor(models.StockMovement{})["properties"].(map[string]interface{})["CreatedAt"].(map[string]interface{})
+	if created["format"] != "date-time" {
+		t.Fatalf("expected time.Time as date-time, got %v", created)
+	}
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// tool is one MCP tool: its schemas and the function behind it
+type tool struct {
+	Name         string
+	Description  string
+	Input        interface{} // Zero value of the arguments struct (for the schema)
+	Output       interface{} // Zero value of the result struct (for the schema)
+	Writes       bool        // Hidden in read-only mode
+	Handler      func(args json.RawMessage) (interface{}, error)
+	inputSchema  map[string]interface{}
+	outputSchema map[string]interface{}
+}
+
+// ============================================
+// TOOL ARGUMENTS AND RESULTS
+// ============================================
+
+type searchProductsArgs struct {
+	Query string `json:"query" desc:"Words from the product name or brand (Hebrew or English)"`
+}
+
+type productIDArgs struct {
+	ProductID string `json:"productId" desc:"Product ID, e.g. PROD-001"`
+}
+
+type noArgs struct{}
+
+type recordMovementArgs struct {
+	ProductID   string `json:"productId" desc:"Product ID from search_products"`
+	Type        string `json:"type" enum:"IN,OUT,WASTE,ADJUSTMENT" desc:"IN = received, OUT = used/sold, WASTE = thrown away, ADJUSTMENT = count correction (signed)"`
+	Boxes       int    `json:"boxes,omitempty" desc:"Full boxes"`
+	Units       int    `json:"units,omitempty" desc:"Loose units"`
+	PerformedBy string `json:"performedBy" desc:"Who physically did it"`
+	Reason      string `json:"reason,omitempty" desc:"delivery, sold, expired..."`
+}
+
+type productList struct {
+	Products []*models.Product `json:"products"`
+}
+
+type stockResult struct {
+	Product    *models.Product `json:"product"`
+	Stock      *models.Stock   `json:"stock"`
+	TotalUnits int             `json:"totalUnits"`
+	IsLow      bool            `json:"isLow"`
+}
+
+// buildTools registers every tool backed by the repository
+func (s *Server) buildTools() []*tool {
+	tools := []*tool{
+		{
+			Name:        "search_products",
+			Description: "Search active products by name or brand.",
+			Input:       searchProductsArgs{},
+			Output:      productList{},
+			Handler: func(raw json.RawMessage) (interface{}, error) {
+				var args searchProductsArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				return productList{Products: nonNil(s.store.SearchProducts(args.Query))}, nil
+			},
+		},
+		{
+			Name:        "get_stock",
+			Description: "Get current stock for one product: boxes, loose units, total units and minimum.",
+			Input:       productIDArgs{},
+			Output:      stockResult{},
+			Handler: func(raw json.RawMessage) (interface{}, error) {
+				var args productIDArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				product, err := s.store.GetProduct(args.ProductID)
+				if err != nil {
+					return nil, err
+				}
+				stock, err := s.store.GetStock(args.ProductID)
+				if err != nil {
+					return nil, err
+				}
+				return stockResult{
+					Product:    product,
+					Stock:      stock,
+					TotalUnits: stock.TotalUnits(product.BoxSize),
+					IsLow:      stock.IsLowStock(product.BoxSize),
+				}, nil
+			},
+		},
+		{
+			Name:        "list_low_stock",
+			Description: "List active products below their minimum stock.",
+			Input:       noArgs{},
+			Output:      productList{},
+			Handler: func(raw json.RawMessage) (interface{}, error) {
+				return productList{Products: nonNil(s.store.GetLowStockProducts())}, nil
+			},
+		},
+		{
+			Name:        "record_movement",
+			Description: "Record a stock movement and update stock. Confirm with the user before calling.",
+			Input:       recordMovementArgs{},
+			Output:      models.StockMovement{},
+			Writes:      true,
+			Handler: func(raw json.RawMessage) (interface{}, error) {
+				var args recordMovementArgs
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				m, err := models.NewStockMovement(args.ProductID, args.Type, args.Boxes, args.Units, args.PerformedBy, s.reporter, args.Reason)
+				if err != nil {
+					return nil, err
+				}
+				m.Source = models.SourceAssistant
+				if _, err := s.store.RecordMovement(m); err != nil {
+					return nil, err
+				}
+				return m, nil
+			},
+		},
+	}
+
+	for _, t := range tools {
+		t.inputSchema = SchemaFor(t.Input)
+		t.outputSchema = SchemaFor(t.Output)
+	}
+	return tools
+}
+
+// decodeArgs strictly decodes tool arguments (unknown fields are errors)
+func decodeArgs(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// nonNil turns a nil slice into an empty one so JSON shows [] not null
+func nonNil(products []*models.Product) []*models.Product {
+	if products == nil {
+		return []*models.Product{}
+	}
+	return products
+}
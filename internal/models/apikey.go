@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// API key errors
+var (
+	ErrAPIKeyNameRequired = errors.New("API key name is required")
+	ErrAPIKeyNameTooLong  = errors.New("API key name must be at most 100 characters")
+	ErrAPIKeyNoScopes     = errors.New("API key needs at least one scope")
+	ErrAPIKeyHash         = errors.New("API key hash is required")
+)
+
+// MaxAPIKeyNameLength is the longest key name we accept (in characters)
+const MaxAPIKeyNameLength = 100
+
+// APIKey lets a machine (the POS, a script on the bar tablet) call the API
+// without a human login. Only the hash of the key is stored; the key itself
+// is shown once, when it is created.
+type APIKey struct {
+	ID         string    // Unique identifier, also the first part of the key
+	Name       string    // What uses it: "POS", "bar tablet"
+	KeyHash    string    // SHA-256 of the secret part (hex)
+	Scopes     []string  // What it may do: "inventory:view", "movements:out"
+	CreatedBy  string    // User.ID of the owner who created it
+	CreatedAt  time.Time // When it was created
+	LastUsedAt time.Time // Zero = never used
+	RevokedAt  time.Time // Zero = active
+}
+
+// IsActive reports whether the key can still be used
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt.IsZero()
+}
+
+// Validate checks if an APIKey is valid
+func (k *APIKey) Validate() error {
+	if strings.TrimSpace(k.Name) == "" {
+		return ErrAPIKeyNameRequired
+	}
+	if utf8.RuneCountInString(k.Name) > MaxAPIKeyNameLength {
+		return ErrAPIKeyNameTooLong
+	}
+	if len(k.Scopes) == 0 {
+		return ErrAPIKeyNoScopes
+	}
+	if k.KeyHash == "" {
+		return ErrAPIKeyHash
+	}
+	return nil
+}
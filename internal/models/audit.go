@@ -0,0 +1,74 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// Audited entities
+const (
+	AuditEntityProduct = "product"
+)
+
+// Audit actions
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"        // Soft delete (IsActive → false)
+	AuditRestore  = "restore"       // Back from the archive (IsActive → true)
+	AuditPurge    = "purge"         // Hard delete: the product is gone
+	AuditMerge    = "merge"         // A duplicate folded into another product
+	AuditMinStock = "set_min_stock" // Low-stock threshold changed
+)
+
+// AuditEntry records one change to a product or setting:
+// who made it, when, and which fields changed from what to what
+type AuditEntry struct {
+	ID        string        // Unique identifier (e.g., "AUD-001")
+	Entity    string        // What kind of thing changed: "product"
+	EntityID  string        // Which one: "PROD-001"
+	Action    string        // "create", "update", "delete", "restore", "purge", "merge", "set_min_stock"
+	Actor     string        // Username (or API key) that made the change; empty if unknown
+	Changes   []FieldChange // Field-level diff
+	CreatedAt time.Time     // When it happened
+}
+
+// FieldChange is one field of an audit entry: Before is nil on create
+type FieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// DiffFields compares two structs of the same type field by field and
+// returns the exported fields that differ (ID, Version and UpdatedAt are
+// skipped: they change with every write).
+// A nil before means the thing was created: every non-empty field of
+// after is listed.
+func DiffFields(before, after interface{}) []FieldChange {
+	a := reflect.Indirect(reflect.ValueOf(after))
+	var b reflect.Value
+	if before != nil && !reflect.ValueOf(before).IsNil() {
+		b = reflect.Indirect(reflect.ValueOf(before))
+	}
+
+	var changes []FieldChange
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if !field.IsExported() || field.Name == "ID" || field.Name == "Version" || field.Name == "UpdatedAt" {
+			continue
+		}
+		newValue := a.Field(i).Interface()
+		if !b.IsValid() {
+			if !a.Field(i).IsZero() {
+				changes = append(changes, FieldChange{Field: field.Name, After: newValue})
+			}
+			continue
+		}
+		oldValue := b.Field(i).Interface()
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: field.Name, Before: oldValue, After: newValue})
+		}
+	}
+	return changes
+}
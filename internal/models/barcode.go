@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ============================================
+// BARCODES: EAN-8, UPC-A, EAN-13, GTIN-14
+// ============================================
+
+// Barcode errors
+var (
+	ErrBarcodeInvalid      = errors.New("barcode must be 8, 12, 13 or 14 digits")
+	ErrBarcodeCheckDigit   = errors.New("barcode check digit is wrong")
+	ErrInvalidPackLevel    = errors.New("pack level must be unit or case")
+	ErrBarcodeCaseNeedsBox = errors.New("case barcode needs a product with a box size")
+)
+
+// Pack levels - what one scan of the barcode means
+const (
+	PackUnit = "unit" // Barcode on a single item: one scan = 1 unit
+	PackCase = "case" // Barcode on the box: one scan = 1 box
+)
+
+// ProductBarcode links a printed barcode to a product and pack level
+// A product can have many barcodes (new packaging, unit + case, ...)
+type ProductBarcode struct {
+	Code      string    // Normalized digits (see NormalizeBarcode)
+	ProductID string    // Links to Product.ID
+	PackLevel string    // "unit" or "case"
+	CreatedAt time.Time // When it was added
+}
+
+// Validate checks the code and pack level
+func (b *ProductBarcode) Validate() error {
+	if b.PackLevel != PackUnit && b.PackLevel != PackCase {
+		return fmt.Errorf("%w: %s", ErrInvalidPackLevel, b.PackLevel)
+	}
+	_, err := NormalizeBarcode(b.Code)
+	return err
+}
+
+// NormalizeBarcode validates a scanned code and returns its stored form.
+// UPC-A (12 digits) is stored as EAN-13 with a leading 0, so scanners
+// that report either form find the same product.
+func NormalizeBarcode(code string) (string, error) {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w: %q", ErrBarcodeInvalid, code)
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %q", ErrBarcodeInvalid, code)
+		}
+	}
+	if !validCheckDigit(code) {
+		return "", fmt.Errorf("%w: %s", ErrBarcodeCheckDigit, code)
+	}
+	if len(code) == 12 {
+		code = "0" + code
+	}
+	return code, nil
+}
+
+// validCheckDigit runs the GS1 mod-10 check used by all EAN/UPC codes:
+// from the right (without the check digit), weights are 3,1,3,1...
+func validCheckDigit(code string) bool {
+	sum := 0
+	body := code[:len(code)-1]
+	for i := len(body) - 1; i >= 0; i-- {
+		digit := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := (10 - sum%10) % 10
+	return check == int(code[len(code)-1]-'0')
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		err  error
+	}{
+		{"4006381333931", "4006381333931", nil},   // EAN-13
+		{"036000291452", "0036000291452", nil},    // UPC-A → EAN-13
+		{"0036000291452", "0036000291452", nil},   // Same code scanned as EAN-13
+		{"96385074", "96385074", nil},             // EAN-8
+		{"10012345678902", "10012345678902", nil}, // GTIN-14 (case)
+		{"4006381333932", "", ErrBarcodeCheckDigit},
+		{"036000291453", "", ErrBarcodeCheckDigit},
+		{"12345", "", ErrBarcodeInvalid},
+		{"40063813339A1", "", ErrBarcodeInvalid},
+		{"", "", ErrBarcodeInvalid},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeBarcode(tt.code)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("NormalizeBarcode(%q) error = %v, want %v", tt.code, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeBarcode(%q) = %q, %v; want %q", tt.code, got, err, tt.want)
+		}
+	}
+}
+
+func TestProductBarcodeValidate(t *testing.T) {
+	b := &ProductBarcode{Code: "4006381333931", ProductID: "PROD-001", PackLevel: "pallet"}
+	if err := b.Validate(); !errors.Is(err, ErrInvalidPackLevel) {
+		t.Fatalf("expected ErrInvalidPackLevel, got %v", err)
+	}
+	b.PackLevel = PackCase
+	if err := b.Validate(); err != nil {
+		t.Fatalf("expected valid barcode, got %v", err)
+	}
+}
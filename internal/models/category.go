@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+)
+
+// Category errors
+var (
+	ErrCategoryInvalidID    = errors.New("category ID must be 1-50 lowercase letters, digits or _ (e.g. dry_goods)")
+	ErrCategoryNameRequired = errors.New("category needs a Hebrew or English name")
+	ErrCategoryNameTooLong  = errors.New("category name must be at most 100 characters")
+	ErrCategoryMinStock     = errors.New("category default min stock cannot be negative")
+	ErrCategoryOwnParent    = errors.New("category cannot be its own parent")
+)
+
+// MaxCategoryNameLength is the longest category name we accept (in characters)
+const MaxCategoryNameLength = 100
+
+// categoryIDPattern is what a category ID looks like: "drinks", "dry_goods"
+var categoryIDPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// Category groups products ("drinks", "dairy"). Products store the ID,
+// so renaming a category doesn't touch them.
+type Category struct {
+	ID              string // Short lowercase key: "drinks"
+	NameHe          string // Hebrew name: "משקאות"
+	NameEn          string // English name: "Drinks"
+	SortOrder       int    // Lists show categories by SortOrder, then ID
+	ParentID        string // Optional parent category ("" = top level)
+	DefaultMinStock int    // Min stock (units) given to new products of the category
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// DisplayName returns the name shown in lists (Hebrew first)
+func (c *Category) DisplayName() string {
+	if c.NameHe != "" {
+		return c.NameHe
+	}
+	return c.NameEn
+}
+
+// LocalizedName returns the name in lang, or the other name when that
+// one is empty
+func (c *Category) LocalizedName(lang string) string {
+	if lang == i18n.English && c.NameEn != "" {
+		return c.NameEn
+	}
+	return c.DisplayName()
+}
+
+// Validate checks if a Category is valid (the parent is checked by the store)
+func (c *Category) Validate() error {
+	if !categoryIDPattern.MatchString(c.ID) {
+		return ErrCategoryInvalidID
+	}
+	if strings.TrimSpace(c.NameHe) == "" && strings.TrimSpace(c.NameEn) == "" {
+		return ErrCategoryNameRequired
+	}
+	if utf8.RuneCountInString(c.NameHe) > MaxCategoryNameLength || utf8.RuneCountInString(c.NameEn) > MaxCategoryNameLength {
+		return ErrCategoryNameTooLong
+	}
+	if c.DefaultMinStock < 0 {
+		return ErrCategoryMinStock
+	}
+	if c.ParentID == c.ID {
+		return ErrCategoryOwnParent
+	}
+	return nil
+}
+
+// CategoryChecker tells whether a category exists. The repository is one;
+// Product.Validate asks it.
+type CategoryChecker interface {
+	HasCategory(id string) bool
+}
+
+// DefaultCategories are the categories a new store starts with
+// (migration 015 inserts the same rows)
+func DefaultCategories() []*Category {
+	return []*Category{
+		{ID: "drinks", NameHe: "משקאות", NameEn: "Drinks", SortOrder: 10},
+		{ID: "vegetables", NameHe: "ירקות", NameEn: "Vegetables", SortOrder: 20},
+		{ID: "dairy", NameHe: "מוצרי חלב", NameEn: "Dairy", SortOrder: 30},
+		{ID: "meat", NameHe: "בשר", NameEn: "Meat", SortOrder: 40},
+		{ID: "basics", NameHe: "מוצרים בסיסיים", NameEn: "Basics", SortOrder: 50},
+		{ID: "dry_goods", NameHe: "מוצרים יבשים", NameEn: "Dry goods", SortOrder: 60},
+		{ID: "sauces", NameHe: "רטבים", NameEn: "Sauces", SortOrder: 70},
+		{ID: "canned", NameHe: "שימורים", NameEn: "Canned goods", SortOrder: 80},
+		{ID: "packaging", NameHe: "אריזות", NameEn: "Packaging", SortOrder: 90},
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key header we accept
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyRecord remembers the first response to a request sent with an
+// Idempotency-Key header, so a retry (phone on bad Wi-Fi) gets the same
+// answer instead of recording "received 5 boxes" twice
+type IdempotencyRecord struct {
+	Scope       string            // Who sent it: user ID or "key:<API key ID>" ("" with auth off)
+	Key         string            // The Idempotency-Key header
+	RequestHash string            // SHA-256 of method, path and body (hex)
+	Status      int               // HTTP status; 0 while the first request is still running
+	Headers     map[string]string // Response headers worth replaying (Content-Type, ETag)
+	Body        []byte            // Response body
+	CreatedAt   time.Time
+	ExpiresAt   time.Time // After this the key can be used again
+}
+
+// IsDone reports whether the first request has finished
+func (r *IdempotencyRecord) IsDone() bool {
+	return r.Status != 0
+}
+
+// IsExpired reports whether the record no longer protects its key
+func (r *IdempotencyRecord) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -4,7 +4,9 @@ package models
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ============================================
@@ -16,7 +18,7 @@ var (
 	// Product errors
 	ErrProductNameRequired    = errors.New("product name is required")
 	ErrProductInvalidSize     = errors.New("product size must be positive")
-	ErrProductInvalidPrice    = errors.New("product price must be positive")
+	ErrProductInvalidPrice    = errors.New("product price cannot be negative")
 	ErrProductInvalidCategory = errors.New("invalid product category")
 
 	// Stock errors
@@ -27,20 +29,31 @@ var (
 	ErrMovementInvalidType = errors.New("invalid movement type")
 	ErrMovementNoQuantity  = errors.New("movement must have boxes or units")
 	ErrMovementNoPerformer = errors.New("performed_by is required")
+	ErrMovementClientID    = errors.New("client ID must be at most 100 characters")
+
+	// Alias errors
+	ErrAliasRequired        = errors.New("alias is required")
+	ErrAliasTooLong         = errors.New("alias must be at most 100 characters")
+	ErrAliasProductRequired = errors.New("product ID is required for alias")
 )
 
 // Product represents an item in our inventory
 // Identified uniquely by: Brand + Size + ContainerType
+// The JSON names are the ones PATCH /products/{id} takes, so a client can
+// build a merge patch from the product it fetched.
 type Product struct {
-	ID            string  // Unique identifier (e.g., "PROD-001")
-	Name          string  // Hebrew name: "קוקה קולה 330 מ״ל פחית"
-	Brand         string  // Brand name (usually English): "Coca Cola"
-	Size          int     // Size in ml or grams
-	ContainerType string  // "can", "bottle", "bag", "piece"
-	BoxSize       int     // Units per box (0 if sold individually)
-	Price         float64 // Price per unit in NIS
-	Category      string  // "drinks", "vegetables", "dairy"
-	IsActive      bool    // Is product still sold?
+	ID            string  `json:"id"`            // Unique identifier (e.g., "PROD-001")
+	Name          string  `json:"name"`          // Hebrew name: "קוקה קולה 330 מ״ל פחית"
+	Brand         string  `json:"brand"`         // Brand name (usually English): "Coca Cola"
+	Size          int     `json:"size"`          // Size in ml or grams
+	ContainerType string  `json:"containerType"` // "can", "bottle", "bag", "piece"
+	BoxSize       int     `json:"boxSize"`       // Units per box (0 if sold individually)
+	Price         float64 `json:"price"`         // Price per unit in NIS
+	Category      string  `json:"category"`      // "drinks", "vegetables", "dairy"
+	IsActive      bool    `json:"isActive"`      // Is product still sold?
+	Version       int     `json:"version"`       // Bumped on every change (the API's ETag); 0 on input = don't check
+
+	UpdatedAt time.Time `json:"updatedAt"` // Last change (offline clients sync what changed since)
 }
 
 // Stock tracks inventory levels for a product
@@ -50,6 +63,7 @@ type Stock struct {
 	QuantityUnits int       // Loose units (not in boxes)
 	MinStock      int       // Alert threshold
 	LastUpdated   time.Time // Last modification time
+	Version       int       // Bumped on every change (the API's ETag)
 }
 
 // TotalUnits calculates total units from boxes and loose units
@@ -73,7 +87,42 @@ type StockMovement struct {
 	PerformedBy string    // WHO actually did the physical action
 	ReportedBy  string    // WHO logged it in the system
 	Reason      string    // Why: "delivery", "sold", "expired"
-	CreatedAt   time.Time // When this was logged
+	Source      string    // HOW it was logged: "manual", "assistant", "scan", "invoice", "offline"
+	CreatedAt   time.Time // When this was logged (on the device, for offline movements)
+
+	// Staff directory links; the names above keep the spelling at the time.
+	// Empty on movements logged before the directory existed.
+	PerformedByID string // Staff.ID of the performer
+	ReportedByID  string // Staff.ID of the reporter
+
+	// ID the offline client made up, so a batch sent twice is applied once.
+	// Unique; empty for movements logged online.
+	ClientID string
+}
+
+// ProductAlias is another name staff use for a product:
+// Hebrew or English nickname, transliteration, slang ("קולה קטנה", "coke can")
+type ProductAlias struct {
+	ID        string    // Unique identifier (e.g., "ALS-001")
+	ProductID string    // Links to Product.ID
+	Alias     string    // The name as staff say it
+	CreatedAt time.Time // When it was added
+}
+
+// StockDelta returns the signed change this movement makes to stock.
+// IN adds, OUT and WASTE remove, ADJUSTMENT is applied as-is (can be negative).
+func (m *StockMovement) StockDelta() (boxes, units int) {
+	switch m.Type {
+	case MovementOut, MovementWaste:
+		return -m.Boxes, -m.Units
+	default:
+		return m.Boxes, m.Units
+	}
+}
+
+// TotalUnits converts the movement quantity to units using the product's box size
+func (m *StockMovement) TotalUnits(boxSize int) int {
+	return (m.Boxes * boxSize) + m.Units
 }
 
 // Movement types as constants
@@ -84,16 +133,14 @@ const (
 	MovementAdjustment = "ADJUSTMENT" // Inventory correction
 )
 
-// Categories in Hebrew and English
-var Categories = map[string]string{
-	"drinks":     "משקאות",
-	"vegetables": "ירקות",
-	"dairy":      "מוצרי חלב",
-	"meat":       "בשר",
-	"dry_goods":  "מוצרים יבשים",
-	"sauces":     "רטבים",
-	"canned":     "שימורים",
-}
+// Movement sources - where a movement was logged from
+const (
+	SourceManual    = "manual"    // Typed in by a person
+	SourceAssistant = "assistant" // Proposed by the AI assistant, confirmed by a person
+	SourceScan      = "scan"      // Barcode scanned at the shelf or back door
+	SourceInvoice   = "invoice"   // Supplier invoice line, approved by the owner
+	SourceOffline   = "offline"   // Logged on a device without signal, synced later
+)
 
 // ============================================
 // VALIDATION FUNCTIONS
@@ -101,8 +148,9 @@ var Categories = map[string]string{
 // ============================================
 
 // Validate checks if a Product has all required fields
-// Returns nil if valid, or an error describing what's wrong
-func (p *Product) Validate() error {
+// Returns nil if valid, or an error describing what's wrong.
+// The category must be one categories knows; nil skips that check.
+func (p *Product) Validate(categories CategoryChecker) error {
 	// Check required string fields
 	if p.Name == "" {
 		return ErrProductNameRequired
@@ -117,8 +165,8 @@ func (p *Product) Validate() error {
 	}
 
 	// Check category is valid
-	if p.Category != "" {
-		if _, exists := Categories[p.Category]; !exists {
+	if p.Category != "" && categories != nil {
+		if !categories.HasCategory(p.Category) {
 			// Wrap error with context using fmt.Errorf and %w
 			return fmt.Errorf("%w: %s", ErrProductInvalidCategory, p.Category)
 		}
@@ -163,6 +211,30 @@ func (m *StockMovement) Validate() error {
 		return ErrMovementNoPerformer
 	}
 
+	if utf8.RuneCountInString(m.ClientID) > MaxClientIDLength {
+		return ErrMovementClientID
+	}
+
+	return nil
+}
+
+// MaxClientIDLength is the longest movement client ID we accept
+const MaxClientIDLength = 100
+
+// MaxAliasLength is the longest alias we accept (in characters)
+const MaxAliasLength = 100
+
+// Validate checks if a ProductAlias is valid
+func (a *ProductAlias) Validate() error {
+	if a.ProductID == "" {
+		return ErrAliasProductRequired
+	}
+	if strings.TrimSpace(a.Alias) == "" {
+		return ErrAliasRequired
+	}
+	if utf8.RuneCountInString(a.Alias) > MaxAliasLength {
+		return ErrAliasTooLong
+	}
 	return nil
 }
 
@@ -171,7 +243,8 @@ func (m *StockMovement) Validate() error {
 // ============================================
 
 // NewProduct creates a new Product with validation
-// This is a "constructor" pattern in Go
+// This is a "constructor" pattern in Go. The category is checked when the
+// product is stored.
 func NewProduct(name, brand string, size int, containerType string, boxSize int, price float64, category string) (*Product, error) {
 	p := &Product{
 		ID:            "", // Will be set by database
@@ -186,7 +259,7 @@ func NewProduct(name, brand string, size int, containerType string, boxSize int,
 	}
 
 	// Validate before returning
-	if err := p.Validate(); err != nil {
+	if err := p.Validate(nil); err != nil {
 		return nil, fmt.Errorf("invalid product: %w", err)
 	}
 
@@ -210,6 +283,7 @@ func NewStockMovement(productID, movementType string, boxes, units int, performe
 		PerformedBy: performedBy,
 		ReportedBy:  reportedBy,
 		Reason:      reason,
+		Source:      SourceManual,
 		CreatedAt:   time.Now(),
 	}
 
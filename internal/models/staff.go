@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Staff errors
+var (
+	ErrStaffNameRequired = errors.New("staff member needs a Hebrew or English name")
+	ErrStaffNameTooLong  = errors.New("staff name must be at most 100 characters")
+)
+
+// MaxStaffNameLength is the longest staff name we accept (in characters)
+const MaxStaffNameLength = 100
+
+// Staff is a person who moves stock - with or without a login.
+// Movements point to staff by ID, so "Yosef", "yosef" and "יוסף"
+// are one person instead of three spellings.
+type Staff struct {
+	ID        string // Unique identifier (e.g., "STF-001")
+	NameHe    string // Hebrew name: "יוסף"
+	NameEn    string // English name: "Yosef"
+	UserID    string // Optional login account (User.ID), "" if none
+	IsActive  bool   // Left the restaurant = inactive (history keeps the name)
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DisplayName returns the name written on movements (Hebrew first)
+func (s *Staff) DisplayName() string {
+	if s.NameHe != "" {
+		return s.NameHe
+	}
+	return s.NameEn
+}
+
+// Validate checks if a Staff member is valid
+func (s *Staff) Validate() error {
+	if strings.TrimSpace(s.NameHe) == "" && strings.TrimSpace(s.NameEn) == "" {
+		return ErrStaffNameRequired
+	}
+	if utf8.RuneCountInString(s.NameHe) > MaxStaffNameLength || utf8.RuneCountInString(s.NameEn) > MaxStaffNameLength {
+		return ErrStaffNameTooLong
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Supplier SKU errors
+var (
+	ErrSupplierRequired           = errors.New("supplier is required")
+	ErrSKURequired                = errors.New("supplier SKU is required")
+	ErrSupplierSKUProductRequired = errors.New("product ID is required for supplier SKU")
+)
+
+// SupplierSKU maps a supplier's item code to one of our products
+// Invoices list the supplier's code, not our product ID
+type SupplierSKU struct {
+	Supplier  string    // Supplier name as written on the invoice
+	SKU       string    // Supplier's item code (מק"ט)
+	ProductID string    // Links to Product.ID
+	PackLevel string    // What one invoiced item is: "unit" or "case"
+	UpdatedAt time.Time // Last time the mapping was set
+}
+
+// Validate checks if a SupplierSKU is valid
+func (s *SupplierSKU) Validate() error {
+	if s.Supplier == "" {
+		return ErrSupplierRequired
+	}
+	if s.SKU == "" {
+		return ErrSKURequired
+	}
+	if s.ProductID == "" {
+		return ErrSupplierSKUProductRequired
+	}
+	if s.PackLevel != PackUnit && s.PackLevel != PackCase {
+		return ErrInvalidPackLevel
+	}
+	return nil
+}
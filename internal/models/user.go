@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/i18n"
+)
+
+// User errors
+var (
+	ErrUsernameInvalid = errors.New("username must be 3-50 letters, digits, '.', '_' or '-'")
+	ErrPasswordHash    = errors.New("password hash is required")
+	ErrInvalidRole     = errors.New("role must be owner, manager or employee")
+	ErrInvalidLanguage = errors.New("language must be he or en")
+)
+
+// User roles, from most to least trusted
+const (
+	RoleOwner    = "owner"    // Everything, including users and deletes
+	RoleManager  = "manager"  // Products and IN/OUT movements; no deletes or adjustments
+	RoleEmployee = "employee" // Views stock and records what they took (OUT)
+)
+
+// ValidRoles contains all allowed user roles
+var ValidRoles = map[string]bool{
+	RoleOwner:    true,
+	RoleManager:  true,
+	RoleEmployee: true,
+}
+
+// User is a person who can log in
+// Usernames are stored lowercase; login is case-insensitive
+type User struct {
+	ID           string    // Unique identifier (e.g., "USR-001")
+	Username     string    // Login name: "dana"
+	DisplayName  string    // Shown in the UI: "דנה כהן"
+	PasswordHash string    // bcrypt hash - never the password itself
+	Role         string    // "owner", "manager" or "employee"
+	IsActive     bool      // Inactive users can't log in
+	FailedLogins int       // Failed attempts since the last success
+	LockedUntil  time.Time // Zero = not locked
+	Language     string    // Language of API messages: "he", "en" or "" (from Accept-Language)
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// IsLocked reports whether the account is locked at the given time
+func (u *User) IsLocked(now time.Time) bool {
+	return now.Before(u.LockedUntil)
+}
+
+// Session is one login (one device). The refresh token belongs to it;
+// logging out revokes the session and every access token issued for it.
+type Session struct {
+	ID          string    // Unique identifier, also the "sid" claim of access tokens
+	UserID      string    // Links to User.ID
+	RefreshHash string    // SHA-256 of the current refresh token (hex)
+	ExpiresAt   time.Time // Refresh token expiry
+	RevokedAt   time.Time // Zero = active
+	CreatedAt   time.Time
+}
+
+// IsActive reports whether the session can still be used at the given time
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt.IsZero() && now.Before(s.ExpiresAt)
+}
+
+// NormalizeUsername trims and lowercases a username
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Validate checks if a User is valid
+func (u *User) Validate() error {
+	n := len([]rune(u.Username))
+	if n < 3 || n > 50 {
+		return fmt.Errorf("%w: %q", ErrUsernameInvalid, u.Username)
+	}
+	for _, r := range u.Username {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-' {
+			return fmt.Errorf("%w: %q", ErrUsernameInvalid, u.Username)
+		}
+	}
+	if u.PasswordHash == "" {
+		return ErrPasswordHash
+	}
+	if !ValidRoles[u.Role] {
+		return fmt.Errorf("%w: %q", ErrInvalidRole, u.Role)
+	}
+	if u.Language != "" && !i18n.Supported(u.Language) {
+		return fmt.Errorf("%w: %q", ErrInvalidLanguage, u.Language)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package nlp
+
+import "github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+
+// ============================================
+// LEXICON: the words the parser understands
+// ============================================
+// Everything is lowercase. Hebrew words are listed without
+// one-letter prefixes (ו, ה, ב, ל, מ, ש) - the parser strips those.
+
+// verb maps a word to a movement type and a default reason
+type verb struct {
+	Type   string
+	Reason string
+}
+
+// verbs in Hebrew (common genders/persons) and English
+var verbs = map[string]verb{
+	// IN - stock received
+	"קיבל": {models.MovementIn, "delivery"}, "קיבלה": {models.MovementIn, "delivery"},
+	"קיבלו": {models.MovementIn, "delivery"}, "קיבלתי": {models.MovementIn, "delivery"},
+	"קיבלנו": {models.MovementIn, "delivery"}, "הגיע": {models.MovementIn, "delivery"},
+	"הגיעה": {models.MovementIn, "delivery"}, "הגיעו": {models.MovementIn, "delivery"},
+	"התקבל": {models.MovementIn, "delivery"}, "התקבלו": {models.MovementIn, "delivery"},
+	"הביא": {models.MovementIn, "delivery"}, "הביאה": {models.MovementIn, "delivery"},
+	"הביאו": {models.MovementIn, "delivery"}, "הכניס": {models.MovementIn, "delivery"},
+	"הכניסה": {models.MovementIn, "delivery"}, "הכניסו": {models.MovementIn, "delivery"},
+	"received": {models.MovementIn, "delivery"}, "receive": {models.MovementIn, "delivery"},
+	"got": {models.MovementIn, "delivery"}, "delivered": {models.MovementIn, "delivery"},
+	"arrived": {models.MovementIn, "delivery"}, "added": {models.MovementIn, "delivery"},
+	"brought": {models.MovementIn, "delivery"},
+
+	// OUT - stock taken for use or sold
+	"לקח": {models.MovementOut, "used"}, "לקחה": {models.MovementOut, "used"},
+	"לקחו": {models.MovementOut, "used"}, "לקחתי": {models.MovementOut, "used"},
+	"לקחנו": {models.MovementOut, "used"}, "הוציא": {models.MovementOut, "used"},
+	"הוציאה": {models.MovementOut, "used"}, "הוציאו": {models.MovementOut, "used"},
+	"הוצאתי": {models.MovementOut, "used"}, "השתמש": {models.MovementOut, "used"},
+	"השתמשה": {models.MovementOut, "used"}, "השתמשו": {models.MovementOut, "used"},
+	"מכר": {models.MovementOut, "sold"}, "מכרה": {models.MovementOut, "sold"},
+	"מכרו": {models.MovementOut, "sold"}, "נמכר": {models.MovementOut, "sold"},
+	"נמכרו": {models.MovementOut, "sold"},
+	"took":  {models.MovementOut, "used"}, "take": {models.MovementOut, "used"},
+	"taken": {models.MovementOut, "used"}, "used": {models.MovementOut, "used"},
+	"removed": {models.MovementOut, "used"}, "sold": {models.MovementOut, "sold"},
+
+	// WASTE - stock thrown away
+	"זרק": {models.MovementWaste, "waste"}, "זרקה": {models.MovementWaste, "waste"},
+	"זרקו": {models.MovementWaste, "waste"}, "זרקתי": {models.MovementWaste, "waste"},
+	"נזרק": {models.MovementWaste, "waste"}, "נזרקו": {models.MovementWaste, "waste"},
+	"התקלקל": {models.MovementWaste, "spoiled"}, "התקלקלו": {models.MovementWaste, "spoiled"},
+	"נשבר": {models.MovementWaste, "broken"}, "נשברו": {models.MovementWaste, "broken"},
+	"פג":    {models.MovementWaste, "expired"},
+	"threw": {models.MovementWaste, "waste"}, "thrown": {models.MovementWaste, "waste"},
+	"wasted": {models.MovementWaste, "waste"}, "expired": {models.MovementWaste, "expired"},
+	"spoiled": {models.MovementWaste, "spoiled"}, "broke": {models.MovementWaste, "broken"},
+	"broken": {models.MovementWaste, "broken"}, "dumped": {models.MovementWaste, "waste"},
+}
+
+// passiveVerbs describe what happened to the stock, not who did it.
+// A word before them is the product ("קולה נזרקו"), never the performer.
+var passiveVerbs = map[string]bool{
+	"הגיע": true, "הגיעה": true, "הגיעו": true, "התקבל": true, "התקבלו": true,
+	"נמכר": true, "נמכרו": true, "נזרק": true, "נזרקו": true, "התקלקל": true,
+	"התקלקלו": true, "נשבר": true, "נשברו": true, "פג": true,
+	"arrived": true, "delivered": true, "taken": true, "sold": true, "thrown": true,
+	"expired": true, "spoiled": true, "broken": true,
+}
+
+// numberWords are Hebrew (both genders, construct form) and English numbers
+var numberWords = map[string]int{
+	"אחד": 1, "אחת": 1,
+	"שניים": 2, "שנים": 2, "שתיים": 2, "שתים": 2, "שני": 2, "שתי": 2,
+	"שלוש": 3, "שלושה": 3, "שלושת": 3,
+	"ארבע": 4, "ארבעה": 4, "ארבעת": 4,
+	"חמש": 5, "חמישה": 5, "חמשת": 5,
+	"שש": 6, "שישה": 6, "ששת": 6,
+	"שבע": 7, "שבעה": 7, "שבעת": 7,
+	"שמונה": 8, "שמונת": 8,
+	"תשע": 9, "תשעה": 9, "תשעת": 9,
+	"עשר": 10, "עשרה": 10, "עשרת": 10,
+	"עשרים": 20, "שלושים": 30, "ארבעים": 40, "חמישים": 50,
+	"one": 1, "a": 1, "an": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"eleven": 11, "twelve": 12, "dozen": 12, "twenty": 20,
+}
+
+// Quantity kinds: is the number counting boxes or loose units?
+const (
+	kindBox  = "box"
+	kindUnit = "unit"
+)
+
+// unitWords map packaging words to boxes or units
+var unitWords = map[string]string{
+	"ארגז": kindBox, "ארגזים": kindBox, "קרטון": kindBox, "קרטונים": kindBox,
+	"box": kindBox, "boxes": kindBox, "case": kindBox, "cases": kindBox,
+	"carton": kindBox, "cartons": kindBox,
+	"יחידה": kindUnit, "יחידות": kindUnit, "פחית": kindUnit, "פחיות": kindUnit,
+	"בקבוק": kindUnit, "בקבוקים": kindUnit, "שקית": kindUnit, "שקיות": kindUnit,
+	"unit": kindUnit, "units": kindUnit, "can": kindUnit, "cans": kindUnit,
+	"bottle": kindUnit, "bottles": kindUnit, "piece": kindUnit, "pieces": kindUnit,
+	"pcs": kindUnit, "bag": kindUnit, "bags": kindUnit,
+}
+
+// sizeWords follow a number that is a product size, not a quantity ("330 מ״ל")
+var sizeWords = map[string]bool{
+	"מ״ל": true, "מל": true, "ml": true, "ליטר": true, "l": true,
+	"גרם": true, "גר": true, "g": true, "ק״ג": true, "קג": true, "kg": true,
+}
+
+// selfWords mean the speaker did it ("I took..."): performer = reporter
+var selfWords = map[string]bool{
+	"אני": true, "אנחנו": true, "i": true, "we": true,
+}
+
+// byWords introduce the performer after the fact ("...taken by Yosef")
+var byWords = map[string]bool{
+	"by": true, "ע״י": true, "עי": true, "ידי": true,
+}
+
+// stopWords carry no meaning for product lookup
+var stopWords = map[string]bool{
+	"את": true, "של": true, "עם": true, "עוד": true, "על": true,
+	"the": true, "of": true, "some": true, "and": true, "from": true, "x": true,
+}
+
+// hebrewPrefixes are one-letter prefixes glued to Hebrew words
+const hebrewPrefixes = "והבלמש"
@@ -0,0 +1,352 @@
+// Package nlp turns free-text staff messages into draft stock movements.
+// It is fully deterministic: word lists + the product repository, no model
+// and no network. Anything it is not sure about is returned as an
+// Ambiguity so the caller can ask the user instead of guessing.
+package nlp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// Fields that can be ambiguous in a parsed message
+const (
+	FieldType      = "type"
+	FieldQuantity  = "quantity"
+	FieldProduct   = "product"
+	FieldPerformer = "performedBy"
+)
+
+// ProductSearcher is the part of the repository the parser needs
+type ProductSearcher interface {
+	SearchProductsRanked(query string) []repository.ProductMatch
+}
+
+// Ambiguity describes one thing the parser could not decide on its own
+type Ambiguity struct {
+	Field   string   `json:"field"`
+	Message string   `json:"message"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Result is the outcome of parsing one message
+// Draft is always filled as far as possible, but only safe to record
+// when IsComplete() is true.
+type Result struct {
+	Text        string                `json:"text"`
+	Draft       *models.StockMovement `json:"draft"`
+	Product     *models.Product       `json:"product,omitempty"`
+	Candidates  []*models.Product     `json:"candidates,omitempty"`
+	Ambiguities []Ambiguity           `json:"ambiguities,omitempty"`
+}
+
+// IsComplete reports whether the draft can be recorded without asking the user
+func (r *Result) IsComplete() bool {
+	return len(r.Ambiguities) == 0
+}
+
+// addAmbiguity records something the user has to clarify
+func (r *Result) addAmbiguity(field, message string, options ...string) {
+	r.Ambiguities = append(r.Ambiguities, Ambiguity{Field: field, Message: message, Options: options})
+}
+
+// Parser converts Hebrew/English messages into draft movements
+type Parser struct {
+	products ProductSearcher
+}
+
+// NewParser creates a parser that resolves products with the given searcher
+func NewParser(products ProductSearcher) *Parser {
+	return &Parser{products: products}
+}
+
+// token is one word of the message
+type token struct {
+	raw  string // As typed (keeps the case of names)
+	norm string // Lowercase, used for lookups
+	used bool   // Already consumed as verb/number/performer/...
+}
+
+// Parse reads a message like "יוסף לקח 2 ארגזים קולה" or
+// "received 5 boxes coke". reporter is the logged-in user; when no
+// performer is mentioned the movement is self-reported.
+func (p *Parser) Parse(text, reporter string) *Result {
+	res := &Result{
+		Text:  text,
+		Draft: &models.StockMovement{ReportedBy: reporter},
+	}
+	tokens := tokenize(text)
+
+	// 1) WHAT happened: the verb decides IN/OUT/WASTE
+	verbIdx := -1
+	for i := range tokens {
+		if v, ok := lookup(verbs, tokens[i].norm); ok {
+			res.Draft.Type = v.Type
+			res.Draft.Reason = v.Reason
+			tokens[i].used = true
+			verbIdx = i
+			break
+		}
+	}
+	if verbIdx == -1 {
+		res.addAmbiguity(FieldType, "what happened to the stock?",
+			models.MovementIn, models.MovementOut, models.MovementWaste)
+	}
+
+	// 2) WHO did it
+	res.Draft.PerformedBy = findPerformer(tokens, verbIdx, reporter)
+	if res.Draft.PerformedBy == "" {
+		res.addAmbiguity(FieldPerformer, "who did it?")
+	}
+
+	// 3) HOW MUCH: "2 ארגזים", "5 boxes", "ארגז" (= 1 box), or a bare number
+	bare, hasBare := findQuantities(tokens, res.Draft)
+
+	// 4) WHICH product: whatever is left
+	var query []string
+	for _, t := range tokens {
+		if t.used || stopWords[t.norm] || selfWords[t.norm] || isHebrewPrefixOnly(t.norm) {
+			continue
+		}
+		query = append(query, t.norm)
+	}
+	p.resolveProduct(query, res)
+
+	// A bare number is boxes or units - only the product can tell
+	if hasBare {
+		res.Draft.Units = bare
+		if !soldLoose(res) {
+			res.addAmbiguity(FieldQuantity, fmt.Sprintf("%d boxes or %d units?", bare, bare), "boxes", "units")
+		}
+	}
+	if res.Draft.Boxes == 0 && res.Draft.Units == 0 {
+		res.addAmbiguity(FieldQuantity, "how many?")
+	}
+
+	return res
+}
+
+// soldLoose reports whether every possible product has no boxes,
+// so a bare number can only mean units
+func soldLoose(res *Result) bool {
+	if res.Product != nil {
+		return res.Product.BoxSize == 0
+	}
+	if len(res.Candidates) == 0 {
+		return false
+	}
+	for _, c := range res.Candidates {
+		if c.BoxSize != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits a message into words, keeping Hebrew geresh/gershayim
+// inside words ("מ״ל", "ע״י")
+func tokenize(text string) []token {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '״' && r != '"' && r != '\'' && r != '׳'
+	})
+	tokens := make([]token, 0, len(words))
+	for _, w := range words {
+		w = strings.Trim(w, "\"'")
+		if w == "" {
+			continue
+		}
+		// Normalize ASCII quotes to gershayim so "מ"ל" == "מ״ל"
+		norm := strings.ToLower(strings.ReplaceAll(w, "\"", "״"))
+		tokens = append(tokens, token{raw: w, norm: norm})
+	}
+	return tokens
+}
+
+// lookup finds a word in a table, retrying without Hebrew prefixes
+// ("והארגזים" → "ארגזים")
+func lookup[T any](table map[string]T, word string) (T, bool) {
+	for _, w := range withoutPrefixes(word) {
+		if v, ok := table[w]; ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// withoutPrefixes returns the word and up to two prefix-stripped variants
+func withoutPrefixes(word string) []string {
+	variants := []string{word}
+	runes := []rune(word)
+	for i := 0; i < 2 && len(runes)-i > 2; i++ {
+		if !strings.ContainsRune(hebrewPrefixes, runes[i]) {
+			break
+		}
+		variants = append(variants, string(runes[i+1:]))
+	}
+	return variants
+}
+
+// isHebrewPrefixOnly catches a prefix split off by a dash ("ו-3")
+func isHebrewPrefixOnly(word string) bool {
+	runes := []rune(word)
+	return len(runes) == 1 && strings.ContainsRune(hebrewPrefixes, runes[0])
+}
+
+// parseNumber reads digits or a number word
+func parseNumber(word string) (int, bool) {
+	if n, err := strconv.Atoi(word); err == nil {
+		return n, true
+	}
+	return lookup(numberWords, word)
+}
+
+// findPerformer looks for "by X" / "ע״י X" or a name right before an
+// active verb ("יוסף לקח"). Falls back to the reporter when the speaker
+// talks about themselves or mentions nobody.
+func findPerformer(tokens []token, verbIdx int, reporter string) string {
+	for i := range tokens {
+		if !tokens[i].used && byWords[tokens[i].norm] && i+1 < len(tokens) && !tokens[i+1].used {
+			tokens[i].used = true
+			tokens[i+1].used = true
+			return tokens[i+1].raw
+		}
+	}
+
+	if verbIdx > 0 && !isPassive(tokens[verbIdx].norm) {
+		var name []string
+		for i := 0; i < verbIdx; i++ {
+			t := tokens[i]
+			if _, isNum := parseNumber(t.norm); isNum {
+				return reporter
+			}
+			if _, isUnit := lookup(unitWords, t.norm); isUnit {
+				return reporter
+			}
+			if !stopWords[t.norm] {
+				name = append(name, t.raw)
+			}
+		}
+		if len(name) > 0 && len(name) <= 2 {
+			for i := 0; i < verbIdx; i++ {
+				tokens[i].used = true
+			}
+			if len(name) == 1 && selfWords[strings.ToLower(name[0])] {
+				return reporter
+			}
+			return strings.Join(name, " ")
+		}
+	}
+
+	return reporter
+}
+
+// isPassive reports whether a verb takes no performer before it
+func isPassive(word string) bool {
+	_, ok := lookup(passiveVerbs, word)
+	return ok
+}
+
+// findQuantities fills Boxes/Units from "<number> <unit word>" pairs and
+// lone box words. Returns the first number with no unit word, if any.
+func findQuantities(tokens []token, draft *models.StockMovement) (int, bool) {
+	bare, hasBare := 0, false
+
+	for i := range tokens {
+		if tokens[i].used {
+			continue
+		}
+		n, isNum := parseNumber(tokens[i].norm)
+		if !isNum {
+			// "ארגז קולה" / "box of coke" = one box
+			if kind, ok := lookup(unitWords, tokens[i].norm); ok && kind == kindBox && isSingularBox(tokens[i].norm) {
+				draft.Boxes++
+				tokens[i].used = true
+			}
+			continue
+		}
+
+		if i+1 < len(tokens) {
+			next := tokens[i+1].norm
+			if _, isSize := lookup(sizeWords, next); isSize {
+				continue // "330 מ״ל" is part of the product name
+			}
+			if kind, ok := lookup(unitWords, next); ok {
+				if kind == kindBox {
+					draft.Boxes += n
+				} else {
+					draft.Units += n
+				}
+				tokens[i].used = true
+				tokens[i+1].used = true
+				continue
+			}
+		}
+
+		// Number words like "a"/"one" only count next to a unit word
+		if _, err := strconv.Atoi(tokens[i].norm); err != nil && n == 1 {
+			continue
+		}
+		if !hasBare {
+			bare, hasBare = n, true
+			tokens[i].used = true
+		}
+	}
+
+	if draft.Boxes != 0 || draft.Units != 0 {
+		return 0, false
+	}
+	return bare, hasBare
+}
+
+// isSingularBox reports whether a box word means exactly one box
+func isSingularBox(word string) bool {
+	for _, w := range withoutPrefixes(word) {
+		switch w {
+		case "ארגז", "קרטון", "box", "case", "carton":
+			return true
+		}
+	}
+	return false
+}
+
+// resolveProduct finds the product for the leftover words.
+// Only the best-scoring products are candidates; more than one
+// equally good product is an ambiguity, never a guess.
+func (p *Parser) resolveProduct(query []string, res *Result) {
+	if len(query) == 0 {
+		res.addAmbiguity(FieldProduct, "which product?")
+		return
+	}
+
+	phrase := strings.Join(query, " ")
+	var candidates []*models.Product
+	matches := p.products.SearchProductsRanked(phrase)
+	for _, m := range matches {
+		// Results are sorted best first - stop at the first worse one
+		if m.Score < matches[0].Score {
+			break
+		}
+		candidates = append(candidates, m.Product)
+	}
+
+	switch len(candidates) {
+	case 0:
+		res.addAmbiguity(FieldProduct, fmt.Sprintf("no product matches %q", phrase))
+	case 1:
+		res.Product = candidates[0]
+		res.Draft.ProductID = candidates[0].ID
+	default:
+		res.Candidates = candidates
+		options := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			options = append(options, c.ID)
+		}
+		res.addAmbiguity(FieldProduct, fmt.Sprintf("%d products match %q", len(candidates), phrase), options...)
+	}
+}
@@ -0,0 +1,95 @@
+package nlp
+
+import (
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// newTestStore returns a store with the demo products from cmd/server
+func newTestStore(t *testing.T) *repository.MemoryStore {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	products := []*models.Product{
+		{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"},
+		{Name: "פנטה 330 מ״ל פחית", Brand: "Fanta", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"},
+		{Name: "פלפל אדום", Brand: "ירקות טריים", Size: 1000, ContainerType: "kg", Price: 15, Category: "vegetables"},
+		{Name: "פלפל ירוק", Brand: "חוות הגליל", Size: 1000, ContainerType: "kg", Price: 12, Category: "vegetables"},
+		{Name: "חומוס 400 גרם", Brand: "עשי", Size: 400, ContainerType: "can", BoxSize: 12, Price: 8, Category: "canned"},
+	}
+	for _, p := range products {
+		if _, err := store.AddProduct(p); err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+	}
+	return store
+}
+
+func TestParse(t *testing.T) {
+	parser := NewParser(newTestStore(t))
+
+	tests := []struct {
+		text        string
+		wantType    string
+		wantBoxes   int
+		wantUnits   int
+		wantBy      string
+		wantProduct string
+		wantAmbig   []string
+	}{
+		{"יוסף לקח 2 ארגזים קולה", models.MovementOut, 2, 0, "יוסף", "PROD-001", nil},
+		{"received 5 boxes coca cola", models.MovementIn, 5, 0, "Dani", "PROD-001", nil},
+		{"זרקתי שלוש פחיות פנטה", models.MovementWaste, 0, 3, "Dani", "PROD-002", nil},
+		{"Yosef took a box of fanta", models.MovementOut, 1, 0, "Yosef", "PROD-002", nil},
+		{"הגיע ארגז וחמש יחידות קולה 330 מ״ל", models.MovementIn, 1, 5, "Dani", "PROD-001", nil},
+		{"פלפל נזרק 2", models.MovementWaste, 0, 2, "Dani", "", []string{FieldProduct}},
+		{"זרקו 2 פלפל אדום על ידי משה", models.MovementWaste, 0, 2, "משה", "PROD-003", nil},
+		{"לקחתי 3 חומוס", models.MovementOut, 0, 3, "Dani", "PROD-005", []string{FieldQuantity}},
+		{"קולה", "", 0, 0, "Dani", "PROD-001", []string{FieldType, FieldQuantity}},
+		{"took 2 boxes of pizza dough", models.MovementOut, 2, 0, "Dani", "", []string{FieldProduct}},
+	}
+
+	for _, tt := range tests {
+		res := parser.Parse(tt.text, "Dani")
+		d := res.Draft
+		if d.Type != tt.wantType || d.Boxes != tt.wantBoxes || d.Units != tt.wantUnits {
+			t.Errorf("%q: got type=%q boxes=%d units=%d", tt.text, d.Type, d.Boxes, d.Units)
+		}
+		if d.PerformedBy != tt.wantBy || d.ReportedBy != "Dani" {
+			t.Errorf("%q: got performedBy=%q reportedBy=%q", tt.text, d.PerformedBy, d.ReportedBy)
+		}
+		if d.ProductID != tt.wantProduct {
+			t.Errorf("%q: got product %q want %q", tt.text, d.ProductID, tt.wantProduct)
+		}
+		var fields []string
+		for _, a := range res.Ambiguities {
+			fields = append(fields, a.Field)
+		}
+		if len(fields) != len(tt.wantAmbig) {
+			t.Errorf("%q: got ambiguities %v want %v", tt.text, fields, tt.wantAmbig)
+			continue
+		}
+		for i := range fields {
+			if fields[i] != tt.wantAmbig[i] {
+				t.Errorf("%q: got ambiguities %v want %v", tt.text, fields, tt.wantAmbig)
+				break
+			}
+		}
+		if res.IsComplete() {
+			if err := d.Validate(); err != nil {
+				t.Errorf("%q: complete draft is invalid: %v", tt.text, err)
+			}
+		}
+	}
+}
+
+func TestParseAmbiguousProductListsCandidates(t *testing.T) {
+	res := NewParser(newTestStore(t)).Parse("הוציא 4 פלפל", "Dani")
+	if len(res.Candidates) != 2 || res.Draft.ProductID != "" {
+		t.Fatalf("expected 2 pepper candidates and no product, got %+v", res)
+	}
+	if res.Draft.PerformedBy != "Dani" {
+		t.Fatalf("expected self-reported performer, got %q", res.Draft.PerformedBy)
+	}
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testAPIKeyTouch runs against any store; PostgreSQL runs it with -tags integration
+func testAPIKeyTouch(t *testing.T, store Repository) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+	user := &models.User{Username: fmt.Sprintf("keys%d", suffix), PasswordHash: "hash", Role: models.RoleOwner, IsActive: true}
+	if _, err := store.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	k := &models.APIKey{ID: fmt.Sprintf("key%d", suffix), Name: "POS", KeyHash: "h", Scopes: []string{"inventory:view"}, CreatedBy: user.ID}
+	if err := store.CreateAPIKey(k); err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	used := time.Now().UTC().Truncate(time.Second)
+	if err := store.TouchAPIKey(k.ID, used); err != nil {
+		t.Fatalf("TouchAPIKey failed: %v", err)
+	}
+	if got, _ := store.GetAPIKey(k.ID); !got.LastUsedAt.Equal(used) {
+		t.Errorf("LastUsedAt = %v, want %v", got.LastUsedAt, used)
+	}
+
+	// A stale copy (read before the revoke) can't bring the key back
+	stale, _ := store.GetAPIKey(k.ID)
+	revoked := *stale
+	revoked.RevokedAt = used.Add(time.Second)
+	if err := store.UpdateAPIKey(&revoked); err != nil {
+		t.Fatalf("UpdateAPIKey failed: %v", err)
+	}
+	if err := store.TouchAPIKey(stale.ID, used.Add(time.Minute)); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("touch revoked key: got %v, want ErrAPIKeyNotFound", err)
+	}
+	got, _ := store.GetAPIKey(k.ID)
+	if got.IsActive() || !got.LastUsedAt.Equal(used) {
+		t.Errorf("after touching a revoked key: %+v", got)
+	}
+
+	if err := store.TouchAPIKey("missing", used); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("touch missing key: got %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestMemoryStoreAPIKeyTouch(t *testing.T) {
+	testAPIKeyTouch(t, NewMemoryStore())
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testArchive runs against any store; PostgreSQL runs it with -tags integration
+func testArchive(t *testing.T, store Repository) {
+	t.Helper()
+	brand := fmt.Sprintf("archive-%d", time.Now().UnixNano())
+	size := 330 // Brand + size + container is unique in PostgreSQL
+	add := func(name string) string {
+		size++
+		id, err := store.AddProduct(&models.Product{Name: name, Brand: brand, Size: size, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+		if err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+		return id
+	}
+	archived := func() []string {
+		page, err := store.QueryProducts(ProductQuery{Brand: brand, Status: ProductStatusInactive})
+		if err != nil {
+			t.Fatalf("QueryProducts failed: %v", err)
+		}
+		var names []string
+		for _, p := range page.Products {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	// Archive and restore
+	cola := add("Cola")
+	if err := store.PurgeProduct(cola, 0); !errors.Is(err, ErrProductActive) {
+		t.Errorf("purge active product: got %v, want ErrProductActive", err)
+	}
+	if err := store.DeleteProduct(cola, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if got := fmt.Sprint(archived()); got != "[Cola]" {
+		t.Errorf("archived products: got %s, want [Cola]", got)
+	}
+	if err := store.RestoreProduct(cola, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("restore with stale version: got %v, want ErrVersionConflict", err)
+	}
+	if err := store.WithActor("dana").RestoreProduct(cola, 2); err != nil {
+		t.Fatalf("RestoreProduct failed: %v", err)
+	}
+	if p, _ := store.GetProduct(cola); !p.IsActive || p.Version != 3 {
+		t.Errorf("restored product: active %v version %d, want true 3", p.IsActive, p.Version)
+	}
+	if got := archived(); len(got) != 0 {
+		t.Errorf("archived products after restore: got %v, want none", got)
+	}
+	entries := store.ListAudit(AuditFilter{EntityID: cola, Limit: 1})
+	if len(entries) != 1 || entries[0].Action != models.AuditRestore || entries[0].Actor != "dana" {
+		t.Errorf("restore audit entry: got %+v", entries)
+	}
+
+	// Purge is refused while there is stock or history
+	if err := store.DeleteProduct(cola, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if err := store.UpdateStock(cola, 1, 0); err != nil {
+		t.Fatalf("UpdateStock failed: %v", err)
+	}
+	if err := store.PurgeProduct(cola, 0); !errors.Is(err, ErrProductInUse) {
+		t.Errorf("purge product with stock: got %v, want ErrProductInUse", err)
+	}
+	fanta := add("Fanta")
+	m, _ := models.NewStockMovement(fanta, models.MovementIn, 1, 0, "יוסף", "", "delivery")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	m, _ = models.NewStockMovement(fanta, models.MovementOut, 1, 0, "יוסף", "", "")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	if err := store.DeleteProduct(fanta, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if err := store.PurgeProduct(fanta, 0); !errors.Is(err, ErrProductInUse) {
+		t.Errorf("purge product with movements: got %v, want ErrProductInUse", err)
+	}
+
+	// Archived products take no movements: their stock stays as it was
+	m, _ = models.NewStockMovement(cola, models.MovementOut, 1, 0, "יוסף", "", "")
+	if _, err := store.RecordMovement(m); !errors.Is(err, ErrProductInactive) {
+		t.Errorf("movement of archived product: got %v, want ErrProductInactive", err)
+	}
+	if st, _ := store.GetStock(cola); st.QuantityBoxes != 1 {
+		t.Errorf("stock of archived product changed: %+v", st)
+	}
+
+	// An archived product nobody used goes for good, with its codes
+	sprite := add("Sprite")
+	if _, err := store.AddAlias(sprite, "ספרייט"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if _, err := store.AddBarcode(sprite, "4006381333931", models.PackUnit); err != nil {
+		t.Fatalf("AddBarcode failed: %v", err)
+	}
+	if err := store.DeleteProduct(sprite, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if err := store.WithActor("dana").PurgeProduct(sprite, 2); err != nil {
+		t.Fatalf("PurgeProduct failed: %v", err)
+	}
+	if _, err := store.GetProduct(sprite); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("purged product: got %v, want ErrProductNotFound", err)
+	}
+	if _, err := store.GetStock(sprite); !errors.Is(err, ErrStockNotFound) {
+		t.Errorf("stock of purged product: got %v, want ErrStockNotFound", err)
+	}
+	if _, _, err := store.GetProductByBarcode("4006381333931"); err == nil {
+		t.Error("barcode of purged product still resolves")
+	}
+	if err := store.PurgeProduct(sprite, 0); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("purge twice: got %v, want ErrProductNotFound", err)
+	}
+	entries = store.ListAudit(AuditFilter{EntityID: sprite, Limit: 1})
+	if len(entries) != 1 || entries[0].Action != models.AuditPurge || entries[0].Changes[0].After != nil {
+		t.Errorf("purge audit entry: got %+v", entries)
+	}
+	if got := fmt.Sprint(archived()); got != "[Cola Fanta]" {
+		t.Errorf("archived products: got %s, want [Cola Fanta]", got)
+	}
+}
+
+func TestMemoryStoreArchive(t *testing.T) {
+	testArchive(t, NewMemoryStore())
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// AUDIT: who changed products and settings
+// ============================================
+// Both stores write an audit entry with every AddProduct, UpdateProduct,
+// DeleteProduct, RestoreProduct, PurgeProduct, MergeProducts and
+// SetMinStock (in the same transaction in PostgreSQL).
+// The store methods don't know who is calling, so callers that do - an
+// HTTP request, an invoice approval - use WithActor(username):
+//
+//	api.Store.WithActor("dana").UpdateProduct(p)
+
+// AuditFilter narrows ListAudit; empty fields match everything
+type AuditFilter struct {
+	Entity   string // "product"
+	EntityID string // "PROD-001"
+	Actor    string // Username
+	Limit    int    // 0 = no limit
+}
+
+// matches reports whether an entry passes the filter
+func (f AuditFilter) matches(e *models.AuditEntry) bool {
+	return (f.Entity == "" || e.Entity == f.Entity) &&
+		(f.EntityID == "" || e.EntityID == f.EntityID) &&
+		(f.Actor == "" || e.Actor == f.Actor)
+}
+
+// auditedWriter is implemented by both stores: the audited writes,
+// taking the actor to record
+type auditedWriter interface {
+	addProduct(p *models.Product, actor string) (string, error)
+	updateProduct(p *models.Product, actor string) error
+	deleteProduct(id string, version int, actor string) error
+	restoreProduct(id string, version int, actor string) error
+	purgeProduct(id string, version int, actor string) error
+	mergeProducts(fromID, intoID, actor string) (*MergeResult, error)
+	setMinStock(productID string, minStock, version int, actor string) error
+}
+
+// actorStore is a store that records its audited writes in the name of actor
+type actorStore struct {
+	Repository
+	writer auditedWriter
+	actor  string
+}
+
+func (s *actorStore) AddProduct(p *models.Product) (string, error) {
+	return s.writer.addProduct(p, s.actor)
+}
+
+func (s *actorStore) UpdateProduct(p *models.Product) error {
+	return s.writer.updateProduct(p, s.actor)
+}
+
+func (s *actorStore) DeleteProduct(id string, version int) error {
+	return s.writer.deleteProduct(id, version, s.actor)
+}
+
+func (s *actorStore) RestoreProduct(id string, version int) error {
+	return s.writer.restoreProduct(id, version, s.actor)
+}
+
+func (s *actorStore) PurgeProduct(id string, version int) error {
+	return s.writer.purgeProduct(id, version, s.actor)
+}
+
+func (s *actorStore) MergeProducts(fromID, intoID string) (*MergeResult, error) {
+	return s.writer.mergeProducts(fromID, intoID, s.actor)
+}
+
+func (s *actorStore) SetMinStock(productID string, minStock, version int) error {
+	return s.writer.setMinStock(productID, minStock, version, s.actor)
+}
+
+// purgeChanges lists every non-empty field of a purged product as
+// changed from its value to nothing
+func purgeChanges(p *models.Product) []models.FieldChange {
+	changes := models.DiffFields(nil, p)
+	for i := range changes {
+		changes[i].Before, changes[i].After = changes[i].After, nil
+	}
+	return changes
+}
+
+// newAuditEntry builds a product entry, or nil when nothing changed
+func newAuditEntry(action, entityID, actor string, changes []models.FieldChange) *models.AuditEntry {
+	if len(changes) == 0 {
+		return nil
+	}
+	return &models.AuditEntry{
+		Entity:    models.AuditEntityProduct,
+		EntityID:  entityID,
+		Action:    action,
+		Actor:     actor,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}
+}
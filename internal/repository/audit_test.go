@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testAuditTrail runs against any store; PostgreSQL runs it with -tags integration
+func testAuditTrail(t *testing.T, store Repository) {
+	t.Helper()
+	brand := fmt.Sprintf("audit-%d", time.Now().UnixNano())
+	dana := store.WithActor("dana")
+
+	id, err := dana.AddProduct(&models.Product{Name: "קמח", Brand: brand, Size: 1000, ContainerType: "bag", Price: 5, Category: "dry_goods", IsActive: true})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	p, err := store.GetProduct(id)
+	if err != nil {
+		t.Fatalf("GetProduct failed: %v", err)
+	}
+	updated := *p
+	updated.Price = 6.5
+	if err := store.WithActor("yosef").UpdateProduct(&updated); err != nil {
+		t.Fatalf("UpdateProduct failed: %v", err)
+	}
+	if err := dana.UpdateProduct(&updated); err != nil { // No change: no entry
+		t.Fatalf("UpdateProduct failed: %v", err)
+	}
+	if err := dana.SetMinStock(id, 10, 0); err != nil {
+		t.Fatalf("SetMinStock failed: %v", err)
+	}
+	if err := store.DeleteProduct(id, 0); err != nil { // No actor
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+
+	entries := store.ListAudit(AuditFilter{Entity: models.AuditEntityProduct, EntityID: id})
+	want := []struct{ action, actor, field string }{
+		{models.AuditDelete, "", "IsActive"},
+		{models.AuditMinStock, "dana", "MinStock"},
+		{models.AuditUpdate, "yosef", "Price"},
+		{models.AuditCreate, "dana", "Name"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d audit entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Action != w.action || e.Actor != w.actor || len(e.Changes) == 0 || e.Changes[0].Field != w.field {
+			t.Errorf("entry %d: got %s by %q %+v, want %s by %q on %s", i, e.Action, e.Actor, e.Changes, w.action, w.actor, w.field)
+		}
+	}
+	if price := entries[2].Changes; len(price) != 1 || fmt.Sprint(price[0].Before) != "5" || fmt.Sprint(price[0].After) != "6.5" {
+		t.Errorf("price diff: got %+v, want 5 → 6.5", price)
+	}
+
+	if got := store.ListAudit(AuditFilter{EntityID: id, Actor: "dana", Limit: 1}); len(got) != 1 || got[0].Action != models.AuditMinStock {
+		t.Errorf("filter by actor with limit: got %+v", got)
+	}
+}
+
+func TestMemoryStoreAudit(t *testing.T) {
+	testAuditTrail(t, NewMemoryStore())
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// BACKUP: the whole store at one moment
+// ============================================
+// Snapshot reads every table as of one moment (one transaction in
+// PostgreSQL, one lock in memory); Restore replaces everything in the store
+// with a snapshot, keeping IDs, versions and times. A snapshot taken from
+// PostgreSQL restores into a MemoryStore and the other way around. The
+// file format lives in internal/backup.
+//
+// Login sessions and Idempotency-Key records aren't kept: they are only
+// good for hours. Restore drops them, so everyone logs in again.
+//
+// Backups from before categories were data have no Categories; Restore
+// gives them the default categories, plus one named after its ID for any
+// other category a product is in.
+
+// ErrInvalidSnapshot is returned by Restore for a snapshot that doesn't
+// hold together (a stock row of a product that isn't there, ...)
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Snapshot is every table of the store
+type Snapshot struct {
+	Products     []*models.Product
+	Stock        []*models.Stock
+	Movements    []*models.StockMovement // Oldest first
+	Aliases      []*models.ProductAlias
+	Barcodes     []*models.ProductBarcode
+	SupplierSKUs []*models.SupplierSKU
+	Users        []*models.User
+	Staff        []*models.Staff
+	Categories   []*models.Category
+	APIKeys      []*models.APIKey
+	Audit        []*models.AuditEntry // Oldest first
+}
+
+// Counts returns the number of rows per table
+func (snap *Snapshot) Counts() map[string]int {
+	return map[string]int{
+		"products":     len(snap.Products),
+		"stock":        len(snap.Stock),
+		"movements":    len(snap.Movements),
+		"aliases":      len(snap.Aliases),
+		"barcodes":     len(snap.Barcodes),
+		"supplierSkus": len(snap.SupplierSKUs),
+		"users":        len(snap.Users),
+		"staff":        len(snap.Staff),
+		"categories":   len(snap.Categories),
+		"apiKeys":      len(snap.APIKeys),
+		"audit":        len(snap.Audit),
+	}
+}
+
+// Validate checks that IDs are unique and that every reference points to
+// a row of the snapshot, the way the foreign keys of PostgreSQL would
+func (snap *Snapshot) Validate() error {
+	categories := map[string]*models.Category{}
+	for _, c := range snap.Categories {
+		if c.ID == "" || categories[c.ID] != nil {
+			return fmt.Errorf("%w: category ID %q is empty or repeated", ErrInvalidSnapshot, c.ID)
+		}
+		categories[c.ID] = c
+	}
+	for _, c := range snap.Categories {
+		if err := checkCategoryParent(c, func(id string) *models.Category { return categories[id] }); err != nil {
+			return fmt.Errorf("%w: category %s: %v", ErrInvalidSnapshot, c.ID, err)
+		}
+	}
+	products := map[string]bool{}
+	for _, p := range snap.Products {
+		if p.ID == "" || products[p.ID] {
+			return fmt.Errorf("%w: product ID %q is empty or repeated", ErrInvalidSnapshot, p.ID)
+		}
+		if p.Category != "" && categories[p.Category] == nil {
+			return fmt.Errorf("%w: product %s is in unknown category %s", ErrInvalidSnapshot, p.ID, p.Category)
+		}
+		products[p.ID] = true
+	}
+	users := map[string]bool{}
+	for _, u := range snap.Users {
+		if u.ID == "" || users[u.ID] {
+			return fmt.Errorf("%w: user ID %q is empty or repeated", ErrInvalidSnapshot, u.ID)
+		}
+		users[u.ID] = true
+	}
+	staff := map[string]bool{}
+	for _, st := range snap.Staff {
+		if st.ID == "" || staff[st.ID] {
+			return fmt.Errorf("%w: staff ID %q is empty or repeated", ErrInvalidSnapshot, st.ID)
+		}
+		if st.UserID != "" && !users[st.UserID] {
+			return fmt.Errorf("%w: staff %s links to unknown user %s", ErrInvalidSnapshot, st.ID, st.UserID)
+		}
+		staff[st.ID] = true
+	}
+
+	for _, st := range snap.Stock {
+		if !products[st.ProductID] {
+			return fmt.Errorf("%w: stock of unknown product %s", ErrInvalidSnapshot, st.ProductID)
+		}
+	}
+	movements := map[string]bool{}
+	for _, m := range snap.Movements {
+		if m.ID == "" || movements[m.ID] {
+			return fmt.Errorf("%w: movement ID %q is empty or repeated", ErrInvalidSnapshot, m.ID)
+		}
+		movements[m.ID] = true
+		if !products[m.ProductID] {
+			return fmt.Errorf("%w: movement %s of unknown product %s", ErrInvalidSnapshot, m.ID, m.ProductID)
+		}
+		for _, id := range []string{m.PerformedByID, m.ReportedByID} {
+			if id != "" && !staff[id] {
+				return fmt.Errorf("%w: movement %s names unknown staff %s", ErrInvalidSnapshot, m.ID, id)
+			}
+		}
+	}
+	for _, a := range snap.Aliases {
+		if !products[a.ProductID] {
+			return fmt.Errorf("%w: alias %s of unknown product %s", ErrInvalidSnapshot, a.ID, a.ProductID)
+		}
+	}
+	for _, b := range snap.Barcodes {
+		if !products[b.ProductID] {
+			return fmt.Errorf("%w: barcode %s of unknown product %s", ErrInvalidSnapshot, b.Code, b.ProductID)
+		}
+	}
+	for _, m := range snap.SupplierSKUs {
+		if !products[m.ProductID] {
+			return fmt.Errorf("%w: supplier SKU %s %s of unknown product %s", ErrInvalidSnapshot, m.Supplier, m.SKU, m.ProductID)
+		}
+	}
+	for _, k := range snap.APIKeys {
+		if k.CreatedBy != "" && !users[k.CreatedBy] {
+			return fmt.Errorf("%w: API key %s created by unknown user %s", ErrInvalidSnapshot, k.ID, k.CreatedBy)
+		}
+	}
+	return nil
+}
+
+// withCategories returns snap, or for a backup without categories a copy
+// with the default categories and one for every other category a product
+// is in
+func (snap *Snapshot) withCategories() *Snapshot {
+	if snap.Categories != nil {
+		return snap
+	}
+	filled := *snap
+	known := map[string]bool{}
+	now := time.Now()
+	for _, c := range models.DefaultCategories() {
+		c.CreatedAt, c.UpdatedAt = now, now
+		filled.Categories = append(filled.Categories, c)
+		known[c.ID] = true
+	}
+	for _, p := range snap.Products {
+		if p.Category != "" && !known[p.Category] {
+			filled.Categories = append(filled.Categories, &models.Category{ID: p.Category, NameEn: p.Category, SortOrder: 1000, CreatedAt: now, UpdatedAt: now})
+			known[p.Category] = true
+		}
+	}
+	return &filled
+}
+
+// nextCounter returns the counter that continues after the highest
+// "<prefix>-<number>" ID, so IDs made after a restore don't collide
+func nextCounter(prefix string, ids []string) int {
+	next := 1
+	for _, id := range ids {
+		n, err := strconv.Atoi(strings.TrimPrefix(id, prefix+"-"))
+		if err == nil && strings.HasPrefix(id, prefix+"-") && n >= next {
+			next = n + 1
+		}
+	}
+	return next
+}
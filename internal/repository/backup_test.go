@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testSnapshotRestore runs against any store; PostgreSQL runs it with -tags integration
+func testSnapshotRestore(t *testing.T, store Repository) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+
+	user := &models.User{Username: fmt.Sprintf("backup%d", suffix), PasswordHash: "hash", Role: models.RoleOwner, IsActive: true}
+	if _, err := store.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	staffID, err := store.AddStaff(&models.Staff{NameHe: "יוסף", UserID: user.ID, IsActive: true})
+	if err != nil {
+		t.Fatalf("AddStaff failed: %v", err)
+	}
+	p := &models.Product{Name: "Cola", Brand: fmt.Sprintf("Backup%d", suffix), Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"}
+	productID, err := store.WithActor("dana").AddProduct(p)
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	if _, err := store.AddAlias(productID, "קולה"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if _, err := store.AddBarcode(productID, "5449000000996", models.PackUnit); err != nil {
+		t.Fatalf("AddBarcode failed: %v", err)
+	}
+	m, _ := models.NewStockMovement(productID, models.MovementIn, 2, 0, "יוסף", "", "delivery")
+	m.PerformedByID, m.ClientID = staffID, fmt.Sprintf("client-%d", suffix)
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	if err := store.CreateAPIKey(&models.APIKey{ID: fmt.Sprintf("key%d", suffix), Name: "POS", KeyHash: "h", Scopes: []string{"inventory:view"}, CreatedBy: user.ID}); err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if err := store.CreateSession(&models.Session{ID: fmt.Sprintf("sess%d", suffix), UserID: user.ID, RefreshHash: "r", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	snap, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := snap.Validate(); err != nil {
+		t.Fatalf("snapshot of a working store is invalid: %v", err)
+	}
+
+	// Into an empty memory store: same tables, sessions left behind
+	restored := NewMemoryStore()
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("Restore into memory failed: %v", err)
+	}
+	again, _ := restored.Snapshot()
+	if !reflect.DeepEqual(snap, again) {
+		t.Errorf("restored store differs:\n got  %+v\n want %+v", again, snap)
+	}
+	if st, err := restored.GetStock(productID); err != nil || st.QuantityBoxes != 2 {
+		t.Errorf("restored stock: got %+v, %v; want 2 boxes", st, err)
+	}
+	if mv, err := restored.GetMovementByClientID(m.ClientID); err != nil || mv.PerformedByID != staffID {
+		t.Errorf("restored movement: got %+v, %v", mv, err)
+	}
+	if _, err := restored.GetSession(fmt.Sprintf("sess%d", suffix)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("sessions must not be restored, got %v", err)
+	}
+	newID, err := restored.AddProduct(&models.Product{Name: "Fanta", Brand: "Fanta", Size: 330, ContainerType: "can", Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct after restore failed: %v", err)
+	}
+	for _, old := range snap.Products {
+		if old.ID == newID {
+			t.Errorf("new product got the ID %s of a restored one", newID)
+		}
+	}
+
+	// Back into the original store, after a change that the restore undoes
+	if err := store.DeleteProduct(productID, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if err := store.Restore(snap); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got, err := store.GetProduct(productID); err != nil || !got.IsActive || got.Version != 1 {
+		t.Errorf("product after restore: got %+v, %v; want active version 1", got, err)
+	}
+	if _, err := store.GetSession(fmt.Sprintf("sess%d", suffix)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("restore must log everyone out, got %v", err)
+	}
+	if counts, _ := store.Snapshot(); !reflect.DeepEqual(counts.Counts(), snap.Counts()) {
+		t.Errorf("row counts after restore: got %v, want %v", counts.Counts(), snap.Counts())
+	}
+
+	// A broken snapshot changes nothing
+	broken := *snap
+	broken.Stock = append(broken.Stock, &models.Stock{ProductID: "NOPE"})
+	if err := store.Restore(&broken); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot, got %v", err)
+	}
+	if _, err := store.GetProduct(productID); err != nil {
+		t.Errorf("failed restore changed the store: %v", err)
+	}
+}
+
+func TestMemoryStoreSnapshotRestore(t *testing.T) {
+	testSnapshotRestore(t, NewMemoryStore())
+}
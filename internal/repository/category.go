@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// CATEGORIES: product groups kept as data
+// ============================================
+// Categories used to be a map compiled into the program; now they are rows
+// the owner edits (POST /categories). A store starts with
+// models.DefaultCategories. Products keep the category ID, and
+// Product.Validate asks the store (HasCategory) whether it exists.
+// Categories can nest one under another (ParentID); a category with
+// products or subcategories can't be deleted.
+
+// checkCategoryParent checks that c's parent exists and that following the
+// parents from c never comes back to c. get returns nil for an unknown ID.
+func checkCategoryParent(c *models.Category, get func(id string) *models.Category) error {
+	seen := map[string]bool{c.ID: true}
+	for parentID := c.ParentID; parentID != ""; {
+		parent := get(parentID)
+		if parent == nil {
+			return fmt.Errorf("%w: %s does not exist", ErrCategoryParent, parentID)
+		}
+		if seen[parent.ID] {
+			return fmt.Errorf("%w: %s would be its own ancestor", ErrCategoryParent, c.ID)
+		}
+		seen[parent.ID] = true
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// sortCategories orders categories by SortOrder, then ID
+func sortCategories(categories []*models.Category) {
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].SortOrder != categories[j].SortOrder {
+			return categories[i].SortOrder < categories[j].SortOrder
+		}
+		return categories[i].ID < categories[j].ID
+	})
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testCategories runs against any store; PostgreSQL runs it with -tags integration
+func testCategories(t *testing.T, store Repository) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+	bar := fmt.Sprintf("bar_%d", suffix)
+	beer := fmt.Sprintf("beer_%d", suffix)
+
+	if !store.HasCategory("drinks") {
+		t.Fatal("default category drinks is missing")
+	}
+	if err := store.AddCategory(&models.Category{ID: "Not Valid", NameEn: "x"}); !errors.Is(err, models.ErrCategoryInvalidID) {
+		t.Errorf("add invalid ID: got %v, want ErrCategoryInvalidID", err)
+	}
+	if err := store.AddCategory(&models.Category{ID: beer, NameHe: "בירה", ParentID: "nope"}); !errors.Is(err, ErrCategoryParent) {
+		t.Errorf("add with unknown parent: got %v, want ErrCategoryParent", err)
+	}
+	if err := store.AddCategory(&models.Category{ID: bar, NameHe: "בר", NameEn: "Bar", SortOrder: -1}); err != nil {
+		t.Fatalf("AddCategory failed: %v", err)
+	}
+	if err := store.AddCategory(&models.Category{ID: beer, NameHe: " בירה ", ParentID: bar, DefaultMinStock: 48}); err != nil {
+		t.Fatalf("AddCategory failed: %v", err)
+	}
+	if err := store.AddCategory(&models.Category{ID: bar, NameEn: "Bar"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("add twice: got %v, want ErrCategoryExists", err)
+	}
+	got, err := store.GetCategory(beer)
+	if err != nil || got.NameHe != "בירה" || got.ParentID != bar || got.DefaultMinStock != 48 {
+		t.Errorf("GetCategory: got %+v %v", got, err)
+	}
+	if list := store.ListCategories(); len(list) < 2 || list[0].ID != bar {
+		t.Errorf("ListCategories should start with %s (lowest sort order), got %+v", bar, list)
+	}
+
+	// No loops: bar can't go under its own child
+	if err := store.UpdateCategory(&models.Category{ID: bar, NameEn: "Bar", ParentID: beer}); !errors.Is(err, ErrCategoryParent) {
+		t.Errorf("parent loop: got %v, want ErrCategoryParent", err)
+	}
+	if err := store.UpdateCategory(&models.Category{ID: bar, NameEn: "Bar & drinks", SortOrder: -1}); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if err := store.UpdateCategory(&models.Category{ID: "nope", NameEn: "x"}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("update unknown: got %v, want ErrCategoryNotFound", err)
+	}
+
+	// Products must be in a known category and get its min stock
+	p := &models.Product{Name: "Goldstar", Brand: fmt.Sprintf("cat-%d", suffix), Size: 330, ContainerType: "bottle", BoxSize: 24, Price: 8, Category: "nope"}
+	if _, err := store.AddProduct(p); !errors.Is(err, models.ErrProductInvalidCategory) {
+		t.Errorf("product in unknown category: got %v, want ErrProductInvalidCategory", err)
+	}
+	p.Category = beer
+	id, err := store.AddProduct(p)
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	if st, err := store.GetStock(id); err != nil || st.MinStock != 48 {
+		t.Errorf("min stock of new product: got %+v %v, want 48", st, err)
+	}
+
+	// In use: by a product, by a subcategory
+	if err := store.DeleteCategory(beer); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("delete category with products: got %v, want ErrCategoryInUse", err)
+	}
+	if err := store.DeleteCategory(bar); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("delete category with subcategory: got %v, want ErrCategoryInUse", err)
+	}
+	p.ID, p.Category, p.Version = id, "drinks", 0
+	if err := store.UpdateProduct(p); err != nil {
+		t.Fatalf("UpdateProduct failed: %v", err)
+	}
+	for _, c := range []string{beer, bar} {
+		if err := store.DeleteCategory(c); err != nil {
+			t.Fatalf("DeleteCategory(%s) failed: %v", c, err)
+		}
+	}
+	if store.HasCategory(bar) {
+		t.Errorf("%s still exists after delete", bar)
+	}
+	if err := store.DeleteCategory(bar); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("delete twice: got %v, want ErrCategoryNotFound", err)
+	}
+}
+
+func TestMemoryStoreCategories(t *testing.T) {
+	testCategories(t, NewMemoryStore())
+}
+
+func TestRestoreBackupWithoutCategories(t *testing.T) {
+	// A backup from before categories were data: defaults, plus the
+	// categories its products are in
+	snap := &Snapshot{Products: []*models.Product{
+		{ID: "PROD-001", Name: "Cola", Size: 330, Category: "drinks", IsActive: true, Version: 1},
+		{ID: "PROD-002", Name: "Napkins", Size: 100, Category: "cleaning", IsActive: true, Version: 1},
+	}}
+	store := NewMemoryStore()
+	if err := store.Restore(snap); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for _, id := range []string{"drinks", "packaging", "cleaning"} {
+		if !store.HasCategory(id) {
+			t.Errorf("category %s missing after restore", id)
+		}
+	}
+
+	// A backup with categories must name every category its products are in
+	snap.Categories = []*models.Category{{ID: "drinks", NameEn: "Drinks"}}
+	if err := store.Restore(snap); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("restore with unknown product category: got %v, want ErrInvalidSnapshot", err)
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testIdempotencyKeys runs against any store; PostgreSQL runs it with -tags integration
+func testIdempotencyKeys(t *testing.T, store Repository) {
+	t.Helper()
+	now := time.Now().Truncate(time.Millisecond)
+	scope := fmt.Sprintf("USR-%d", now.UnixNano())
+	newRecord := func(key string, at time.Time) *models.IdempotencyRecord {
+		return &models.IdempotencyRecord{Scope: scope, Key: key, RequestHash: "hash-1", CreatedAt: at, ExpiresAt: at.Add(time.Hour)}
+	}
+
+	first := newRecord("k1", now)
+	if existing, err := store.ReserveIdempotencyKey(first); err != nil || existing != nil {
+		t.Fatalf("first reserve: expected to own the key, got %+v, %v", existing, err)
+	}
+	existing, err := store.ReserveIdempotencyKey(newRecord("k1", now.Add(time.Second)))
+	if err != nil || existing == nil || existing.IsDone() {
+		t.Fatalf("retry while running: expected the running record, got %+v, %v", existing, err)
+	}
+	if existing, _ := store.ReserveIdempotencyKey(&models.IdempotencyRecord{Scope: scope + "-other", Key: "k1", RequestHash: "hash-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); existing != nil {
+		t.Errorf("keys must be per scope, got %+v", existing)
+	}
+
+	first.Status, first.Body = 201, []byte(`{"id":"MOV-001"}`)
+	first.Headers = map[string]string{"Content-Type": "application/json"}
+	if err := store.SaveIdempotentResponse(first); err != nil {
+		t.Fatalf("SaveIdempotentResponse failed: %v", err)
+	}
+	existing, err = store.ReserveIdempotencyKey(newRecord("k1", now.Add(time.Second)))
+	if err != nil || existing == nil {
+		t.Fatalf("retry after save: expected the stored record, got %+v, %v", existing, err)
+	}
+	if existing.Status != 201 || string(existing.Body) != `{"id":"MOV-001"}` || existing.Headers["Content-Type"] != "application/json" {
+		t.Errorf("stored response not returned: %+v", existing)
+	}
+
+	// Expired: the key can be used again
+	if existing, err := store.ReserveIdempotencyKey(newRecord("k1", now.Add(2*time.Hour))); err != nil || existing != nil {
+		t.Errorf("reserve after expiry: expected to own the key, got %+v, %v", existing, err)
+	}
+
+	// Released (the request failed): the key can be retried
+	if _, err := store.ReserveIdempotencyKey(newRecord("k2", now)); err != nil {
+		t.Fatalf("reserve k2 failed: %v", err)
+	}
+	if err := store.ReleaseIdempotencyKey(scope, "k2"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey failed: %v", err)
+	}
+	if existing, _ := store.ReserveIdempotencyKey(newRecord("k2", now)); existing != nil {
+		t.Errorf("reserve after release: expected to own the key, got %+v", existing)
+	}
+
+	if n := store.PurgeIdempotencyKeys(now.Add(90 * time.Minute)); n < 1 {
+		t.Errorf("PurgeIdempotencyKeys removed %d records, want at least 1", n)
+	}
+	if existing, _ := store.ReserveIdempotencyKey(newRecord("k2", now.Add(90*time.Minute))); existing != nil {
+		t.Errorf("purged key still held: %+v", existing)
+	}
+}
+
+func TestMemoryStoreIdempotencyKeys(t *testing.T) {
+	testIdempotencyKeys(t, NewMemoryStore())
+}
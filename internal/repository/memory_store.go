@@ -3,6 +3,7 @@ package repository
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,10 +16,35 @@ import (
 // ============================================
 
 var (
-	ErrProductNotFound   = fmt.Errorf("product not found")
-	ErrProductExists     = fmt.Errorf("product already exists")
-	ErrStockNotFound     = fmt.Errorf("stock not found")
-	ErrInsufficientStock = fmt.Errorf("insufficient stock")
+	ErrProductNotFound        = fmt.Errorf("product not found")
+	ErrProductExists          = fmt.Errorf("product already exists")
+	ErrStockNotFound          = fmt.Errorf("stock not found")
+	ErrInsufficientStock      = fmt.Errorf("insufficient stock")
+	ErrVersionConflict        = fmt.Errorf("changed by someone else since you loaded it")
+	ErrAliasNotFound          = fmt.Errorf("alias not found")
+	ErrAliasExists            = fmt.Errorf("alias already exists")
+	ErrBarcodeNotFound        = fmt.Errorf("barcode not found")
+	ErrBarcodeExists          = fmt.Errorf("barcode already exists")
+	ErrSupplierSKUNotFound    = fmt.Errorf("supplier SKU not found")
+	ErrUserNotFound           = fmt.Errorf("user not found")
+	ErrUserExists             = fmt.Errorf("user already exists")
+	ErrSessionNotFound        = fmt.Errorf("session not found")
+	ErrAPIKeyNotFound         = fmt.Errorf("API key not found")
+	ErrIdempotencyKeyNotFound = fmt.Errorf("idempotency key not reserved")
+	ErrStaffNotFound          = fmt.Errorf("staff member not found")
+	ErrStaffUserLinked        = fmt.Errorf("login account is already linked to another staff member")
+	ErrMovementNotFound       = fmt.Errorf("movement not found")
+	ErrMovementExists         = fmt.Errorf("movement already recorded")
+	ErrInvalidProductQuery    = fmt.Errorf("invalid product query")
+	ErrInvalidCursor          = fmt.Errorf("invalid cursor (start again from the first page)")
+	ErrProductActive          = fmt.Errorf("product is active (archive it first)")
+	ErrProductInactive        = fmt.Errorf("product is not active")
+	ErrProductInUse           = fmt.Errorf("product is still in use")
+	ErrInvalidMerge           = fmt.Errorf("cannot merge these products")
+	ErrCategoryNotFound       = fmt.Errorf("category not found")
+	ErrCategoryExists         = fmt.Errorf("category already exists")
+	ErrCategoryInUse          = fmt.Errorf("category is still in use")
+	ErrCategoryParent         = fmt.Errorf("invalid parent category")
 )
 
 // ============================================
@@ -33,8 +59,44 @@ type MemoryStore struct {
 	products map[string]*models.Product // productID → Product
 	stock    map[string]*models.Stock   // productID → Stock
 
-	// Counter for generating IDs
-	nextID int
+	// Movement log in insertion order (oldest first)
+	movements []*models.StockMovement
+
+	// Aliases per product, oldest first
+	aliases map[string][]*models.ProductAlias // productID → aliases
+
+	// Barcodes by normalized code (a code belongs to one product)
+	barcodes map[string]*models.ProductBarcode // code → barcode
+
+	// Supplier item codes, keyed by supplierSKUKey
+	supplierSKUs map[string]*models.SupplierSKU
+
+	// Users and their login sessions
+	users    map[string]*models.User    // userID → User
+	sessions map[string]*models.Session // sessionID → Session
+
+	// Machine API keys
+	apiKeys map[string]*models.APIKey // keyID → APIKey
+
+	// Staff directory
+	staff map[string]*models.Staff // staffID → Staff
+
+	// Product categories
+	categories map[string]*models.Category // categoryID → Category
+
+	// Audit trail in insertion order (oldest first)
+	audit []*models.AuditEntry
+
+	// First responses per Idempotency-Key, keyed by idempotencyKey
+	idempotency map[string]*models.IdempotencyRecord
+
+	// Counters for generating IDs
+	nextID         int
+	nextMovementID int
+	nextAliasID    int
+	nextUserID     int
+	nextStaffID    int
+	nextAuditID    int
 
 	// Mutex for thread safety (multiple goroutines accessing store)
 	// We'll learn about this more in concurrency lessons
@@ -44,9 +106,23 @@ type MemoryStore struct {
 // NewMemoryStore creates a new empty store
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		products: make(map[string]*models.Product),
-		stock:    make(map[string]*models.Stock),
-		nextID:   1,
+		products:       make(map[string]*models.Product),
+		stock:          make(map[string]*models.Stock),
+		aliases:        make(map[string][]*models.ProductAlias),
+		barcodes:       make(map[string]*models.ProductBarcode),
+		supplierSKUs:   make(map[string]*models.SupplierSKU),
+		users:          make(map[string]*models.User),
+		sessions:       make(map[string]*models.Session),
+		apiKeys:        make(map[string]*models.APIKey),
+		idempotency:    make(map[string]*models.IdempotencyRecord),
+		staff:          make(map[string]*models.Staff),
+		categories:     defaultCategoryMap(),
+		nextID:         1,
+		nextMovementID: 1,
+		nextAliasID:    1,
+		nextUserID:     1,
+		nextStaffID:    1,
+		nextAuditID:    1,
 	}
 }
 
@@ -57,14 +133,26 @@ func NewMemoryStore() *MemoryStore {
 // AddProduct adds a new product to the store
 // Returns the generated ID or error if validation fails
 func (s *MemoryStore) AddProduct(p *models.Product) (string, error) {
+	return s.addProduct(p, "")
+}
+
+func (s *MemoryStore) addProduct(p *models.Product, actor string) (string, error) {
 	// Validate first
-	if err := p.Validate(); err != nil {
+	if err := p.Validate(s); err != nil {
 		return "", fmt.Errorf("validation failed: %w", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Brand + size + container is unique, archived products included; the
+	// PostgreSQL index compares them exactly, so this does too
+	for _, other := range s.products {
+		if other.Brand == p.Brand && other.Size == p.Size && other.ContainerType == p.ContainerType {
+			return "", fmt.Errorf("%w: %s %d %s", ErrProductExists, p.Brand, p.Size, p.ContainerType)
+		}
+	}
+
 	// Generate ID
 	id := fmt.Sprintf("PROD-%03d", s.nextID)
 	s.nextID++
@@ -72,19 +160,27 @@ func (s *MemoryStore) AddProduct(p *models.Product) (string, error) {
 	// Set ID and ensure active
 	p.ID = id
 	p.IsActive = true
+	p.Version = 1
+	p.UpdatedAt = time.Now()
 
 	// Store product
 	s.products[id] = p
 
-	// Initialize stock at zero
+	// Initialize stock at zero, with the category's min stock
+	minStock := 0
+	if c := s.categories[p.Category]; c != nil {
+		minStock = c.DefaultMinStock
+	}
 	s.stock[id] = &models.Stock{
 		ProductID:     id,
 		QuantityBoxes: 0,
 		QuantityUnits: 0,
-		MinStock:      0,
+		MinStock:      minStock,
 		LastUpdated:   time.Now(),
+		Version:       1,
 	}
 
+	s.recordAudit(newAuditEntry(models.AuditCreate, id, actor, models.DiffFields(nil, p)))
 	return id, nil
 }
 
@@ -102,7 +198,7 @@ func (s *MemoryStore) GetProduct(id string) (*models.Product, error) {
 	return product, nil
 }
 
-// ListProducts returns all active products
+// ListProducts returns all active products, by name
 func (s *MemoryStore) ListProducts() []*models.Product {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -116,56 +212,113 @@ func (s *MemoryStore) ListProducts() []*models.Product {
 		}
 	}
 
+	// By name, like PostgreSQL (map order is random)
+	sort.Slice(result, func(i, j int) bool { return compareProducts(result[i], result[j], ProductSortName) < 0 })
 	return result
 }
 
-// SearchProducts finds products where name contains the query
-// Case-insensitive search
+// QueryProducts returns one page of the products matching q
+func (s *MemoryStore) QueryProducts(q ProductQuery) (*ProductPage, error) {
+	cursor, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*models.Product
+	for id, p := range s.products {
+		if q.matches(p, s.stock[id]) {
+			copied := *p
+			matched = append(matched, &copied)
+		}
+	}
+	return pageProducts(matched, q, cursor), nil
+}
+
+// SearchProducts finds active products matching the query
+// Hebrew-aware and typo-tolerant, best match first (see search.go)
 // Returns empty slice if no matches (not error!)
 func (s *MemoryStore) SearchProducts(query string) []*models.Product {
+	return matchedProducts(s.SearchProductsRanked(query))
+}
+
+// SearchProductsRanked finds active products with their relevance score
+func (s *MemoryStore) SearchProductsRanked(query string) []ProductMatch {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	query = strings.ToLower(query)
-	var results []*models.Product
-
+	active := make([]*models.Product, 0, len(s.products))
+	aliases := make(map[string][]string)
 	for _, p := range s.products {
-		if !p.IsActive {
-			continue
+		if p.IsActive {
+			active = append(active, p)
+			for _, a := range s.aliases[p.ID] {
+				aliases[p.ID] = append(aliases[p.ID], a.Alias)
+			}
 		}
+	}
+
+	return rankProducts(query, active, aliases)
+}
 
-		// Search in Name, Brand, or Category
-		nameLower := strings.ToLower(p.Name)
-		brandLower := strings.ToLower(p.Brand)
+// ListProductsChangedSince returns products (deleted ones too) changed at
+// or after since, oldest change first
+func (s *MemoryStore) ListProductsChangedSince(since time.Time) ([]*models.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-		if strings.Contains(nameLower, query) || strings.Contains(brandLower, query) {
-			results = append(results, p)
+	result := make([]*models.Product, 0)
+	for _, p := range s.products {
+		if !p.UpdatedAt.Before(since) {
+			result = append(result, p)
 		}
 	}
-
-	return results
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].UpdatedAt.Equal(result[j].UpdatedAt) {
+			return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
 }
 
 // UpdateProduct updates an existing product
 func (s *MemoryStore) UpdateProduct(p *models.Product) error {
-	if err := p.Validate(); err != nil {
+	return s.updateProduct(p, "")
+}
+
+func (s *MemoryStore) updateProduct(p *models.Product, actor string) error {
+	if err := p.Validate(s); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.products[p.ID]; !exists {
+	before, exists := s.products[p.ID]
+	if !exists {
 		return fmt.Errorf("%w: %s", ErrProductNotFound, p.ID)
 	}
+	if p.Version != 0 && p.Version != before.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, p.ID, before.Version, p.Version)
+	}
 
+	p.Version = before.Version + 1
+	p.UpdatedAt = time.Now()
 	s.products[p.ID] = p
+	s.recordAudit(newAuditEntry(models.AuditUpdate, p.ID, actor, models.DiffFields(before, p)))
 	return nil
 }
 
 // DeleteProduct soft-deletes a product (sets IsActive = false)
 // We don't really delete to preserve history
-func (s *MemoryStore) DeleteProduct(id string) error {
+func (s *MemoryStore) DeleteProduct(id string, version int) error {
+	return s.deleteProduct(id, version, "")
+}
+
+func (s *MemoryStore) deleteProduct(id string, version int, actor string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -173,11 +326,180 @@ func (s *MemoryStore) DeleteProduct(id string) error {
 	if !exists {
 		return fmt.Errorf("%w: %s", ErrProductNotFound, id)
 	}
+	if version != 0 && version != product.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, product.Version, version)
+	}
 
+	before := *product
 	product.IsActive = false
+	product.Version++
+	product.UpdatedAt = time.Now()
+	s.recordAudit(newAuditEntry(models.AuditDelete, id, actor, models.DiffFields(&before, product)))
+	return nil
+}
+
+// RestoreProduct brings a soft-deleted product back (IsActive = true)
+func (s *MemoryStore) RestoreProduct(id string, version int) error {
+	return s.restoreProduct(id, version, "")
+}
+
+func (s *MemoryStore) restoreProduct(id string, version int, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.products[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	}
+	if version != 0 && version != product.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, product.Version, version)
+	}
+	if product.IsActive {
+		return nil
+	}
+
+	before := *product
+	product.IsActive = true
+	product.Version++
+	product.UpdatedAt = time.Now()
+	s.recordAudit(newAuditEntry(models.AuditRestore, id, actor, models.DiffFields(&before, product)))
+	return nil
+}
+
+// PurgeProduct deletes an archived product for good
+func (s *MemoryStore) PurgeProduct(id string, version int) error {
+	return s.purgeProduct(id, version, "")
+}
+
+func (s *MemoryStore) purgeProduct(id string, version int, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.products[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	}
+	if version != 0 && version != product.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, product.Version, version)
+	}
+	if product.IsActive {
+		return fmt.Errorf("%w: %s", ErrProductActive, id)
+	}
+	if st := s.stock[id]; st != nil && (st.QuantityBoxes != 0 || st.QuantityUnits != 0) {
+		return fmt.Errorf("%w: %s has %d boxes and %d units in stock", ErrProductInUse, id, st.QuantityBoxes, st.QuantityUnits)
+	}
+	movements := 0
+	for _, m := range s.movements {
+		if m.ProductID == id {
+			movements++
+		}
+	}
+	if movements > 0 {
+		return fmt.Errorf("%w: %d movements refer to %s", ErrProductInUse, movements, id)
+	}
+
+	delete(s.products, id)
+	delete(s.stock, id)
+	delete(s.aliases, id)
+	for code, b := range s.barcodes {
+		if b.ProductID == id {
+			delete(s.barcodes, code)
+		}
+	}
+	for key, m := range s.supplierSKUs {
+		if m.ProductID == id {
+			delete(s.supplierSKUs, key)
+		}
+	}
+	s.recordAudit(newAuditEntry(models.AuditPurge, id, actor, purgeChanges(product)))
 	return nil
 }
 
+// MergeProducts folds the duplicate fromID into intoID
+func (s *MemoryStore) MergeProducts(fromID, intoID string) (*MergeResult, error) {
+	return s.mergeProducts(fromID, intoID, "")
+}
+
+func (s *MemoryStore) mergeProducts(fromID, intoID, actor string) (*MergeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, exists := s.products[fromID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, fromID)
+	}
+	into, exists := s.products[intoID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, intoID)
+	}
+	if err := checkMerge(from, into); err != nil {
+		return nil, err
+	}
+	result := &MergeResult{}
+	now := time.Now()
+
+	// Stock
+	stock := s.stock[intoID]
+	if stock == nil {
+		stock = &models.Stock{ProductID: intoID}
+		s.stock[intoID] = stock
+	}
+	before := *stock
+	stock.QuantityBoxes, stock.QuantityUnits = mergeStock(stock, s.stock[fromID])
+	stock.LastUpdated = now
+	stock.Version++
+	if fromStock := s.stock[fromID]; fromStock != nil {
+		fromStock.QuantityBoxes, fromStock.QuantityUnits = 0, 0
+		fromStock.LastUpdated = now
+		fromStock.Version++
+	}
+
+	// Everything that points at the duplicate
+	for _, m := range s.movements {
+		if m.ProductID == fromID {
+			m.ProductID = intoID
+			result.Movements++
+		}
+	}
+	have := make(map[string]bool)
+	for _, a := range s.aliases[intoID] {
+		have[NormalizeSearchText(a.Alias)] = true
+	}
+	for _, a := range s.aliases[fromID] {
+		if key := NormalizeSearchText(a.Alias); !have[key] {
+			have[key] = true
+			a.ProductID = intoID
+			s.aliases[intoID] = append(s.aliases[intoID], a)
+			result.Aliases++
+		}
+	}
+	delete(s.aliases, fromID)
+	for _, b := range s.barcodes {
+		if b.ProductID == fromID {
+			b.ProductID = intoID
+			result.Barcodes++
+		}
+	}
+	for _, m := range s.supplierSKUs {
+		if m.ProductID == fromID {
+			m.ProductID = intoID
+			result.SupplierSKUs++
+		}
+	}
+
+	// Archive the duplicate
+	for _, e := range mergeAuditEntries(from, into, &before, stock, actor) {
+		s.recordAudit(e)
+	}
+	from.IsActive = false
+	from.Version++
+	from.UpdatedAt = now
+
+	merged, mergedStock := *into, *stock
+	result.Product, result.Stock = &merged, &mergedStock
+	return result, nil
+}
+
 // ============================================
 // STOCK OPERATIONS
 // ============================================
@@ -220,12 +542,17 @@ func (s *MemoryStore) UpdateStock(productID string, boxes, units int) error {
 	stock.QuantityBoxes = newBoxes
 	stock.QuantityUnits = newUnits
 	stock.LastUpdated = time.Now()
+	stock.Version++
 
 	return nil
 }
 
 // SetMinStock sets the minimum stock alert threshold
-func (s *MemoryStore) SetMinStock(productID string, minStock int) error {
+func (s *MemoryStore) SetMinStock(productID string, minStock, version int) error {
+	return s.setMinStock(productID, minStock, version, "")
+}
+
+func (s *MemoryStore) setMinStock(productID string, minStock, version int, actor string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -233,11 +560,42 @@ func (s *MemoryStore) SetMinStock(productID string, minStock int) error {
 	if !exists {
 		return fmt.Errorf("%w: %s", ErrStockNotFound, productID)
 	}
+	if version != 0 && version != stock.Version {
+		return fmt.Errorf("%w: stock of %s is at version %d, not %d", ErrVersionConflict, productID, stock.Version, version)
+	}
+	if stock.MinStock == minStock {
+		return nil
+	}
 
+	s.recordAudit(newAuditEntry(models.AuditMinStock, productID, actor,
+		[]models.FieldChange{{Field: "MinStock", Before: stock.MinStock, After: minStock}}))
 	stock.MinStock = minStock
+	stock.LastUpdated = time.Now()
+	stock.Version++
 	return nil
 }
 
+// ListStockChangedSince returns stock rows changed at or after since,
+// oldest change first
+func (s *MemoryStore) ListStockChangedSince(since time.Time) ([]*models.Stock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Stock, 0)
+	for _, st := range s.stock {
+		if !st.LastUpdated.Before(since) {
+			result = append(result, st)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastUpdated.Equal(result[j].LastUpdated) {
+			return result[i].LastUpdated.Before(result[j].LastUpdated)
+		}
+		return result[i].ProductID < result[j].ProductID
+	})
+	return result, nil
+}
+
 // GetLowStockProducts returns products below their minimum stock level
 func (s *MemoryStore) GetLowStockProducts() []*models.Product {
 	s.mu.RLock()
@@ -261,21 +619,1099 @@ func (s *MemoryStore) GetLowStockProducts() []*models.Product {
 }
 
 // ============================================
-// UTILITY METHODS
+// MOVEMENT OPERATIONS
 // ============================================
 
-// Count returns the number of active products
-func (s *MemoryStore) Count() int {
+// RecordMovement applies a movement to stock and appends it to the log
+// Stock and log are updated under one lock, so they never disagree
+func (s *MemoryStore) RecordMovement(m *models.StockMovement) (string, error) {
+	if err := m.Validate(); err != nil {
+		return "", fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m.ClientID != "" {
+		for _, logged := range s.movements {
+			if logged.ClientID == m.ClientID {
+				return "", fmt.Errorf("%w: client ID %s is %s", ErrMovementExists, m.ClientID, logged.ID)
+			}
+		}
+	}
+
+	stock, exists := s.stock[m.ProductID]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrStockNotFound, m.ProductID)
+	}
+	// Archived (or merged away) products have no stock to change
+	if p := s.products[m.ProductID]; p != nil && !p.IsActive {
+		return "", fmt.Errorf("%w: %s", ErrProductInactive, m.ProductID)
+	}
+
+	boxes, units := m.StockDelta()
+	newBoxes := stock.QuantityBoxes + boxes
+	newUnits := stock.QuantityUnits + units
+	if newBoxes < 0 || newUnits < 0 {
+		return "", fmt.Errorf("%w: would result in %d boxes, %d units",
+			ErrInsufficientStock, newBoxes, newUnits)
+	}
+
+	stock.QuantityBoxes = newBoxes
+	stock.QuantityUnits = newUnits
+	stock.LastUpdated = time.Now()
+	stock.Version++
+
+	if m.ID == "" {
+		m.ID = fmt.Sprintf("MOV-%03d", s.nextMovementID)
+		s.nextMovementID++
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.Source == "" {
+		m.Source = models.SourceManual
+	}
+
+	// Store a copy so callers can't change history behind our back
+	logged := *m
+	s.movements = append(s.movements, &logged)
+
+	return m.ID, nil
+}
+
+// ListMovements returns movements matching the filter, oldest first
+func (s *MemoryStore) ListMovements(filter MovementFilter) []*models.StockMovement {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	count := 0
-	for _, p := range s.products {
-		if p.IsActive {
-			count++
+	result := make([]*models.StockMovement, 0)
+	for _, m := range s.movements {
+		if filter.ProductID != "" && m.ProductID != filter.ProductID {
+			continue
+		}
+		if filter.Type != "" && m.Type != filter.Type {
+			continue
+		}
+		if !filter.From.IsZero() && m.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !m.CreatedAt.Before(filter.To) {
+			continue
+		}
+		if filter.StaffID != "" && m.PerformedByID != filter.StaffID && m.ReportedByID != filter.StaffID {
+			continue
 		}
+		result = append(result, m)
 	}
-	return count
+	return result
+}
+
+// GetMovementByClientID finds a movement by the ID its offline client made up
+func (s *MemoryStore) GetMovementByClientID(clientID string) (*models.StockMovement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.movements {
+		if clientID != "" && m.ClientID == clientID {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: client ID %s", ErrMovementNotFound, clientID)
+}
+
+// ============================================
+// ALIAS OPERATIONS
+// ============================================
+
+// AddAlias adds an alias to a product
+// The same alias (after normalization) can't be added twice to one product
+func (s *MemoryStore) AddAlias(productID, alias string) (*models.ProductAlias, error) {
+	a := &models.ProductAlias{ProductID: productID, Alias: strings.TrimSpace(alias)}
+	if err := a.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.products[productID]; !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, productID)
+	}
+	key := NormalizeSearchText(a.Alias)
+	for _, existing := range s.aliases[productID] {
+		if NormalizeSearchText(existing.Alias) == key {
+			return nil, fmt.Errorf("%w: %s", ErrAliasExists, a.Alias)
+		}
+	}
+
+	a.ID = fmt.Sprintf("ALS-%03d", s.nextAliasID)
+	s.nextAliasID++
+	a.CreatedAt = time.Now()
+	s.aliases[productID] = append(s.aliases[productID], a)
+
+	stored := *a
+	return &stored, nil
+}
+
+// ListAliases returns a product's aliases, oldest first
+func (s *MemoryStore) ListAliases(productID string) ([]*models.ProductAlias, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.products[productID]; !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, productID)
+	}
+	result := make([]*models.ProductAlias, 0, len(s.aliases[productID]))
+	for _, a := range s.aliases[productID] {
+		copied := *a
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+// DeleteAlias removes one alias from a product
+func (s *MemoryStore) DeleteAlias(productID, aliasID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.aliases[productID]
+	for i, a := range list {
+		if a.ID == aliasID {
+			s.aliases[productID] = append(list[:i:i], list[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAliasNotFound, aliasID)
+}
+
+// ============================================
+// BARCODE OPERATIONS
+// ============================================
+
+// AddBarcode validates and stores a barcode for a product
+// Case barcodes need a product that comes in boxes
+func (s *MemoryStore) AddBarcode(productID, code, packLevel string) (*models.ProductBarcode, error) {
+	b := &models.ProductBarcode{Code: code, ProductID: productID, PackLevel: packLevel}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	b.Code, _ = models.NormalizeBarcode(code)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, exists := s.products[productID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, productID)
+	}
+	if packLevel == models.PackCase && product.BoxSize == 0 {
+		return nil, fmt.Errorf("validation failed: %w", models.ErrBarcodeCaseNeedsBox)
+	}
+	if _, taken := s.barcodes[b.Code]; taken {
+		return nil, fmt.Errorf("%w: %s", ErrBarcodeExists, b.Code)
+	}
+
+	b.CreatedAt = time.Now()
+	s.barcodes[b.Code] = b
+
+	stored := *b
+	return &stored, nil
+}
+
+// ListBarcodes returns a product's barcodes, oldest first
+func (s *MemoryStore) ListBarcodes(productID string) ([]*models.ProductBarcode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.products[productID]; !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, productID)
+	}
+	result := make([]*models.ProductBarcode, 0)
+	for _, b := range s.barcodes {
+		if b.ProductID == productID {
+			copied := *b
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result, nil
+}
+
+// DeleteBarcode removes one barcode from a product
+func (s *MemoryStore) DeleteBarcode(productID, code string) error {
+	normalized, err := models.NormalizeBarcode(code)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.barcodes[normalized]
+	if !exists || b.ProductID != productID {
+		return fmt.Errorf("%w: %s", ErrBarcodeNotFound, normalized)
+	}
+	delete(s.barcodes, normalized)
+	return nil
+}
+
+// GetProductByBarcode finds the product and pack level for a scanned code
+func (s *MemoryStore) GetProductByBarcode(code string) (*models.Product, *models.ProductBarcode, error) {
+	normalized, err := models.NormalizeBarcode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, exists := s.barcodes[normalized]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrBarcodeNotFound, normalized)
+	}
+	product, exists := s.products[b.ProductID]
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrProductNotFound, b.ProductID)
+	}
+	copied := *b
+	return product, &copied, nil
+}
+
+// ============================================
+// SUPPLIER OPERATIONS
+// ============================================
+
+// supplierSKUKey joins supplier and SKU into one map key
+func supplierSKUKey(supplier, sku string) string {
+	return supplier + "\x00" + sku
+}
+
+// SetSupplierSKU creates or replaces the mapping for (supplier, SKU)
+func (s *MemoryStore) SetSupplierSKU(m *models.SupplierSKU) error {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.products[m.ProductID]; !exists {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, m.ProductID)
+	}
+	stored := *m
+	stored.UpdatedAt = time.Now()
+	s.supplierSKUs[supplierSKUKey(m.Supplier, m.SKU)] = &stored
+	m.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// GetSupplierSKU returns the mapping for a supplier's item code
+func (s *MemoryStore) GetSupplierSKU(supplier, sku string) (*models.SupplierSKU, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, exists := s.supplierSKUs[supplierSKUKey(supplier, sku)]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s %s", ErrSupplierSKUNotFound, supplier, sku)
+	}
+	copied := *m
+	return &copied, nil
+}
+
+// ============================================
+// USER OPERATIONS
+// ============================================
+
+// CreateUser stores a new user, returns generated ID
+func (s *MemoryStore) CreateUser(u *models.User) (string, error) {
+	u.Username = models.NormalizeUsername(u.Username)
+	if err := u.Validate(); err != nil {
+		return "", fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == u.Username {
+			return "", fmt.Errorf("%w: %s", ErrUserExists, u.Username)
+		}
+	}
+
+	u.ID = fmt.Sprintf("USR-%03d", s.nextUserID)
+	s.nextUserID++
+	u.CreatedAt = time.Now()
+	u.UpdatedAt = u.CreatedAt
+
+	stored := *u
+	s.users[u.ID] = &stored
+	return u.ID, nil
+}
+
+// GetUser retrieves a user by ID
+func (s *MemoryStore) GetUser(id string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, exists := s.users[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	copied := *u
+	return &copied, nil
+}
+
+// GetUserByUsername retrieves a user by login name (case-insensitive)
+func (s *MemoryStore) GetUserByUsername(username string) (*models.User, error) {
+	username = models.NormalizeUsername(username)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+}
+
+// UpdateUser saves all fields of an existing user
+func (s *MemoryStore) UpdateUser(u *models.User) error {
+	u.Username = models.NormalizeUsername(u.Username)
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.users[u.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, u.ID)
+	}
+	for _, other := range s.users {
+		if other.ID != u.ID && other.Username == u.Username {
+			return fmt.Errorf("%w: %s", ErrUserExists, u.Username)
+		}
+	}
+
+	u.CreatedAt = existing.CreatedAt
+	u.UpdatedAt = time.Now()
+	stored := *u
+	s.users[u.ID] = &stored
+	return nil
+}
+
+// ListUsers returns all users ordered by username
+func (s *MemoryStore) ListUsers() []*models.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.User, 0, len(s.users))
+	for _, u := range s.users {
+		copied := *u
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	return result
+}
+
+// CountUsers returns the number of users
+func (s *MemoryStore) CountUsers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// ============================================
+// SESSION OPERATIONS
+// ============================================
+
+// CreateSession stores a new session
+func (s *MemoryStore) CreateSession(sess *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[sess.UserID]; !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, sess.UserID)
+	}
+	if sess.CreatedAt.IsZero() {
+		sess.CreatedAt = time.Now()
+	}
+	stored := *sess
+	s.sessions[sess.ID] = &stored
+	return nil
+}
+
+// GetSession retrieves a session by ID
+func (s *MemoryStore) GetSession(id string) (*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, exists := s.sessions[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	copied := *sess
+	return &copied, nil
+}
+
+// UpdateSession saves the refresh hash, expiry and revocation
+func (s *MemoryStore) UpdateSession(sess *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.sessions[sess.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sess.ID)
+	}
+	existing.RefreshHash = sess.RefreshHash
+	existing.ExpiresAt = sess.ExpiresAt
+	existing.RevokedAt = sess.RevokedAt
+	return nil
+}
+
+// ============================================
+// API KEY OPERATIONS
+// ============================================
+
+// copyAPIKey returns a copy that doesn't share the scopes slice
+func copyAPIKey(k *models.APIKey) *models.APIKey {
+	copied := *k
+	copied.Scopes = append([]string(nil), k.Scopes...)
+	return &copied
+}
+
+// CreateAPIKey stores a new key (the ID is set by the caller)
+func (s *MemoryStore) CreateAPIKey(k *models.APIKey) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if err := k.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[k.CreatedBy]; k.CreatedBy != "" && !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, k.CreatedBy)
+	}
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+	s.apiKeys[k.ID] = copyAPIKey(k)
+	return nil
+}
+
+// GetAPIKey retrieves a key by ID
+func (s *MemoryStore) GetAPIKey(id string) (*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, exists := s.apiKeys[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+	return copyAPIKey(k), nil
+}
+
+// UpdateAPIKey saves the revocation
+func (s *MemoryStore) UpdateAPIKey(k *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.apiKeys[k.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, k.ID)
+	}
+	existing.RevokedAt = k.RevokedAt
+	return nil
+}
+
+// TouchAPIKey sets when an active key was last used
+func (s *MemoryStore) TouchAPIKey(id string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.apiKeys[id]
+	if !exists || !existing.IsActive() {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+	existing.LastUsedAt = t
+	return nil
+}
+
+// ListAPIKeys returns all keys (active and revoked), newest first
+func (s *MemoryStore) ListAPIKeys() []*models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.APIKey, 0, len(s.apiKeys))
+	for _, k := range s.apiKeys {
+		result = append(result, copyAPIKey(k))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	return result
+}
+
+// ============================================
+// AUDIT OPERATIONS
+// ============================================
+
+// WithActor returns the store, recording actor on audited changes
+func (s *MemoryStore) WithActor(actor string) Repository {
+	return &actorStore{Repository: s, writer: s, actor: actor}
+}
+
+// recordAudit appends an entry; caller must hold the write lock
+func (s *MemoryStore) recordAudit(e *models.AuditEntry) {
+	if e == nil {
+		return
+	}
+	e.ID = fmt.Sprintf("AUD-%03d", s.nextAuditID)
+	s.nextAuditID++
+	s.audit = append(s.audit, e)
+}
+
+// ListAudit returns matching entries, newest first
+func (s *MemoryStore) ListAudit(filter AuditFilter) []*models.AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*models.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+		if filter.matches(s.audit[i]) {
+			copied := *s.audit[i]
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+// ============================================
+// IDEMPOTENCY OPERATIONS
+// ============================================
+
+// idempotencyKey is the map key of a record: keys are per caller
+func idempotencyKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+// copyIdempotencyRecord returns a copy that shares no maps or slices
+func copyIdempotencyRecord(rec *models.IdempotencyRecord) *models.IdempotencyRecord {
+	copied := *rec
+	copied.Body = append([]byte(nil), rec.Body...)
+	copied.Headers = make(map[string]string, len(rec.Headers))
+	for k, v := range rec.Headers {
+		copied.Headers[k] = v
+	}
+	return &copied
+}
+
+// ReserveIdempotencyKey claims a key, or returns the live record holding it
+func (s *MemoryStore) ReserveIdempotencyKey(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey(rec.Scope, rec.Key)
+	if existing, ok := s.idempotency[k]; ok && !existing.IsExpired(rec.CreatedAt) {
+		return copyIdempotencyRecord(existing), nil
+	}
+	s.idempotency[k] = copyIdempotencyRecord(rec)
+	return nil, nil
+}
+
+// SaveIdempotentResponse stores the response of a reserved key
+func (s *MemoryStore) SaveIdempotentResponse(rec *models.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey(rec.Scope, rec.Key)
+	if _, ok := s.idempotency[k]; !ok {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyNotFound, rec.Key)
+	}
+	s.idempotency[k] = copyIdempotencyRecord(rec)
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a reserved key
+func (s *MemoryStore) ReleaseIdempotencyKey(scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, idempotencyKey(scope, key))
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes records expired at now
+func (s *MemoryStore) PurgeIdempotencyKeys(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for k, rec := range s.idempotency {
+		if rec.IsExpired(now) {
+			delete(s.idempotency, k)
+			n++
+		}
+	}
+	return n
+}
+
+// ============================================
+// STAFF OPERATIONS
+// ============================================
+
+// AddStaff stores a new staff member, returns generated ID
+func (s *MemoryStore) AddStaff(st *models.Staff) (string, error) {
+	st.NameHe = strings.TrimSpace(st.NameHe)
+	st.NameEn = strings.TrimSpace(st.NameEn)
+	if err := st.Validate(); err != nil {
+		return "", fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkStaffUser(st); err != nil {
+		return "", err
+	}
+	st.ID = fmt.Sprintf("STF-%03d", s.nextStaffID)
+	s.nextStaffID++
+	st.CreatedAt = time.Now()
+	st.UpdatedAt = st.CreatedAt
+
+	stored := *st
+	s.staff[st.ID] = &stored
+	return st.ID, nil
+}
+
+// GetStaff retrieves a staff member by ID
+func (s *MemoryStore) GetStaff(id string) (*models.Staff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st, exists := s.staff[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrStaffNotFound, id)
+	}
+	copied := *st
+	return &copied, nil
+}
+
+// GetStaffByUser retrieves the staff member linked to a login account
+func (s *MemoryStore) GetStaffByUser(userID string) (*models.Staff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, st := range s.staff {
+		if userID != "" && st.UserID == userID {
+			copied := *st
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: user %s", ErrStaffNotFound, userID)
+}
+
+// UpdateStaff saves names, login link and active flag
+func (s *MemoryStore) UpdateStaff(st *models.Staff) error {
+	st.NameHe = strings.TrimSpace(st.NameHe)
+	st.NameEn = strings.TrimSpace(st.NameEn)
+	if err := st.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.staff[st.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrStaffNotFound, st.ID)
+	}
+	if err := s.checkStaffUser(st); err != nil {
+		return err
+	}
+	st.CreatedAt = existing.CreatedAt
+	st.UpdatedAt = time.Now()
+	stored := *st
+	s.staff[st.ID] = &stored
+	return nil
+}
+
+// checkStaffUser makes sure a login account links to one staff member
+// and exists. Caller must hold the lock.
+func (s *MemoryStore) checkStaffUser(st *models.Staff) error {
+	if st.UserID == "" {
+		return nil
+	}
+	if _, exists := s.users[st.UserID]; !exists {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, st.UserID)
+	}
+	for _, other := range s.staff {
+		if other.ID != st.ID && other.UserID == st.UserID {
+			return fmt.Errorf("%w: %s", ErrStaffUserLinked, st.UserID)
+		}
+	}
+	return nil
+}
+
+// ListStaff returns all staff members ordered by ID
+func (s *MemoryStore) ListStaff() []*models.Staff {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Staff, 0, len(s.staff))
+	for _, st := range s.staff {
+		copied := *st
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// SetMovementStaff links an already logged movement to staff members
+func (s *MemoryStore) SetMovementStaff(movementID, performedByID, reportedByID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []string{performedByID, reportedByID} {
+		if _, exists := s.staff[id]; id != "" && !exists {
+			return fmt.Errorf("%w: %s", ErrStaffNotFound, id)
+		}
+	}
+	for _, m := range s.movements {
+		if m.ID == movementID {
+			m.PerformedByID = performedByID
+			m.ReportedByID = reportedByID
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrMovementNotFound, movementID)
+}
+
+// ============================================
+// UTILITY METHODS
+// ============================================
+
+// Count returns the number of active products
+func (s *MemoryStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, p := range s.products {
+		if p.IsActive {
+			count++
+		}
+	}
+	return count
+}
+
+// ============================================
+// CATEGORY OPERATIONS
+// ============================================
+
+// defaultCategoryMap returns models.DefaultCategories by ID
+func defaultCategoryMap() map[string]*models.Category {
+	categories := make(map[string]*models.Category)
+	now := time.Now()
+	for _, c := range models.DefaultCategories() {
+		c.CreatedAt, c.UpdatedAt = now, now
+		categories[c.ID] = c
+	}
+	return categories
+}
+
+// HasCategory reports whether a category exists
+func (s *MemoryStore) HasCategory(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.categories[id]
+	return exists
+}
+
+// GetCategory retrieves a category by ID
+func (s *MemoryStore) GetCategory(id string) (*models.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, exists := s.categories[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// ListCategories returns all categories by SortOrder, then ID
+func (s *MemoryStore) ListCategories() []*models.Category {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Category, 0, len(s.categories))
+	for _, c := range s.categories {
+		copied := *c
+		result = append(result, &copied)
+	}
+	sortCategories(result)
+	return result
+}
+
+// AddCategory stores a new category under its own ID
+func (s *MemoryStore) AddCategory(c *models.Category) error {
+	c.NameHe = strings.TrimSpace(c.NameHe)
+	c.NameEn = strings.TrimSpace(c.NameEn)
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.categories[c.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrCategoryExists, c.ID)
+	}
+	if err := checkCategoryParent(c, s.categoryByID); err != nil {
+		return err
+	}
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	stored := *c
+	s.categories[c.ID] = &stored
+	return nil
+}
+
+// UpdateCategory saves names, sort order, parent and default min stock
+func (s *MemoryStore) UpdateCategory(c *models.Category) error {
+	c.NameHe = strings.TrimSpace(c.NameHe)
+	c.NameEn = strings.TrimSpace(c.NameEn)
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.categories[c.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, c.ID)
+	}
+	if err := checkCategoryParent(c, s.categoryByID); err != nil {
+		return err
+	}
+	c.CreatedAt = existing.CreatedAt
+	c.UpdatedAt = time.Now()
+	stored := *c
+	s.categories[c.ID] = &stored
+	return nil
+}
+
+// DeleteCategory removes a category no product or subcategory uses
+func (s *MemoryStore) DeleteCategory(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.categories[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+	products := 0
+	for _, p := range s.products {
+		if p.Category == id {
+			products++
+		}
+	}
+	if products > 0 {
+		return fmt.Errorf("%w: %d products are in %s", ErrCategoryInUse, products, id)
+	}
+	for _, c := range s.categories {
+		if c.ParentID == id {
+			return fmt.Errorf("%w: %s is the parent of %s", ErrCategoryInUse, id, c.ID)
+		}
+	}
+	delete(s.categories, id)
+	return nil
+}
+
+// categoryByID returns a category or nil. Caller must hold the lock.
+func (s *MemoryStore) categoryByID(id string) *models.Category {
+	return s.categories[id]
+}
+
+// ============================================
+// BACKUP OPERATIONS
+// ============================================
+
+// Snapshot returns copies of every table, taken under one lock
+func (s *MemoryStore) Snapshot() (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := &Snapshot{}
+	for _, p := range s.products {
+		copied := *p
+		snap.Products = append(snap.Products, &copied)
+	}
+	sort.Slice(snap.Products, func(i, j int) bool { return snap.Products[i].ID < snap.Products[j].ID })
+	for _, st := range s.stock {
+		copied := *st
+		snap.Stock = append(snap.Stock, &copied)
+	}
+	sort.Slice(snap.Stock, func(i, j int) bool { return snap.Stock[i].ProductID < snap.Stock[j].ProductID })
+	for _, m := range s.movements {
+		copied := *m
+		snap.Movements = append(snap.Movements, &copied)
+	}
+	for _, p := range snap.Products {
+		for _, a := range s.aliases[p.ID] {
+			copied := *a
+			snap.Aliases = append(snap.Aliases, &copied)
+		}
+	}
+	for _, b := range s.barcodes {
+		copied := *b
+		snap.Barcodes = append(snap.Barcodes, &copied)
+	}
+	sort.Slice(snap.Barcodes, func(i, j int) bool { return snap.Barcodes[i].Code < snap.Barcodes[j].Code })
+	for _, m := range s.supplierSKUs {
+		copied := *m
+		snap.SupplierSKUs = append(snap.SupplierSKUs, &copied)
+	}
+	sort.Slice(snap.SupplierSKUs, func(i, j int) bool {
+		return supplierSKUKey(snap.SupplierSKUs[i].Supplier, snap.SupplierSKUs[i].SKU) <
+			supplierSKUKey(snap.SupplierSKUs[j].Supplier, snap.SupplierSKUs[j].SKU)
+	})
+	for _, u := range s.users {
+		copied := *u
+		snap.Users = append(snap.Users, &copied)
+	}
+	sort.Slice(snap.Users, func(i, j int) bool { return snap.Users[i].ID < snap.Users[j].ID })
+	for _, st := range s.staff {
+		copied := *st
+		snap.Staff = append(snap.Staff, &copied)
+	}
+	sort.Slice(snap.Staff, func(i, j int) bool { return snap.Staff[i].ID < snap.Staff[j].ID })
+	for _, c := range s.categories {
+		copied := *c
+		snap.Categories = append(snap.Categories, &copied)
+	}
+	sort.Slice(snap.Categories, func(i, j int) bool { return snap.Categories[i].ID < snap.Categories[j].ID })
+	for _, k := range s.apiKeys {
+		snap.APIKeys = append(snap.APIKeys, copyAPIKey(k))
+	}
+	sort.Slice(snap.APIKeys, func(i, j int) bool { return snap.APIKeys[i].ID < snap.APIKeys[j].ID })
+	for _, e := range s.audit {
+		copied := *e
+		snap.Audit = append(snap.Audit, &copied)
+	}
+	return snap, nil
+}
+
+// Restore replaces all data with copies of the snapshot
+func (s *MemoryStore) Restore(snap *Snapshot) error {
+	snap = snap.withCategories()
+	if err := snap.Validate(); err != nil {
+		return err
+	}
+
+	products := make(map[string]*models.Product, len(snap.Products))
+	var productIDs []string
+	for _, p := range snap.Products {
+		copied := *p
+		products[p.ID] = &copied
+		productIDs = append(productIDs, p.ID)
+	}
+	stock := make(map[string]*models.Stock, len(snap.Stock))
+	for _, st := range snap.Stock {
+		copied := *st
+		stock[st.ProductID] = &copied
+	}
+	movements := make([]*models.StockMovement, 0, len(snap.Movements))
+	var movementIDs []string
+	for _, m := range snap.Movements {
+		copied := *m
+		movements = append(movements, &copied)
+		movementIDs = append(movementIDs, m.ID)
+	}
+	aliases := make(map[string][]*models.ProductAlias)
+	var aliasIDs []string
+	for _, a := range snap.Aliases {
+		copied := *a
+		aliases[a.ProductID] = append(aliases[a.ProductID], &copied)
+		aliasIDs = append(aliasIDs, a.ID)
+	}
+	barcodes := make(map[string]*models.ProductBarcode, len(snap.Barcodes))
+	for _, b := range snap.Barcodes {
+		copied := *b
+		barcodes[b.Code] = &copied
+	}
+	supplierSKUs := make(map[string]*models.SupplierSKU, len(snap.SupplierSKUs))
+	for _, m := range snap.SupplierSKUs {
+		copied := *m
+		supplierSKUs[supplierSKUKey(m.Supplier, m.SKU)] = &copied
+	}
+	users := make(map[string]*models.User, len(snap.Users))
+	var userIDs []string
+	for _, u := range snap.Users {
+		copied := *u
+		users[u.ID] = &copied
+		userIDs = append(userIDs, u.ID)
+	}
+	staff := make(map[string]*models.Staff, len(snap.Staff))
+	var staffIDs []string
+	for _, st := range snap.Staff {
+		copied := *st
+		staff[st.ID] = &copied
+		staffIDs = append(staffIDs, st.ID)
+	}
+	categories := make(map[string]*models.Category, len(snap.Categories))
+	for _, c := range snap.Categories {
+		copied := *c
+		categories[c.ID] = &copied
+	}
+	apiKeys := make(map[string]*models.APIKey, len(snap.APIKeys))
+	for _, k := range snap.APIKeys {
+		apiKeys[k.ID] = copyAPIKey(k)
+	}
+	audit := make([]*models.AuditEntry, 0, len(snap.Audit))
+	var auditIDs []string
+	for _, e := range snap.Audit {
+		copied := *e
+		audit = append(audit, &copied)
+		auditIDs = append(auditIDs, e.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products, s.stock, s.movements = products, stock, movements
+	s.aliases, s.barcodes, s.supplierSKUs = aliases, barcodes, supplierSKUs
+	s.users, s.staff, s.apiKeys, s.audit = users, staff, apiKeys, audit
+	s.categories = categories
+	s.sessions = make(map[string]*models.Session)
+	s.idempotency = make(map[string]*models.IdempotencyRecord)
+	s.nextID = nextCounter("PROD", productIDs)
+	s.nextMovementID = nextCounter("MOV", movementIDs)
+	s.nextAliasID = nextCounter("ALS", aliasIDs)
+	s.nextUserID = nextCounter("USR", userIDs)
+	s.nextStaffID = nextCounter("STF", staffIDs)
+	s.nextAuditID = nextCounter("AUD", auditIDs)
+	return nil
 }
 
 // Clear removes all data (useful for testing)
@@ -285,5 +1721,21 @@ func (s *MemoryStore) Clear() {
 
 	s.products = make(map[string]*models.Product)
 	s.stock = make(map[string]*models.Stock)
+	s.movements = nil
+	s.aliases = make(map[string][]*models.ProductAlias)
+	s.barcodes = make(map[string]*models.ProductBarcode)
+	s.supplierSKUs = make(map[string]*models.SupplierSKU)
+	s.users = make(map[string]*models.User)
+	s.sessions = make(map[string]*models.Session)
+	s.apiKeys = make(map[string]*models.APIKey)
+	s.staff = make(map[string]*models.Staff)
+	s.categories = defaultCategoryMap() // Products need categories; start from the defaults
+	s.audit = nil
+	s.idempotency = make(map[string]*models.IdempotencyRecord)
 	s.nextID = 1
+	s.nextMovementID = 1
+	s.nextAliasID = 1
+	s.nextUserID = 1
+	s.nextStaffID = 1
+	s.nextAuditID = 1
 }
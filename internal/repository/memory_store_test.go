@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+
+	repostest "github.com/mennyaboush/restaurant-inventory-ai/internal/repository/test"
+)
+
+// The shared store tests need no database for MemoryStore,
+// so they run with plain `go test`
+func TestMemoryStoreShared(t *testing.T) {
+	repostest.RunStoreIntegrationTests(t, func(*sql.DB) repostest.Store { return NewMemoryStore() }, nil)
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// MERGE: fold a duplicate product into another
+// ============================================
+// Duplicates creep in ("COCACOLA-330-CAN" next to "Coca-Cola 330 can").
+// MergeProducts moves everything of the duplicate over to the product
+// that stays - stock, movements, aliases, barcodes, supplier SKUs - and
+// archives the duplicate, in one step. The history stays whole: past
+// movements count for the product that stays, and the audit trail of
+// both products says who merged what, and when.
+
+// MergeResult is what MergeProducts did
+type MergeResult struct {
+	Product *models.Product // The product that stays
+	Stock   *models.Stock   // Its stock, with the duplicate's added
+
+	// Rows moved over from the duplicate
+	Movements    int
+	Aliases      int // Aliases the product already had are dropped
+	Barcodes     int
+	SupplierSKUs int
+}
+
+// checkMerge says why from can't be merged into into, or returns nil
+func checkMerge(from, into *models.Product) error {
+	switch {
+	case from.ID == into.ID:
+		return fmt.Errorf("%w: a product can't be merged into itself", ErrInvalidMerge)
+	case !into.IsActive:
+		return fmt.Errorf("%w: %s is archived (restore it first)", ErrInvalidMerge, into.ID)
+	case from.BoxSize != into.BoxSize:
+		// The duplicate's movements, case barcodes and case SKUs count its
+		// own boxes; moved over they would count boxes of another size
+		return fmt.Errorf("%w: box sizes differ (%d and %d, make them equal first)", ErrInvalidMerge, from.BoxSize, into.BoxSize)
+	}
+	return nil
+}
+
+// mergeStock adds the duplicate's stock to the stock of the product that
+// stays (both have the same box size, see checkMerge)
+func mergeStock(into, from *models.Stock) (boxes, units int) {
+	if from == nil {
+		return into.QuantityBoxes, into.QuantityUnits
+	}
+	return into.QuantityBoxes + from.QuantityBoxes, into.QuantityUnits + from.QuantityUnits
+}
+
+// mergeAuditEntries builds the entries of both products: the duplicate
+// records where it went, the product that stays where its stock came from
+func mergeAuditEntries(from, into *models.Product, before, after *models.Stock, actor string) []*models.AuditEntry {
+	fromChanges := []models.FieldChange{{Field: "MergedInto", After: into.ID}}
+	if from.IsActive {
+		fromChanges = append(fromChanges, models.FieldChange{Field: "IsActive", Before: true, After: false})
+	}
+	intoChanges := []models.FieldChange{{Field: "MergedFrom", After: from.ID}}
+	if before.QuantityBoxes != after.QuantityBoxes {
+		intoChanges = append(intoChanges, models.FieldChange{Field: "QuantityBoxes", Before: before.QuantityBoxes, After: after.QuantityBoxes})
+	}
+	if before.QuantityUnits != after.QuantityUnits {
+		intoChanges = append(intoChanges, models.FieldChange{Field: "QuantityUnits", Before: before.QuantityUnits, After: after.QuantityUnits})
+	}
+	return []*models.AuditEntry{
+		newAuditEntry(models.AuditMerge, from.ID, actor, fromChanges),
+		newAuditEntry(models.AuditMerge, into.ID, actor, intoChanges),
+	}
+}
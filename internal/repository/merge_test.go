@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testMergeProducts runs against any store; PostgreSQL runs it with -tags integration
+func testMergeProducts(t *testing.T, store Repository) {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+	size := 330 // Brand + size + container is unique in PostgreSQL
+	add := func(name string, boxSize int) string {
+		size++
+		id, err := store.AddProduct(&models.Product{Name: name, Brand: fmt.Sprintf("merge-%d", suffix), Size: size, ContainerType: "can", BoxSize: boxSize, Price: 5, Category: "drinks"})
+		if err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+		return id
+	}
+	move := func(productID, movementType string, boxes, units int) {
+		m, _ := models.NewStockMovement(productID, movementType, boxes, units, "יוסף", "", "")
+		if _, err := store.RecordMovement(m); err != nil {
+			t.Fatalf("RecordMovement failed: %v", err)
+		}
+	}
+
+	keep := add("Coca-Cola 330 can", 24)
+	dup := add("COCACOLA-330-CAN", 24)
+	move(keep, models.MovementIn, 2, 3)
+	move(dup, models.MovementIn, 1, 5)
+	move(dup, models.MovementOut, 0, 1)
+	for _, a := range []struct{ id, alias string }{{keep, "קולה"}, {dup, "קולה"}, {dup, "coke can"}} {
+		if _, err := store.AddAlias(a.id, a.alias); err != nil {
+			t.Fatalf("AddAlias failed: %v", err)
+		}
+	}
+	if _, err := store.AddBarcode(dup, "5449000000996", models.PackUnit); err != nil {
+		t.Fatalf("AddBarcode failed: %v", err)
+	}
+	supplier := fmt.Sprintf("Tempo-%d", suffix)
+	if err := store.SetSupplierSKU(&models.SupplierSKU{Supplier: supplier, SKU: "4711", ProductID: dup, PackLevel: models.PackCase}); err != nil {
+		t.Fatalf("SetSupplierSKU failed: %v", err)
+	}
+
+	if _, err := store.MergeProducts(keep, keep); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("merge into itself: got %v, want ErrInvalidMerge", err)
+	}
+	if _, err := store.MergeProducts(dup, "PROD-NOPE"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("merge into unknown product: got %v, want ErrProductNotFound", err)
+	}
+
+	res, err := store.WithActor("dana").MergeProducts(dup, keep)
+	if err != nil {
+		t.Fatalf("MergeProducts failed: %v", err)
+	}
+	if res.Product.ID != keep || res.Movements != 2 || res.Aliases != 1 || res.Barcodes != 1 || res.SupplierSKUs != 1 {
+		t.Errorf("merge result: got %+v", res)
+	}
+	if res.Stock.QuantityBoxes != 3 || res.Stock.QuantityUnits != 7 {
+		t.Errorf("merged stock: got %d boxes %d units, want 3 boxes 7 units", res.Stock.QuantityBoxes, res.Stock.QuantityUnits)
+	}
+	if st, _ := store.GetStock(dup); st.QuantityBoxes != 0 || st.QuantityUnits != 0 {
+		t.Errorf("duplicate's stock: got %d boxes %d units, want none", st.QuantityBoxes, st.QuantityUnits)
+	}
+	if p, _ := store.GetProduct(dup); p.IsActive {
+		t.Error("duplicate is still active")
+	}
+	if got := store.ListMovements(MovementFilter{ProductID: keep}); len(got) != 3 {
+		t.Errorf("movements of the product kept: got %d, want 3", len(got))
+	}
+	if got := store.ListMovements(MovementFilter{ProductID: dup}); len(got) != 0 {
+		t.Errorf("movements of the duplicate: got %d, want 0", len(got))
+	}
+	aliases, _ := store.ListAliases(keep)
+	var names []string
+	for _, a := range aliases {
+		names = append(names, a.Alias)
+	}
+	if fmt.Sprint(names) != "[קולה coke can]" {
+		t.Errorf("aliases: got %v, want [קולה coke can]", names)
+	}
+	if p, _, err := store.GetProductByBarcode("5449000000996"); err != nil || p.ID != keep {
+		t.Errorf("barcode: got %v %v, want %s", p, err, keep)
+	}
+	if m, err := store.GetSupplierSKU(supplier, "4711"); err != nil || m.ProductID != keep {
+		t.Errorf("supplier SKU: got %+v %v, want %s", m, err, keep)
+	}
+	for _, id := range []string{dup, keep} {
+		entries := store.ListAudit(AuditFilter{EntityID: id, Limit: 1})
+		if len(entries) != 1 || entries[0].Action != models.AuditMerge || entries[0].Actor != "dana" {
+			t.Errorf("merge audit entry of %s: got %+v", id, entries)
+		}
+	}
+
+	if _, err := store.MergeProducts(keep, dup); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("merge into archived product: got %v, want ErrInvalidMerge", err)
+	}
+
+	// Another box size: its movements would count boxes of the wrong
+	// size, so the merge is refused and nothing moves
+	sixPack := add("Coca-Cola 6-pack", 6)
+	move(sixPack, models.MovementIn, 2, 1)
+	if _, err := store.MergeProducts(sixPack, keep); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("merge with another box size: got %v, want ErrInvalidMerge", err)
+	}
+	if st, _ := store.GetStock(keep); st.QuantityBoxes != 3 || st.QuantityUnits != 7 {
+		t.Errorf("stock after refused merge: got %d boxes %d units, want 3 boxes 7 units", st.QuantityBoxes, st.QuantityUnits)
+	}
+	if got := store.ListMovements(MovementFilter{ProductID: sixPack}); len(got) != 1 {
+		t.Errorf("movements of the refused duplicate: got %d, want 1", len(got))
+	}
+	if p, _ := store.GetProduct(sixPack); !p.IsActive {
+		t.Error("refused duplicate was archived")
+	}
+}
+
+func TestMemoryStoreMergeProducts(t *testing.T) {
+	testMergeProducts(t, NewMemoryStore())
+}
@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
@@ -24,9 +30,34 @@ func genID(p *models.Product) string {
 	return fmt.Sprintf("%s-%d-%s", strings.ToUpper(strings.ReplaceAll(p.Brand, " ", "")), p.Size, strings.ToUpper(p.ContainerType))
 }
 
+// productColumns is the SELECT list matching scanProduct
+const productColumns = `id, name, brand, size, container_type, box_size, price, category, is_active, version, COALESCE(updated_at, created_at)`
+
+// scanProduct reads one row selected with productColumns
+func scanProduct(row interface{ Scan(...interface{}) error }) (*models.Product, error) {
+	var p models.Product
+	if err := row.Scan(&p.ID, &p.Name, &p.Brand, &p.Size, &p.ContainerType, &p.BoxSize, &p.Price, &p.Category, &p.IsActive, &p.Version, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// lockProduct reads a product inside tx and locks its row until commit
+func lockProduct(tx *sql.Tx, id string) (*models.Product, error) {
+	p, err := scanProduct(tx.QueryRow(`SELECT `+productColumns+` FROM products WHERE id=$1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	}
+	return p, err
+}
+
 // AddProduct creates a new product and ensures a stock row exists
 func (s *PostgresStore) AddProduct(p *models.Product) (string, error) {
-	if err := p.Validate(); err != nil {
+	return s.addProduct(p, "")
+}
+
+func (s *PostgresStore) addProduct(p *models.Product, actor string) (string, error) {
+	if err := p.Validate(s); err != nil {
 		return "", err
 	}
 	id := p.ID
@@ -40,12 +71,23 @@ func (s *PostgresStore) AddProduct(p *models.Product) (string, error) {
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	_, err = tx.Exec(`INSERT INTO products (id, name, brand, size, container_type, box_size, price, category, is_active) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT (brand,size,container_type) DO NOTHING`, id, p.Name, p.Brand, p.Size, p.ContainerType, p.BoxSize, p.Price, p.Category, p.IsActive)
+	res, err := tx.Exec(`INSERT INTO products (id, name, brand, size, container_type, box_size, price, category, is_active) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT (brand,size,container_type) DO NOTHING`, id, p.Name, p.Brand, p.Size, p.ContainerType, p.BoxSize, p.Price, p.Category, p.IsActive)
 	if err != nil {
 		return "", err
 	}
+	if cnt, err := res.RowsAffected(); err != nil {
+		return "", err
+	} else if cnt == 0 {
+		return "", fmt.Errorf("%w: %s %d %s", ErrProductExists, p.Brand, p.Size, p.ContainerType)
+	}
+	p.Version = 1
+	created := *p
+	created.ID = id
+	if err := insertAudit(tx, newAuditEntry(models.AuditCreate, id, actor, models.DiffFields(nil, &created))); err != nil {
+		return "", err
+	}
 
-	_, err = tx.Exec(`INSERT INTO stocks (product_id, quantity_boxes, quantity_units, min_stock, last_updated) VALUES ($1,0,0,0,CURRENT_TIMESTAMP) ON CONFLICT (product_id) DO NOTHING`, id)
+	_, err = tx.Exec(`INSERT INTO stocks (product_id, quantity_boxes, quantity_units, min_stock, last_updated) VALUES ($1,0,0,COALESCE((SELECT default_min_stock FROM categories WHERE id=$2),0),CURRENT_TIMESTAMP) ON CONFLICT (product_id) DO NOTHING`, id, p.Category)
 	if err != nil {
 		return "", err
 	}
@@ -58,102 +100,467 @@ func (s *PostgresStore) AddProduct(p *models.Product) (string, error) {
 
 // GetProduct retrieves a product by ID
 func (s *PostgresStore) GetProduct(id string) (*models.Product, error) {
-	row := s.db.QueryRow(`SELECT id, name, brand, size, container_type, box_size, price, category, is_active FROM products WHERE id=$1`, id)
-	var p models.Product
-	if err := row.Scan(&p.ID, &p.Name, &p.Brand, &p.Size, &p.ContainerType, &p.BoxSize, &p.Price, &p.Category, &p.IsActive); err != nil {
+	p, err := scanProduct(s.db.QueryRow(`SELECT `+productColumns+` FROM products WHERE id=$1`, id))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("%w: %s", ErrProductNotFound, id)
 		}
 		return nil, err
 	}
-	return &p, nil
+	return p, nil
 }
 
-// ListProducts returns all active products
+// ListProducts returns all active products, by name
 func (s *PostgresStore) ListProducts() []*models.Product {
-	rows, err := s.db.Query(`SELECT id, name, brand, size, container_type, box_size, price, category, is_active FROM products WHERE is_active = true ORDER BY name`)
+	rows, err := s.db.Query(`SELECT ` + productColumns + ` FROM products WHERE is_active = true ORDER BY name COLLATE "C", id COLLATE "C"`)
 	if err != nil {
 		return []*models.Product{}
 	}
 	defer rows.Close()
 	var res []*models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Brand, &p.Size, &p.ContainerType, &p.BoxSize, &p.Price, &p.Category, &p.IsActive); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			continue
 		}
-		res = append(res, &p)
+		res = append(res, p)
 	}
 	return res
 }
 
-// SearchProducts by name or brand
+// QueryProducts returns one page of the products matching q
+func (s *PostgresStore) QueryProducts(q ProductQuery) (*ProductPage, error) {
+	cursor, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	where := ` WHERE 1=1`
+	var args []interface{}
+	switch q.Status {
+	case ProductStatusActive:
+		where += ` AND is_active = true`
+	case ProductStatusInactive:
+		where += ` AND is_active = false`
+	}
+	for _, f := range []struct{ column, value string }{
+		{"category", q.Category},
+		{"brand", q.Brand},
+		{"container_type", q.ContainerType},
+	} {
+		if f.value != "" {
+			args = append(args, f.value)
+			where += fmt.Sprintf(" AND LOWER(%s)=LOWER($%d)", f.column, len(args))
+		}
+	}
+	if q.MinPrice > 0 {
+		args = append(args, q.MinPrice)
+		where += fmt.Sprintf(" AND price >= $%d", len(args))
+	}
+	if q.MaxPrice > 0 {
+		args = append(args, q.MaxPrice)
+		where += fmt.Sprintf(" AND price <= $%d", len(args))
+	}
+	if q.LowStock {
+		where += ` AND EXISTS (SELECT 1 FROM stocks s WHERE s.product_id = products.id AND (s.quantity_boxes * COALESCE(products.box_size,0) + s.quantity_units) < s.min_stock)`
+	}
+
+	page := &ProductPage{Products: []*models.Product{}}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM products`+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	column, dir, op := productSortColumns[q.Sort], "ASC", ">"
+	if q.Desc {
+		dir, op = "DESC", "<"
+	}
+	if cursor != nil {
+		value, _ := cursorArg(cursor)
+		args = append(args, value, cursor.ID)
+		where += fmt.Sprintf(` AND (%s, id COLLATE "C") %s ($%d, $%d)`, column, op, len(args)-1, len(args))
+	}
+	args = append(args, q.Limit+1) // One more tells whether there is a next page
+	query := `SELECT ` + productColumns + ` FROM products` + where +
+		fmt.Sprintf(` ORDER BY %s %s, id COLLATE "C" %s LIMIT $%d`, column, dir, dir, len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Products = append(page.Products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Products) > q.Limit {
+		page.Products = page.Products[:q.Limit]
+		last := page.Products[q.Limit-1]
+		page.NextCursor = productCursor{Sort: q.Sort, Desc: q.Desc, Value: sortValue(last, q.Sort), ID: last.ID}.encode()
+	}
+	return page, nil
+}
+
+// SearchProducts by name or brand, best match first
 func (s *PostgresStore) SearchProducts(query string) []*models.Product {
-	q := "%" + query + "%"
-	rows, err := s.db.Query(`SELECT id, name, brand, size, container_type, box_size, price, category, is_active FROM products WHERE is_active = true AND (name ILIKE $1 OR brand ILIKE $1) ORDER BY name`, q)
+	return matchedProducts(s.SearchProductsRanked(query))
+}
+
+// SearchProductsRanked loads active products and their aliases and
+// ranks them in Go, so results are identical to MemoryStore (see search.go)
+func (s *PostgresStore) SearchProductsRanked(query string) []ProductMatch {
+	return rankProducts(query, s.ListProducts(), s.allAliases())
+}
+
+// ListProductsChangedSince returns products (deleted ones too) changed at
+// or after since, oldest change first
+func (s *PostgresStore) ListProductsChangedSince(since time.Time) ([]*models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE 1=1`
+	var args []interface{}
+	if !since.IsZero() {
+		args = append(args, since)
+		query += fmt.Sprintf(" AND COALESCE(updated_at, created_at) >= $%d", len(args))
+	}
+	query += " ORDER BY COALESCE(updated_at, created_at), id"
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return []*models.Product{}
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// allAliases returns alias texts per product ID, oldest first
+func (s *PostgresStore) allAliases() map[string][]string {
+	res := make(map[string][]string)
+	rows, err := s.db.Query(`SELECT product_id, alias FROM product_aliases ORDER BY created_at, id`)
+	if err != nil {
+		return res
 	}
 	defer rows.Close()
-	var res []*models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Brand, &p.Size, &p.ContainerType, &p.BoxSize, &p.Price, &p.Category, &p.IsActive); err != nil {
+		var productID, alias string
+		if err := rows.Scan(&productID, &alias); err != nil {
 			continue
 		}
-		res = append(res, &p)
+		res[productID] = append(res[productID], alias)
 	}
 	return res
 }
 
 // UpdateProduct updates an existing product
 func (s *PostgresStore) UpdateProduct(p *models.Product) error {
-	if err := p.Validate(); err != nil {
+	return s.updateProduct(p, "")
+}
+
+func (s *PostgresStore) updateProduct(p *models.Product, actor string) error {
+	if err := p.Validate(s); err != nil {
 		return err
 	}
-	res, err := s.db.Exec(`UPDATE products SET name=$2, brand=$3, size=$4, container_type=$5, box_size=$6, price=$7, category=$8, is_active=$9, updated_at=CURRENT_TIMESTAMP WHERE id=$1`, p.ID, p.Name, p.Brand, p.Size, p.ContainerType, p.BoxSize, p.Price, p.Category, p.IsActive)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	cnt, err := res.RowsAffected()
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockProduct(tx, p.ID)
 	if err != nil {
 		return err
 	}
-	if cnt == 0 {
-		return fmt.Errorf("%w: %s", ErrProductNotFound, p.ID)
+	if p.Version != 0 && p.Version != before.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, p.ID, before.Version, p.Version)
 	}
-	return nil
+	err = tx.QueryRow(`UPDATE products SET name=$2, brand=$3, size=$4, container_type=$5, box_size=$6, price=$7, category=$8, is_active=$9, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1 RETURNING updated_at`, p.ID, p.Name, p.Brand, p.Size, p.ContainerType, p.BoxSize, p.Price, p.Category, p.IsActive).Scan(&p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	p.Version = before.Version + 1
+	if err := insertAudit(tx, newAuditEntry(models.AuditUpdate, p.ID, actor, models.DiffFields(before, p))); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // DeleteProduct soft-deletes a product
-func (s *PostgresStore) DeleteProduct(id string) error {
-	res, err := s.db.Exec(`UPDATE products SET is_active = false, updated_at=CURRENT_TIMESTAMP WHERE id=$1`, id)
+func (s *PostgresStore) DeleteProduct(id string, version int) error {
+	return s.deleteProduct(id, version, "")
+}
+
+func (s *PostgresStore) deleteProduct(id string, version int, actor string) error {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	cnt, err := res.RowsAffected()
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockProduct(tx, id)
 	if err != nil {
 		return err
 	}
-	if cnt == 0 {
-		return fmt.Errorf("%w: %s", ErrProductNotFound, id)
+	if version != 0 && version != before.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, before.Version, version)
 	}
-	return nil
+	if _, err := tx.Exec(`UPDATE products SET is_active = false, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1`, id); err != nil {
+		return err
+	}
+	after := *before
+	after.IsActive = false
+	if err := insertAudit(tx, newAuditEntry(models.AuditDelete, id, actor, models.DiffFields(before, &after))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreProduct brings a soft-deleted product back
+func (s *PostgresStore) RestoreProduct(id string, version int) error {
+	return s.restoreProduct(id, version, "")
+}
+
+func (s *PostgresStore) restoreProduct(id string, version int, actor string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockProduct(tx, id)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != before.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, before.Version, version)
+	}
+	if before.IsActive {
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE products SET is_active = true, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1`, id); err != nil {
+		return err
+	}
+	after := *before
+	after.IsActive = true
+	if err := insertAudit(tx, newAuditEntry(models.AuditRestore, id, actor, models.DiffFields(before, &after))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeProduct deletes an archived product for good. Aliases, barcodes
+// and supplier SKUs go with it (ON DELETE CASCADE); the stock row has no
+// foreign key, so it is deleted here.
+func (s *PostgresStore) PurgeProduct(id string, version int) error {
+	return s.purgeProduct(id, version, "")
+}
+
+func (s *PostgresStore) purgeProduct(id string, version int, actor string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockProduct(tx, id)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != before.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, id, before.Version, version)
+	}
+	if before.IsActive {
+		return fmt.Errorf("%w: %s", ErrProductActive, id)
+	}
+	var boxes, units int
+	err = tx.QueryRow(`SELECT quantity_boxes, quantity_units FROM stocks WHERE product_id=$1 FOR UPDATE`, id).Scan(&boxes, &units)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if boxes != 0 || units != 0 {
+		return fmt.Errorf("%w: %s has %d boxes and %d units in stock", ErrProductInUse, id, boxes, units)
+	}
+	var movements int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM stock_movements WHERE product_id=$1`, id).Scan(&movements); err != nil {
+		return err
+	}
+	if movements > 0 {
+		return fmt.Errorf("%w: %d movements refer to %s", ErrProductInUse, movements, id)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM stocks WHERE product_id=$1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM products WHERE id=$1`, id); err != nil {
+		return err
+	}
+	if err := insertAudit(tx, newAuditEntry(models.AuditPurge, id, actor, purgeChanges(before))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeProducts folds the duplicate fromID into intoID, in one transaction
+func (s *PostgresStore) MergeProducts(fromID, intoID string) (*MergeResult, error) {
+	return s.mergeProducts(fromID, intoID, "")
+}
+
+func (s *PostgresStore) mergeProducts(fromID, intoID, actor string) (*MergeResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock both products in ID order, so two merges can't deadlock
+	locked := map[string]*models.Product{}
+	ids := []string{fromID, intoID}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if locked[id], err = lockProduct(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	from, into := locked[fromID], locked[intoID]
+	if err := checkMerge(from, into); err != nil {
+		return nil, err
+	}
+	result := &MergeResult{}
+
+	// Stock
+	if _, err := tx.Exec(`INSERT INTO stocks (product_id) VALUES ($1) ON CONFLICT (product_id) DO NOTHING`, intoID); err != nil {
+		return nil, err
+	}
+	stocks := map[string]*models.Stock{}
+	rows, err := tx.Query(`SELECT `+stockColumns+` FROM stocks WHERE product_id IN ($1, $2) ORDER BY product_id FOR UPDATE`, fromID, intoID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		st, err := scanStock(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stocks[st.ProductID] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	before := stocks[intoID]
+	boxes, units := mergeStock(before, stocks[fromID])
+	result.Stock, err = scanStock(tx.QueryRow(`UPDATE stocks SET quantity_boxes=$2, quantity_units=$3, version=version+1, last_updated=CURRENT_TIMESTAMP WHERE product_id=$1 RETURNING `+stockColumns, intoID, boxes, units))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE stocks SET quantity_boxes=0, quantity_units=0, version=version+1, last_updated=CURRENT_TIMESTAMP WHERE product_id=$1`, fromID); err != nil {
+		return nil, err
+	}
+
+	// Everything that points at the duplicate. Aliases the product that
+	// stays already has would break the unique index, so they go first.
+	if _, err := tx.Exec(`DELETE FROM product_aliases a WHERE a.product_id=$1 AND EXISTS (SELECT 1 FROM product_aliases b WHERE b.product_id=$2 AND b.normalized=a.normalized)`, fromID, intoID); err != nil {
+		return nil, err
+	}
+	moves := []struct {
+		query string
+		count *int
+	}{
+		{`UPDATE stock_movements SET product_id=$2 WHERE product_id=$1`, &result.Movements},
+		{`UPDATE product_aliases SET product_id=$2 WHERE product_id=$1`, &result.Aliases},
+		{`UPDATE product_barcodes SET product_id=$2 WHERE product_id=$1`, &result.Barcodes},
+		{`UPDATE supplier_skus SET product_id=$2 WHERE product_id=$1`, &result.SupplierSKUs},
+	}
+	for _, m := range moves {
+		res, err := tx.Exec(m.query, fromID, intoID)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		*m.count = int(n)
+	}
+
+	// Archive the duplicate
+	if _, err := tx.Exec(`UPDATE products SET is_active = false, version=version+1, updated_at=CURRENT_TIMESTAMP WHERE id=$1`, fromID); err != nil {
+		return nil, err
+	}
+	for _, e := range mergeAuditEntries(from, into, before, result.Stock, actor) {
+		if err := insertAudit(tx, e); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	result.Product = into
+	return result, nil
 }
 
 // GetStock retrieves stock for a product
 func (s *PostgresStore) GetStock(productID string) (*models.Stock, error) {
-	row := s.db.QueryRow(`SELECT product_id, quantity_boxes, quantity_units, min_stock, last_updated FROM stocks WHERE product_id=$1`, productID)
-	var st models.Stock
-	if err := row.Scan(&st.ProductID, &st.QuantityBoxes, &st.QuantityUnits, &st.MinStock, &st.LastUpdated); err != nil {
+	st, err := scanStock(s.db.QueryRow(`SELECT `+stockColumns+` FROM stocks WHERE product_id=$1`, productID))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("%w: %s", ErrStockNotFound, productID)
 		}
 		return nil, err
 	}
+	return st, nil
+}
+
+// stockColumns is the SELECT list matching scanStock
+const stockColumns = `product_id, quantity_boxes, quantity_units, min_stock, last_updated, version`
+
+// scanStock reads one row selected with stockColumns
+func scanStock(row interface{ Scan(...interface{}) error }) (*models.Stock, error) {
+	var st models.Stock
+	if err := row.Scan(&st.ProductID, &st.QuantityBoxes, &st.QuantityUnits, &st.MinStock, &st.LastUpdated, &st.Version); err != nil {
+		return nil, err
+	}
 	return &st, nil
 }
 
+// ListStockChangedSince returns stock rows changed at or after since,
+// oldest change first
+func (s *PostgresStore) ListStockChangedSince(since time.Time) ([]*models.Stock, error) {
+	query := `SELECT ` + stockColumns + ` FROM stocks WHERE 1=1`
+	var args []interface{}
+	if !since.IsZero() {
+		args = append(args, since)
+		query += fmt.Sprintf(" AND last_updated >= $%d", len(args))
+	}
+	query += " ORDER BY last_updated, product_id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Stock{}
+	for rows.Next() {
+		st, err := scanStock(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, st)
+	}
+	return res, rows.Err()
+}
+
 // UpdateStock adjusts stock (boxes and units can be negative)
 func (s *PostgresStore) UpdateStock(productID string, boxes, units int) error {
 	tx, err := s.db.Begin()
@@ -162,7 +569,7 @@ func (s *PostgresStore) UpdateStock(productID string, boxes, units int) error {
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	_, err = tx.Exec(`UPDATE stocks SET quantity_boxes = quantity_boxes + $1, quantity_units = quantity_units + $2, last_updated = CURRENT_TIMESTAMP WHERE product_id = $3`, boxes, units, productID)
+	_, err = tx.Exec(`UPDATE stocks SET quantity_boxes = quantity_boxes + $1, quantity_units = quantity_units + $2, version = version + 1, last_updated = CURRENT_TIMESTAMP WHERE product_id = $3`, boxes, units, productID)
 	if err != nil {
 		return err
 	}
@@ -179,28 +586,1298 @@ func (s *PostgresStore) UpdateStock(productID string, boxes, units int) error {
 }
 
 // SetMinStock sets minimum stock threshold
-func (s *PostgresStore) SetMinStock(productID string, minStock int) error {
-	_, err := s.db.Exec(`UPDATE stocks SET min_stock=$1 WHERE product_id=$2`, minStock, productID)
-	return err
+func (s *PostgresStore) SetMinStock(productID string, minStock, version int) error {
+	return s.setMinStock(productID, minStock, version, "")
+}
+
+func (s *PostgresStore) setMinStock(productID string, minStock, version int, actor string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var before, current int
+	if err := tx.QueryRow(`SELECT min_stock, version FROM stocks WHERE product_id=$1 FOR UPDATE`, productID).Scan(&before, &current); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrStockNotFound, productID)
+		}
+		return err
+	}
+	if version != 0 && version != current {
+		return fmt.Errorf("%w: stock of %s is at version %d, not %d", ErrVersionConflict, productID, current, version)
+	}
+	if before == minStock {
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE stocks SET min_stock=$1, version=version+1, last_updated=CURRENT_TIMESTAMP WHERE product_id=$2`, minStock, productID); err != nil {
+		return err
+	}
+	entry := newAuditEntry(models.AuditMinStock, productID, actor,
+		[]models.FieldChange{{Field: "MinStock", Before: before, After: minStock}})
+	if err := insertAudit(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // GetLowStockProducts returns active products below their min stock
 func (s *PostgresStore) GetLowStockProducts() []*models.Product {
-	rows, err := s.db.Query(`SELECT p.id, p.name, p.brand, p.size, p.container_type, p.box_size, p.price, p.category, p.is_active FROM products p JOIN stocks s ON p.id = s.product_id WHERE (s.quantity_boxes * COALESCE(p.box_size,0) + s.quantity_units) < s.min_stock AND p.is_active = true`)
+	rows, err := s.db.Query(`SELECT p.id, p.name, p.brand, p.size, p.container_type, p.box_size, p.price, p.category, p.is_active, p.version, COALESCE(p.updated_at, p.created_at) FROM products p JOIN stocks s ON p.id = s.product_id WHERE (s.quantity_boxes * COALESCE(p.box_size,0) + s.quantity_units) < s.min_stock AND p.is_active = true`)
 	if err != nil {
 		return []*models.Product{}
 	}
 	defer rows.Close()
 	var res []*models.Product
 	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Brand, &p.Size, &p.ContainerType, &p.BoxSize, &p.Price, &p.Category, &p.IsActive); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			continue
 		}
-		res = append(res, &p)
+		res = append(res, p)
 	}
 	return res
 }
 
+// genMovementID creates a unique movement ID from the current time
+func genMovementID() string {
+	return fmt.Sprintf("MOV-%d", time.Now().UnixNano())
+}
+
+// RecordMovement applies a movement to stock and logs it in one transaction
+func (s *PostgresStore) RecordMovement(m *models.StockMovement) (string, error) {
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	if m.ID == "" {
+		m.ID = genMovementID()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	if m.Source == "" {
+		m.Source = models.SourceManual
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the stock row so concurrent movements are applied one after
+	// another, and the product row so it can't be archived meanwhile
+	var qb, qu int
+	var active bool
+	err = tx.QueryRow(`SELECT s.quantity_boxes, s.quantity_units, p.is_active FROM stocks s JOIN products p ON p.id = s.product_id WHERE s.product_id=$1 FOR UPDATE`, m.ProductID).Scan(&qb, &qu, &active)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("%w: %s", ErrStockNotFound, m.ProductID)
+		}
+		return "", err
+	}
+	if !active {
+		return "", fmt.Errorf("%w: %s", ErrProductInactive, m.ProductID)
+	}
+
+	boxes, units := m.StockDelta()
+	qb += boxes
+	qu += units
+	if qb < 0 || qu < 0 {
+		return "", fmt.Errorf("%w: would result in %d boxes, %d units", ErrInsufficientStock, qb, qu)
+	}
+
+	_, err = tx.Exec(`UPDATE stocks SET quantity_boxes=$1, quantity_units=$2, version=version+1, last_updated=CURRENT_TIMESTAMP WHERE product_id=$3`, qb, qu, m.ProductID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`INSERT INTO stock_movements (id, product_id, type, boxes, units, performed_by, reported_by, reason, source, created_at, performed_by_id, reported_by_id, client_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NULLIF($11,''),NULLIF($12,''),NULLIF($13,''))`, m.ID, m.ProductID, m.Type, m.Boxes, m.Units, m.PerformedBy, m.ReportedBy, m.Reason, m.Source, m.CreatedAt, m.PerformedByID, m.ReportedByID, m.ClientID)
+	if err != nil {
+		if strings.Contains(err.Error(), "idx_movements_client_id") {
+			return "", fmt.Errorf("%w: client ID %s", ErrMovementExists, m.ClientID)
+		}
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return m.ID, nil
+}
+
+// ListMovements returns movements matching the filter, oldest first
+func (s *PostgresStore) ListMovements(filter MovementFilter) []*models.StockMovement {
+	query := `SELECT ` + movementColumns + ` FROM stock_movements WHERE 1=1`
+	var args []interface{}
+	if filter.ProductID != "" {
+		args = append(args, filter.ProductID)
+		query += fmt.Sprintf(" AND product_id=$%d", len(args))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(" AND type=$%d", len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	if filter.StaffID != "" {
+		args = append(args, filter.StaffID)
+		query += fmt.Sprintf(" AND (performed_by_id=$%d OR reported_by_id=$%d)", len(args), len(args))
+	}
+	query += " ORDER BY created_at, id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return []*models.StockMovement{}
+	}
+	defer rows.Close()
+	res := []*models.StockMovement{}
+	for rows.Next() {
+		m, err := scanMovement(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// movementColumns is the SELECT list matching scanMovement
+const movementColumns = `id, product_id, type, boxes, units, performed_by, reported_by, COALESCE(reason,''), source, created_at, COALESCE(performed_by_id,''), COALESCE(reported_by_id,''), COALESCE(client_id,'')`
+
+// scanMovement reads one row selected with movementColumns
+func scanMovement(row interface{ Scan(...interface{}) error }) (*models.StockMovement, error) {
+	var m models.StockMovement
+	if err := row.Scan(&m.ID, &m.ProductID, &m.Type, &m.Boxes, &m.Units, &m.PerformedBy, &m.ReportedBy, &m.Reason, &m.Source, &m.CreatedAt, &m.PerformedByID, &m.ReportedByID, &m.ClientID); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMovementByClientID finds a movement by the ID its offline client made up
+func (s *PostgresStore) GetMovementByClientID(clientID string) (*models.StockMovement, error) {
+	m, err := scanMovement(s.db.QueryRow(`SELECT `+movementColumns+` FROM stock_movements WHERE client_id=$1`, clientID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: client ID %s", ErrMovementNotFound, clientID)
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// genAliasID creates a unique alias ID from the current time
+func genAliasID() string {
+	return fmt.Sprintf("ALS-%d", time.Now().UnixNano())
+}
+
+// AddAlias adds an alias to a product
+// The same alias (after normalization) can't be added twice to one product
+func (s *PostgresStore) AddAlias(productID, alias string) (*models.ProductAlias, error) {
+	a := &models.ProductAlias{ProductID: productID, Alias: strings.TrimSpace(alias)}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+
+	a.ID = genAliasID()
+	err := s.db.QueryRow(`INSERT INTO product_aliases (id, product_id, alias, normalized) VALUES ($1,$2,$3,$4) ON CONFLICT (product_id, normalized) DO NOTHING RETURNING created_at`,
+		a.ID, a.ProductID, a.Alias, NormalizeSearchText(a.Alias)).Scan(&a.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrAliasExists, a.Alias)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListAliases returns a product's aliases, oldest first
+func (s *PostgresStore) ListAliases(productID string) ([]*models.ProductAlias, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT id, product_id, alias, created_at FROM product_aliases WHERE product_id=$1 ORDER BY created_at, id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ProductAlias{}
+	for rows.Next() {
+		var a models.ProductAlias
+		if err := rows.Scan(&a.ID, &a.ProductID, &a.Alias, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &a)
+	}
+	return res, rows.Err()
+}
+
+// DeleteAlias removes one alias from a product
+func (s *PostgresStore) DeleteAlias(productID, aliasID string) error {
+	res, err := s.db.Exec(`DELETE FROM product_aliases WHERE id=$1 AND product_id=$2`, aliasID, productID)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrAliasNotFound, aliasID)
+	}
+	return nil
+}
+
+// AddBarcode validates and stores a barcode for a product
+// Case barcodes need a product that comes in boxes
+func (s *PostgresStore) AddBarcode(productID, code, packLevel string) (*models.ProductBarcode, error) {
+	b := &models.ProductBarcode{Code: code, ProductID: productID, PackLevel: packLevel}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	b.Code, _ = models.NormalizeBarcode(code)
+
+	product, err := s.GetProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	if packLevel == models.PackCase && product.BoxSize == 0 {
+		return nil, models.ErrBarcodeCaseNeedsBox
+	}
+
+	err = s.db.QueryRow(`INSERT INTO product_barcodes (code, product_id, pack_level) VALUES ($1,$2,$3) ON CONFLICT (code) DO NOTHING RETURNING created_at`,
+		b.Code, b.ProductID, b.PackLevel).Scan(&b.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrBarcodeExists, b.Code)
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListBarcodes returns a product's barcodes, oldest first
+func (s *PostgresStore) ListBarcodes(productID string) ([]*models.ProductBarcode, error) {
+	if _, err := s.GetProduct(productID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT code, product_id, pack_level, created_at FROM product_barcodes WHERE product_id=$1 ORDER BY created_at, code`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ProductBarcode{}
+	for rows.Next() {
+		var b models.ProductBarcode
+		if err := rows.Scan(&b.Code, &b.ProductID, &b.PackLevel, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &b)
+	}
+	return res, rows.Err()
+}
+
+// DeleteBarcode removes one barcode from a product
+func (s *PostgresStore) DeleteBarcode(productID, code string) error {
+	normalized, err := models.NormalizeBarcode(code)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`DELETE FROM product_barcodes WHERE code=$1 AND product_id=$2`, normalized, productID)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrBarcodeNotFound, normalized)
+	}
+	return nil
+}
+
+// GetProductByBarcode finds the product and pack level for a scanned code
+func (s *PostgresStore) GetProductByBarcode(code string) (*models.Product, *models.ProductBarcode, error) {
+	normalized, err := models.NormalizeBarcode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	var b models.ProductBarcode
+	err = s.db.QueryRow(`SELECT code, product_id, pack_level, created_at FROM product_barcodes WHERE code=$1`, normalized).
+		Scan(&b.Code, &b.ProductID, &b.PackLevel, &b.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("%w: %s", ErrBarcodeNotFound, normalized)
+		}
+		return nil, nil, err
+	}
+	product, err := s.GetProduct(b.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, &b, nil
+}
+
+// SetSupplierSKU creates or replaces the mapping for (supplier, SKU)
+func (s *PostgresStore) SetSupplierSKU(m *models.SupplierSKU) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.GetProduct(m.ProductID); err != nil {
+		return err
+	}
+	m.UpdatedAt = time.Now()
+	_, err := s.db.Exec(`INSERT INTO supplier_skus (supplier, sku, product_id, pack_level, updated_at) VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (supplier, sku) DO UPDATE SET product_id=EXCLUDED.product_id, pack_level=EXCLUDED.pack_level, updated_at=EXCLUDED.updated_at`,
+		m.Supplier, m.SKU, m.ProductID, m.PackLevel, m.UpdatedAt)
+	return err
+}
+
+// GetSupplierSKU returns the mapping for a supplier's item code
+func (s *PostgresStore) GetSupplierSKU(supplier, sku string) (*models.SupplierSKU, error) {
+	var m models.SupplierSKU
+	err := s.db.QueryRow(`SELECT supplier, sku, product_id, pack_level, updated_at FROM supplier_skus WHERE supplier=$1 AND sku=$2`, supplier, sku).
+		Scan(&m.Supplier, &m.SKU, &m.ProductID, &m.PackLevel, &m.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s %s", ErrSupplierSKUNotFound, supplier, sku)
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// genUserID creates a unique user ID from the current time
+func genUserID() string {
+	return fmt.Sprintf("USR-%d", time.Now().UnixNano())
+}
+
+// userColumns is the SELECT list matching scanUser
+const userColumns = `id, username, display_name, password_hash, role, is_active, failed_logins, locked_until, language, created_at, updated_at`
+
+// scanUser reads one row selected with userColumns
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
+	var u models.User
+	var locked sql.NullTime
+	if err := row.Scan(&u.ID, &u.Username, &u.DisplayName, &u.PasswordHash, &u.Role, &u.IsActive, &u.FailedLogins, &locked, &u.Language, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	u.LockedUntil = locked.Time
+	return &u, nil
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// CreateUser stores a new user, returns generated ID
+func (s *PostgresStore) CreateUser(u *models.User) (string, error) {
+	u.Username = models.NormalizeUsername(u.Username)
+	if err := u.Validate(); err != nil {
+		return "", err
+	}
+	if u.ID == "" {
+		u.ID = genUserID()
+	}
+	now := time.Now()
+	res, err := s.db.Exec(`INSERT INTO users (id, username, display_name, password_hash, role, is_active, failed_logins, locked_until, language, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10) ON CONFLICT (username) DO NOTHING`,
+		u.ID, u.Username, u.DisplayName, u.PasswordHash, u.Role, u.IsActive, u.FailedLogins, nullTime(u.LockedUntil), u.Language, now)
+	if err != nil {
+		return "", err
+	}
+	if cnt, err := res.RowsAffected(); err != nil {
+		return "", err
+	} else if cnt == 0 {
+		return "", fmt.Errorf("%w: %s", ErrUserExists, u.Username)
+	}
+	u.CreatedAt, u.UpdatedAt = now, now
+	return u.ID, nil
+}
+
+// GetUser retrieves a user by ID
+func (s *PostgresStore) GetUser(id string) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	return u, err
+}
+
+// GetUserByUsername retrieves a user by login name (case-insensitive)
+func (s *PostgresStore) GetUserByUsername(username string) (*models.User, error) {
+	username = models.NormalizeUsername(username)
+	u, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username=$1`, username))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	return u, err
+}
+
+// UpdateUser saves all fields of an existing user
+func (s *PostgresStore) UpdateUser(u *models.User) error {
+	u.Username = models.NormalizeUsername(u.Username)
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`UPDATE users SET username=$2, display_name=$3, password_hash=$4, role=$5, is_active=$6, failed_logins=$7, locked_until=$8, language=$9, updated_at=CURRENT_TIMESTAMP WHERE id=$1`,
+		u.ID, u.Username, u.DisplayName, u.PasswordHash, u.Role, u.IsActive, u.FailedLogins, nullTime(u.LockedUntil), u.Language)
+	if err != nil {
+		if strings.Contains(err.Error(), "users_username_key") {
+			return fmt.Errorf("%w: %s", ErrUserExists, u.Username)
+		}
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, u.ID)
+	}
+	return nil
+}
+
+// ListUsers returns all users ordered by username
+func (s *PostgresStore) ListUsers() []*models.User {
+	rows, err := s.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
+	if err != nil {
+		return []*models.User{}
+	}
+	defer rows.Close()
+	res := []*models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, u)
+	}
+	return res
+}
+
+// CountUsers returns the number of users
+func (s *PostgresStore) CountUsers() int {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		return 0
+	}
+	return n
+}
+
+// CreateSession stores a new session
+func (s *PostgresStore) CreateSession(sess *models.Session) error {
+	if sess.CreatedAt.IsZero() {
+		sess.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`INSERT INTO sessions (id, user_id, refresh_hash, expires_at, revoked_at, created_at) VALUES ($1,$2,$3,$4,$5,$6)`,
+		sess.ID, sess.UserID, sess.RefreshHash, sess.ExpiresAt, nullTime(sess.RevokedAt), sess.CreatedAt)
+	return err
+}
+
+// GetSession retrieves a session by ID
+func (s *PostgresStore) GetSession(id string) (*models.Session, error) {
+	var sess models.Session
+	var revoked sql.NullTime
+	err := s.db.QueryRow(`SELECT id, user_id, refresh_hash, expires_at, revoked_at, created_at FROM sessions WHERE id=$1`, id).
+		Scan(&sess.ID, &sess.UserID, &sess.RefreshHash, &sess.ExpiresAt, &revoked, &sess.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+		}
+		return nil, err
+	}
+	sess.RevokedAt = revoked.Time
+	return &sess, nil
+}
+
+// UpdateSession saves the refresh hash, expiry and revocation
+func (s *PostgresStore) UpdateSession(sess *models.Session) error {
+	res, err := s.db.Exec(`UPDATE sessions SET refresh_hash=$2, expires_at=$3, revoked_at=$4 WHERE id=$1`,
+		sess.ID, sess.RefreshHash, sess.ExpiresAt, nullTime(sess.RevokedAt))
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sess.ID)
+	}
+	return nil
+}
+
+// apiKeyColumns is the SELECT list matching scanAPIKey
+const apiKeyColumns = `id, name, key_hash, scopes, COALESCE(created_by,''), created_at, last_used_at, revoked_at`
+
+// scanAPIKey reads one row selected with apiKeyColumns
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var k models.APIKey
+	var scopes string
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.KeyHash, &scopes, &k.CreatedBy, &k.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.LastUsedAt, k.RevokedAt = lastUsed.Time, revoked.Time
+	return &k, nil
+}
+
+// CreateAPIKey stores a new key (the ID is set by the caller)
+func (s *PostgresStore) CreateAPIKey(k *models.APIKey) error {
+	k.Name = strings.TrimSpace(k.Name)
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`INSERT INTO api_keys (id, name, key_hash, scopes, created_by, created_at, last_used_at, revoked_at) VALUES ($1,$2,$3,$4,NULLIF($5,''),$6,$7,$8)`,
+		k.ID, k.Name, k.KeyHash, strings.Join(k.Scopes, " "), k.CreatedBy, k.CreatedAt, nullTime(k.LastUsedAt), nullTime(k.RevokedAt))
+	if err != nil && strings.Contains(err.Error(), "api_keys_created_by_fkey") {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, k.CreatedBy)
+	}
+	return err
+}
+
+// GetAPIKey retrieves a key by ID
+func (s *PostgresStore) GetAPIKey(id string) (*models.APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id=$1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+// UpdateAPIKey saves the revocation
+func (s *PostgresStore) UpdateAPIKey(k *models.APIKey) error {
+	res, err := s.db.Exec(`UPDATE api_keys SET revoked_at=$2 WHERE id=$1`, k.ID, nullTime(k.RevokedAt))
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, k.ID)
+	}
+	return nil
+}
+
+// TouchAPIKey sets when an active key was last used. Only this column is
+// written, and only while the key is active, so a revoke that lands
+// after the key was read stays in place.
+func (s *PostgresStore) TouchAPIKey(id string, t time.Time) error {
+	res, err := s.db.Exec(`UPDATE api_keys SET last_used_at=$2 WHERE id=$1 AND revoked_at IS NULL`, id, t)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+	return nil
+}
+
+// ListAPIKeys returns all keys (active and revoked), newest first
+func (s *PostgresStore) ListAPIKeys() []*models.APIKey {
+	rows, err := s.db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return []*models.APIKey{}
+	}
+	defer rows.Close()
+	res := []*models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, k)
+	}
+	return res
+}
+
+// WithActor returns the store, recording actor on audited changes
+func (s *PostgresStore) WithActor(actor string) Repository {
+	return &actorStore{Repository: s, writer: s, actor: actor}
+}
+
+// genAuditID creates a unique audit entry ID. A change can write several
+// entries back to back (a merge writes two), faster than the clock ticks
+// on some platforms, so the time gets a random suffix.
+func genAuditID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("AUD-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("AUD-%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
+}
+
+// insertAudit writes an audit entry inside the transaction of the change
+func insertAudit(tx *sql.Tx, e *models.AuditEntry) error {
+	if e == nil {
+		return nil
+	}
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	e.ID = genAuditID()
+	_, err = tx.Exec(`INSERT INTO audit_log (id, entity, entity_id, action, actor, changes, created_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		e.ID, e.Entity, e.EntityID, e.Action, e.Actor, string(changes), e.CreatedAt)
+	return err
+}
+
+// auditColumns is the SELECT list matching scanAudit
+const auditColumns = `id, entity, entity_id, action, actor, changes, created_at`
+
+// scanAudit reads one row selected with auditColumns
+func scanAudit(row interface{ Scan(...interface{}) error }) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var changes []byte
+	if err := row.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.Actor, &changes, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &e.Changes); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListAudit returns matching entries, newest first
+func (s *PostgresStore) ListAudit(filter AuditFilter) []*models.AuditEntry {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE 1=1`
+	var args []interface{}
+	if filter.Entity != "" {
+		args = append(args, filter.Entity)
+		query += fmt.Sprintf(" AND entity=$%d", len(args))
+	}
+	if filter.EntityID != "" {
+		args = append(args, filter.EntityID)
+		query += fmt.Sprintf(" AND entity_id=$%d", len(args))
+	}
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		query += fmt.Sprintf(" AND actor=$%d", len(args))
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return []*models.AuditEntry{}
+	}
+	defer rows.Close()
+	res := []*models.AuditEntry{}
+	for rows.Next() {
+		e, err := scanAudit(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// genStaffID creates a unique staff ID from the current time
+func genStaffID() string {
+	return fmt.Sprintf("STF-%d", time.Now().UnixNano())
+}
+
+// staffColumns is the SELECT list matching scanStaff
+const staffColumns = `id, name_he, name_en, COALESCE(user_id,''), is_active, created_at, updated_at`
+
+// scanStaff reads one row selected with staffColumns
+func scanStaff(row interface{ Scan(...interface{}) error }) (*models.Staff, error) {
+	var st models.Staff
+	if err := row.Scan(&st.ID, &st.NameHe, &st.NameEn, &st.UserID, &st.IsActive, &st.CreatedAt, &st.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// staffError maps constraint violations on the staff table
+func staffError(err error, st *models.Staff) error {
+	switch {
+	case strings.Contains(err.Error(), "staff_user_id_key"):
+		return fmt.Errorf("%w: %s", ErrStaffUserLinked, st.UserID)
+	case strings.Contains(err.Error(), "staff_user_id_fkey"):
+		return fmt.Errorf("%w: %s", ErrUserNotFound, st.UserID)
+	}
+	return err
+}
+
+// AddStaff stores a new staff member, returns generated ID
+func (s *PostgresStore) AddStaff(st *models.Staff) (string, error) {
+	st.NameHe = strings.TrimSpace(st.NameHe)
+	st.NameEn = strings.TrimSpace(st.NameEn)
+	if err := st.Validate(); err != nil {
+		return "", err
+	}
+	if st.ID == "" {
+		st.ID = genStaffID()
+	}
+	now := time.Now()
+	_, err := s.db.Exec(`INSERT INTO staff (id, name_he, name_en, user_id, is_active, created_at, updated_at) VALUES ($1,$2,$3,NULLIF($4,''),$5,$6,$6)`,
+		st.ID, st.NameHe, st.NameEn, st.UserID, st.IsActive, now)
+	if err != nil {
+		return "", staffError(err, st)
+	}
+	st.CreatedAt, st.UpdatedAt = now, now
+	return st.ID, nil
+}
+
+// GetStaff retrieves a staff member by ID
+func (s *PostgresStore) GetStaff(id string) (*models.Staff, error) {
+	st, err := scanStaff(s.db.QueryRow(`SELECT `+staffColumns+` FROM staff WHERE id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrStaffNotFound, id)
+	}
+	return st, err
+}
+
+// GetStaffByUser retrieves the staff member linked to a login account
+func (s *PostgresStore) GetStaffByUser(userID string) (*models.Staff, error) {
+	st, err := scanStaff(s.db.QueryRow(`SELECT `+staffColumns+` FROM staff WHERE user_id=$1`, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: user %s", ErrStaffNotFound, userID)
+	}
+	return st, err
+}
+
+// UpdateStaff saves names, login link and active flag
+func (s *PostgresStore) UpdateStaff(st *models.Staff) error {
+	st.NameHe = strings.TrimSpace(st.NameHe)
+	st.NameEn = strings.TrimSpace(st.NameEn)
+	if err := st.Validate(); err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`UPDATE staff SET name_he=$2, name_en=$3, user_id=NULLIF($4,''), is_active=$5, updated_at=CURRENT_TIMESTAMP WHERE id=$1`,
+		st.ID, st.NameHe, st.NameEn, st.UserID, st.IsActive)
+	if err != nil {
+		return staffError(err, st)
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrStaffNotFound, st.ID)
+	}
+	return nil
+}
+
+// ListStaff returns all staff members ordered by ID
+func (s *PostgresStore) ListStaff() []*models.Staff {
+	rows, err := s.db.Query(`SELECT ` + staffColumns + ` FROM staff ORDER BY id`)
+	if err != nil {
+		return []*models.Staff{}
+	}
+	defer rows.Close()
+	res := []*models.Staff{}
+	for rows.Next() {
+		st, err := scanStaff(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, st)
+	}
+	return res
+}
+
+// SetMovementStaff links an already logged movement to staff members
+func (s *PostgresStore) SetMovementStaff(movementID, performedByID, reportedByID string) error {
+	res, err := s.db.Exec(`UPDATE stock_movements SET performed_by_id=NULLIF($2,''), reported_by_id=NULLIF($3,'') WHERE id=$1`,
+		movementID, performedByID, reportedByID)
+	if err != nil {
+		if strings.Contains(err.Error(), "_by_id_fkey") {
+			return fmt.Errorf("%w: %s / %s", ErrStaffNotFound, performedByID, reportedByID)
+		}
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrMovementNotFound, movementID)
+	}
+	return nil
+}
+
+// ReserveIdempotencyKey claims a key, or returns the live record holding it.
+// The insert takes over an expired record in the same statement, so two
+// retries racing for one key can't both win.
+func (s *PostgresStore) ReserveIdempotencyKey(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	headers, err := json.Marshal(rec.Headers)
+	if err != nil {
+		return nil, err
+	}
+	// The holder may release its key between our insert and select: try again once
+	for attempt := 0; attempt < 2; attempt++ {
+		res, err := s.db.Exec(`INSERT INTO idempotency_keys (scope, idem_key, request_hash, status, headers, body, created_at, expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+			ON CONFLICT (scope, idem_key) DO UPDATE SET request_hash=EXCLUDED.request_hash, status=EXCLUDED.status, headers=EXCLUDED.headers, body=EXCLUDED.body, created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= EXCLUDED.created_at`,
+			rec.Scope, rec.Key, rec.RequestHash, rec.Status, string(headers), rec.Body, rec.CreatedAt, rec.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		cnt, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if cnt == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyRecord
+		var stored []byte
+		err = s.db.QueryRow(`SELECT scope, idem_key, request_hash, status, headers, COALESCE(body,''), created_at, expires_at FROM idempotency_keys WHERE scope=$1 AND idem_key=$2`,
+			rec.Scope, rec.Key).Scan(&existing.Scope, &existing.Key, &existing.RequestHash, &existing.Status, &stored, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(stored, &existing.Headers); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyNotFound, rec.Key)
+}
+
+// SaveIdempotentResponse stores the response of a reserved key
+func (s *PostgresStore) SaveIdempotentResponse(rec *models.IdempotencyRecord) error {
+	headers, err := json.Marshal(rec.Headers)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`UPDATE idempotency_keys SET status=$3, headers=$4, body=$5 WHERE scope=$1 AND idem_key=$2`,
+		rec.Scope, rec.Key, rec.Status, string(headers), rec.Body)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyNotFound, rec.Key)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a reserved key
+func (s *PostgresStore) ReleaseIdempotencyKey(scope, key string) error {
+	_, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE scope=$1 AND idem_key=$2`, scope, key)
+	return err
+}
+
+// PurgeIdempotencyKeys deletes records expired at now
+func (s *PostgresStore) PurgeIdempotencyKeys(now time.Time) int {
+	res, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return int(cnt)
+}
+
+// categoryColumns are the columns scanCategory reads, in order
+const categoryColumns = `id, name_he, name_en, sort_order, COALESCE(parent_id,''), default_min_stock, created_at, updated_at`
+
+// scanCategory reads one row selected with categoryColumns
+func scanCategory(row interface{ Scan(...interface{}) error }) (*models.Category, error) {
+	var c models.Category
+	if err := row.Scan(&c.ID, &c.NameHe, &c.NameEn, &c.SortOrder, &c.ParentID, &c.DefaultMinStock, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// lockCategories reads and locks all categories (a short list), so
+// parents can be checked for loops without another change slipping in
+func lockCategories(tx *sql.Tx) (map[string]*models.Category, error) {
+	categories := map[string]*models.Category{}
+	err := queryEach(tx, `SELECT `+categoryColumns+` FROM categories FOR UPDATE`, func(rows *sql.Rows) error {
+		c, err := scanCategory(rows)
+		if err == nil {
+			categories[c.ID] = c
+		}
+		return err
+	})
+	return categories, err
+}
+
+// HasCategory reports whether a category exists
+func (s *PostgresStore) HasCategory(id string) bool {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM categories WHERE id=$1)`, id).Scan(&exists)
+	return err == nil && exists
+}
+
+// GetCategory retrieves a category by ID
+func (s *PostgresStore) GetCategory(id string) (*models.Category, error) {
+	c, err := scanCategory(s.db.QueryRow(`SELECT `+categoryColumns+` FROM categories WHERE id=$1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+	return c, err
+}
+
+// ListCategories returns all categories by sort order, then ID
+func (s *PostgresStore) ListCategories() []*models.Category {
+	rows, err := s.db.Query(`SELECT ` + categoryColumns + ` FROM categories ORDER BY sort_order, id COLLATE "C"`)
+	if err != nil {
+		return []*models.Category{}
+	}
+	defer rows.Close()
+	res := []*models.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			continue
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// AddCategory stores a new category under its own ID
+func (s *PostgresStore) AddCategory(c *models.Category) error {
+	return s.saveCategory(c, true)
+}
+
+// UpdateCategory saves names, sort order, parent and default min stock
+func (s *PostgresStore) UpdateCategory(c *models.Category) error {
+	return s.saveCategory(c, false)
+}
+
+// saveCategory inserts (create) or updates a category after checking its parent
+func (s *PostgresStore) saveCategory(c *models.Category, create bool) error {
+	c.NameHe = strings.TrimSpace(c.NameHe)
+	c.NameEn = strings.TrimSpace(c.NameEn)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	categories, err := lockCategories(tx)
+	if err != nil {
+		return err
+	}
+	existing := categories[c.ID]
+	switch {
+	case create && existing != nil:
+		return fmt.Errorf("%w: %s", ErrCategoryExists, c.ID)
+	case !create && existing == nil:
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, c.ID)
+	}
+	if err := checkCategoryParent(c, func(id string) *models.Category {
+		if id == c.ID {
+			return c
+		}
+		return categories[id]
+	}); err != nil {
+		return err
+	}
+
+	if create {
+		err = tx.QueryRow(`INSERT INTO categories (id, name_he, name_en, sort_order, parent_id, default_min_stock) VALUES ($1,$2,$3,$4,NULLIF($5,''),$6) RETURNING created_at, updated_at`,
+			c.ID, c.NameHe, c.NameEn, c.SortOrder, c.ParentID, c.DefaultMinStock).Scan(&c.CreatedAt, &c.UpdatedAt)
+	} else {
+		err = tx.QueryRow(`UPDATE categories SET name_he=$2, name_en=$3, sort_order=$4, parent_id=NULLIF($5,''), default_min_stock=$6, updated_at=CURRENT_TIMESTAMP WHERE id=$1 RETURNING created_at, updated_at`,
+			c.ID, c.NameHe, c.NameEn, c.SortOrder, c.ParentID, c.DefaultMinStock).Scan(&c.CreatedAt, &c.UpdatedAt)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCategory removes a category no product or subcategory uses
+func (s *PostgresStore) DeleteCategory(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	categories, err := lockCategories(tx)
+	if err != nil {
+		return err
+	}
+	if categories[id] == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+	var products int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM products WHERE category=$1`, id).Scan(&products); err != nil {
+		return err
+	}
+	if products > 0 {
+		return fmt.Errorf("%w: %d products are in %s", ErrCategoryInUse, products, id)
+	}
+	for _, c := range categories {
+		if c.ParentID == id {
+			return fmt.Errorf("%w: %s is the parent of %s", ErrCategoryInUse, id, c.ID)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM categories WHERE id=$1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Snapshot reads every table in one read-only transaction, so the
+// tables agree with each other even while movements keep coming in
+func (s *PostgresStore) Snapshot() (*Snapshot, error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Byte order (COLLATE "C"), the order the memory store sorts in
+	snap := &Snapshot{}
+	tables := []struct {
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		{`SELECT ` + productColumns + ` FROM products ORDER BY id COLLATE "C"`, func(rows *sql.Rows) error {
+			p, err := scanProduct(rows)
+			if err == nil {
+				snap.Products = append(snap.Products, p)
+			}
+			return err
+		}},
+		{`SELECT ` + stockColumns + ` FROM stocks ORDER BY product_id COLLATE "C"`, func(rows *sql.Rows) error {
+			st, err := scanStock(rows)
+			if err == nil {
+				snap.Stock = append(snap.Stock, st)
+			}
+			return err
+		}},
+		{`SELECT ` + movementColumns + ` FROM stock_movements ORDER BY created_at, id`, func(rows *sql.Rows) error {
+			m, err := scanMovement(rows)
+			if err == nil {
+				snap.Movements = append(snap.Movements, m)
+			}
+			return err
+		}},
+		{`SELECT id, product_id, alias, created_at FROM product_aliases ORDER BY product_id COLLATE "C", created_at, id`, func(rows *sql.Rows) error {
+			var a models.ProductAlias
+			err := rows.Scan(&a.ID, &a.ProductID, &a.Alias, &a.CreatedAt)
+			if err == nil {
+				snap.Aliases = append(snap.Aliases, &a)
+			}
+			return err
+		}},
+		{`SELECT code, product_id, pack_level, created_at FROM product_barcodes ORDER BY code`, func(rows *sql.Rows) error {
+			var b models.ProductBarcode
+			err := rows.Scan(&b.Code, &b.ProductID, &b.PackLevel, &b.CreatedAt)
+			if err == nil {
+				snap.Barcodes = append(snap.Barcodes, &b)
+			}
+			return err
+		}},
+		{`SELECT supplier, sku, product_id, pack_level, updated_at FROM supplier_skus ORDER BY supplier COLLATE "C", sku COLLATE "C"`, func(rows *sql.Rows) error {
+			var m models.SupplierSKU
+			err := rows.Scan(&m.Supplier, &m.SKU, &m.ProductID, &m.PackLevel, &m.UpdatedAt)
+			if err == nil {
+				snap.SupplierSKUs = append(snap.SupplierSKUs, &m)
+			}
+			return err
+		}},
+		{`SELECT ` + userColumns + ` FROM users ORDER BY id COLLATE "C"`, func(rows *sql.Rows) error {
+			u, err := scanUser(rows)
+			if err == nil {
+				snap.Users = append(snap.Users, u)
+			}
+			return err
+		}},
+		{`SELECT ` + staffColumns + ` FROM staff ORDER BY id COLLATE "C"`, func(rows *sql.Rows) error {
+			st, err := scanStaff(rows)
+			if err == nil {
+				snap.Staff = append(snap.Staff, st)
+			}
+			return err
+		}},
+		{`SELECT ` + categoryColumns + ` FROM categories ORDER BY id COLLATE "C"`, func(rows *sql.Rows) error {
+			c, err := scanCategory(rows)
+			if err == nil {
+				snap.Categories = append(snap.Categories, c)
+			}
+			return err
+		}},
+		{`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id COLLATE "C"`, func(rows *sql.Rows) error {
+			k, err := scanAPIKey(rows)
+			if err == nil {
+				snap.APIKeys = append(snap.APIKeys, k)
+			}
+			return err
+		}},
+		{`SELECT ` + auditColumns + ` FROM audit_log ORDER BY created_at, id`, func(rows *sql.Rows) error {
+			e, err := scanAudit(rows)
+			if err == nil {
+				snap.Audit = append(snap.Audit, e)
+			}
+			return err
+		}},
+	}
+	for _, table := range tables {
+		if err := queryEach(tx, table.query, table.scan); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// queryEach runs query in tx and calls scan for every row
+func queryEach(tx *sql.Tx, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Restore empties every table and loads the snapshot, in one transaction:
+// if any row fails, the data from before is still there
+func (s *PostgresStore) Restore(snap *Snapshot) error {
+	snap = snap.withCategories()
+	if err := snap.Validate(); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// No CASCADE: a table added later must be added here (and to Snapshot)
+	if _, err := tx.Exec(`TRUNCATE products, stocks, stock_movements, product_aliases, product_barcodes, supplier_skus,
+		users, sessions, staff, categories, api_keys, audit_log, idempotency_keys`); err != nil {
+		return err
+	}
+
+	// Referenced tables first
+	for _, u := range snap.Users {
+		if _, err := tx.Exec(`INSERT INTO users (id, username, display_name, password_hash, role, is_active, failed_logins, locked_until, language, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+			u.ID, u.Username, u.DisplayName, u.PasswordHash, u.Role, u.IsActive, u.FailedLogins, nullTime(u.LockedUntil), u.Language, u.CreatedAt, u.UpdatedAt); err != nil {
+			return fmt.Errorf("user %s: %w", u.ID, err)
+		}
+	}
+	for _, st := range snap.Staff {
+		if _, err := tx.Exec(`INSERT INTO staff (id, name_he, name_en, user_id, is_active, created_at, updated_at) VALUES ($1,$2,$3,NULLIF($4,''),$5,$6,$7)`,
+			st.ID, st.NameHe, st.NameEn, st.UserID, st.IsActive, st.CreatedAt, st.UpdatedAt); err != nil {
+			return fmt.Errorf("staff %s: %w", st.ID, err)
+		}
+	}
+	// Categories without parents first, then the parents: any order works
+	for _, c := range snap.Categories {
+		if _, err := tx.Exec(`INSERT INTO categories (id, name_he, name_en, sort_order, default_min_stock, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+			c.ID, c.NameHe, c.NameEn, c.SortOrder, c.DefaultMinStock, c.CreatedAt, c.UpdatedAt); err != nil {
+			return fmt.Errorf("category %s: %w", c.ID, err)
+		}
+	}
+	for _, c := range snap.Categories {
+		if c.ParentID == "" {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE categories SET parent_id=$2 WHERE id=$1`, c.ID, c.ParentID); err != nil {
+			return fmt.Errorf("category %s: %w", c.ID, err)
+		}
+	}
+	for _, p := range snap.Products {
+		if _, err := tx.Exec(`INSERT INTO products (id, name, brand, size, container_type, box_size, price, category, is_active, version, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$11)`,
+			p.ID, p.Name, p.Brand, p.Size, p.ContainerType, p.BoxSize, p.Price, p.Category, p.IsActive, p.Version, p.UpdatedAt); err != nil {
+			return fmt.Errorf("product %s: %w", p.ID, err)
+		}
+	}
+	for _, st := range snap.Stock {
+		if _, err := tx.Exec(`INSERT INTO stocks (product_id, quantity_boxes, quantity_units, min_stock, last_updated, version) VALUES ($1,$2,$3,$4,$5,$6)`,
+			st.ProductID, st.QuantityBoxes, st.QuantityUnits, st.MinStock, st.LastUpdated, st.Version); err != nil {
+			return fmt.Errorf("stock %s: %w", st.ProductID, err)
+		}
+	}
+	for _, m := range snap.Movements {
+		if _, err := tx.Exec(`INSERT INTO stock_movements (id, product_id, type, boxes, units, performed_by, reported_by, reason, source, created_at, performed_by_id, reported_by_id, client_id) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NULLIF($11,''),NULLIF($12,''),NULLIF($13,''))`,
+			m.ID, m.ProductID, m.Type, m.Boxes, m.Units, m.PerformedBy, m.ReportedBy, m.Reason, m.Source, m.CreatedAt, m.PerformedByID, m.ReportedByID, m.ClientID); err != nil {
+			return fmt.Errorf("movement %s: %w", m.ID, err)
+		}
+	}
+	for _, a := range snap.Aliases {
+		if _, err := tx.Exec(`INSERT INTO product_aliases (id, product_id, alias, normalized, created_at) VALUES ($1,$2,$3,$4,$5)`,
+			a.ID, a.ProductID, a.Alias, NormalizeSearchText(a.Alias), a.CreatedAt); err != nil {
+			return fmt.Errorf("alias %s: %w", a.ID, err)
+		}
+	}
+	for _, b := range snap.Barcodes {
+		if _, err := tx.Exec(`INSERT INTO product_barcodes (code, product_id, pack_level, created_at) VALUES ($1,$2,$3,$4)`,
+			b.Code, b.ProductID, b.PackLevel, b.CreatedAt); err != nil {
+			return fmt.Errorf("barcode %s: %w", b.Code, err)
+		}
+	}
+	for _, m := range snap.SupplierSKUs {
+		if _, err := tx.Exec(`INSERT INTO supplier_skus (supplier, sku, product_id, pack_level, updated_at) VALUES ($1,$2,$3,$4,$5)`,
+			m.Supplier, m.SKU, m.ProductID, m.PackLevel, m.UpdatedAt); err != nil {
+			return fmt.Errorf("supplier SKU %s %s: %w", m.Supplier, m.SKU, err)
+		}
+	}
+	for _, k := range snap.APIKeys {
+		if _, err := tx.Exec(`INSERT INTO api_keys (id, name, key_hash, scopes, created_by, created_at, last_used_at, revoked_at) VALUES ($1,$2,$3,$4,NULLIF($5,''),$6,$7,$8)`,
+			k.ID, k.Name, k.KeyHash, strings.Join(k.Scopes, " "), k.CreatedBy, k.CreatedAt, nullTime(k.LastUsedAt), nullTime(k.RevokedAt)); err != nil {
+			return fmt.Errorf("API key %s: %w", k.ID, err)
+		}
+	}
+	for _, e := range snap.Audit {
+		changes, err := json.Marshal(e.Changes)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO audit_log (id, entity, entity_id, action, actor, changes, created_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+			e.ID, e.Entity, e.EntityID, e.Action, e.Actor, string(changes), e.CreatedAt); err != nil {
+			return fmt.Errorf("audit entry %s: %w", e.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
 // Ensure PostgresStore implements Repository
 var _ Repository = (*PostgresStore)(nil)
@@ -108,6 +108,38 @@ func prepareDBForTest(t *testing.T) *sql.DB {
 		}
 	}
 
+	// Later migrations are idempotent (IF NOT EXISTS), so always apply them
+	for _, name := range []string{
+		"003_add_movement_source.sql",
+		"004_add_product_aliases.sql",
+		"005_add_product_barcodes.sql",
+		"006_add_supplier_skus.sql",
+		"007_create_users.sql",
+		"008_add_user_roles.sql",
+		"009_create_staff.sql",
+		"010_create_api_keys.sql",
+		"011_create_audit_log.sql",
+		"012_add_versions.sql",
+		"013_create_idempotency_keys.sql",
+		"014_add_offline_sync.sql",
+		"015_create_categories.sql",
+		"016_add_user_language.sql",
+	} {
+		up, rerr := extractUpSQL(migrationPath(name))
+		if rerr != nil {
+			t.Fatalf("cannot read migration %s: %v", name, rerr)
+		}
+		for _, s := range strings.Split(up, ";") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if _, e := db.Exec(s); e != nil {
+				t.Fatalf("failed to apply migration %s stmt: %v", name, e)
+			}
+		}
+	}
+
 	// Verify tables exist; helpful debug if migrations didn't run
 	var reg sql.NullString
 	if err := db.QueryRow("SELECT to_regclass('public.stocks')").Scan(&reg); err != nil {
@@ -135,3 +167,51 @@ func TestPostgresStore_CRUD_and_Edges(t *testing.T) {
 		return NewPostgresStore(db)
 	}, db)
 }
+
+func TestPostgresStore_Audit(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testAuditTrail(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_IdempotencyKeys(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testIdempotencyKeys(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_SnapshotRestore(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testSnapshotRestore(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_QueryProducts(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testQueryProducts(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_Archive(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testArchive(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_MergeProducts(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testMergeProducts(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_Categories(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testCategories(t, NewPostgresStore(db))
+}
+
+func TestPostgresStore_APIKeyTouch(t *testing.T) {
+	db := prepareDBForTest(t)
+	defer db.Close()
+	testAPIKeyTouch(t, NewPostgresStore(db))
+}
@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// PRODUCT QUERIES: filter, sort, page
+// ============================================
+// QueryProducts pages with a cursor (keyset), not an offset: the cursor
+// holds the sort value and ID of the last product of a page, and the next
+// page starts after it. Products added or deleted in between don't shift
+// the pages. Both stores order by the sort field, then ID, comparing text
+// byte by byte, so a cursor means the same thing in either.
+
+// Product list page sizes
+const (
+	DefaultProductPageSize = 50
+	MaxProductPageSize     = 500
+)
+
+// Product statuses to list
+const (
+	ProductStatusActive   = "active" // Default
+	ProductStatusInactive = "inactive"
+	ProductStatusAll      = "all"
+)
+
+// Product sort fields
+const (
+	ProductSortName      = "name" // Default
+	ProductSortBrand     = "brand"
+	ProductSortCategory  = "category"
+	ProductSortPrice     = "price"
+	ProductSortSize      = "size"
+	ProductSortUpdatedAt = "updatedAt"
+)
+
+// productSortColumns maps sort fields to their PostgreSQL expression
+var productSortColumns = map[string]string{
+	ProductSortName:      `name COLLATE "C"`,
+	ProductSortBrand:     `brand COLLATE "C"`,
+	ProductSortCategory:  `category COLLATE "C"`,
+	ProductSortPrice:     `price`,
+	ProductSortSize:      `size`,
+	ProductSortUpdatedAt: `COALESCE(updated_at, created_at)`,
+}
+
+// ProductQuery filters, sorts and pages QueryProducts
+// Zero values mean "don't filter on this field"
+type ProductQuery struct {
+	Category      string  // Case-insensitive
+	Brand         string  // Case-insensitive
+	ContainerType string  // Case-insensitive
+	Status        string  // active (default), inactive or all
+	MinPrice      float64 // Price >= MinPrice
+	MaxPrice      float64 // Price <= MaxPrice (0 = no limit)
+	LowStock      bool    // Only products below their minimum stock
+
+	Sort   string // Sort field (default name)
+	Desc   bool   // Descending
+	Limit  int    // Page size (0 = DefaultProductPageSize, at most MaxProductPageSize)
+	Cursor string // NextCursor of the previous page; "" = first page
+}
+
+// ProductPage is one page of QueryProducts
+type ProductPage struct {
+	Products   []*models.Product
+	Total      int    // Products matching the filters, on all pages
+	NextCursor string // "" on the last page
+}
+
+// productCursor is what a cursor string holds
+type productCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"` // Sort value of the last product (see sortValue)
+	ID    string `json:"i"`
+}
+
+// normalize fills in defaults and checks the query; it returns the decoded
+// cursor, nil on the first page
+func (q *ProductQuery) normalize() (*productCursor, error) {
+	if q.Status == "" {
+		q.Status = ProductStatusActive
+	}
+	switch q.Status {
+	case ProductStatusActive, ProductStatusInactive, ProductStatusAll:
+	default:
+		return nil, fmt.Errorf("%w: status %q (use active, inactive or all)", ErrInvalidProductQuery, q.Status)
+	}
+	if q.Sort == "" {
+		q.Sort = ProductSortName
+	}
+	if _, ok := productSortColumns[q.Sort]; !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidProductQuery, q.Sort)
+	}
+	if q.MinPrice < 0 || q.MaxPrice < 0 || (q.MaxPrice > 0 && q.MaxPrice < q.MinPrice) {
+		return nil, fmt.Errorf("%w: price range %v-%v", ErrInvalidProductQuery, q.MinPrice, q.MaxPrice)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultProductPageSize
+	}
+	if q.Limit > MaxProductPageSize {
+		q.Limit = MaxProductPageSize
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c productCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, fmt.Errorf("%w: it belongs to another sort order", ErrInvalidCursor)
+	}
+	if _, err := cursorArg(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// encode turns a cursor into its string
+func (c productCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// sortValue is p's value of a sort field, as stored in cursors
+func sortValue(p *models.Product, field string) string {
+	switch field {
+	case ProductSortBrand:
+		return p.Brand
+	case ProductSortCategory:
+		return p.Category
+	case ProductSortPrice:
+		return strconv.FormatFloat(p.Price, 'f', -1, 64)
+	case ProductSortSize:
+		return strconv.Itoa(p.Size)
+	case ProductSortUpdatedAt:
+		return p.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return p.Name
+	}
+}
+
+// cursorArg converts a cursor value back for a PostgreSQL comparison
+func cursorArg(c *productCursor) (interface{}, error) {
+	switch c.Sort {
+	case ProductSortPrice:
+		v, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return v, nil
+	case ProductSortSize:
+		v, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return v, nil
+	case ProductSortUpdatedAt:
+		v, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return v, nil
+	default:
+		return c.Value, nil
+	}
+}
+
+// compareProducts orders two products by a sort field, then ID (ascending)
+func compareProducts(a, b *models.Product, field string) int {
+	var c int
+	switch field {
+	case ProductSortPrice:
+		c = compareFloat(a.Price, b.Price)
+	case ProductSortSize:
+		c = a.Size - b.Size
+	case ProductSortUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		c = strings.Compare(sortValue(a, field), sortValue(b, field))
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// matches reports whether p passes the filters of q (not the cursor)
+func (q *ProductQuery) matches(p *models.Product, stock *models.Stock) bool {
+	switch {
+	case q.Status == ProductStatusActive && !p.IsActive,
+		q.Status == ProductStatusInactive && p.IsActive,
+		q.Category != "" && !strings.EqualFold(p.Category, q.Category),
+		q.Brand != "" && !strings.EqualFold(p.Brand, q.Brand),
+		q.ContainerType != "" && !strings.EqualFold(p.ContainerType, q.ContainerType),
+		p.Price < q.MinPrice,
+		q.MaxPrice > 0 && p.Price > q.MaxPrice,
+		q.LowStock && (stock == nil || !stock.IsLowStock(p.BoxSize)):
+		return false
+	}
+	return true
+}
+
+// pageProducts sorts the matching products, skips to the cursor and cuts
+// one page (the memory store's QueryProducts)
+func pageProducts(matched []*models.Product, q ProductQuery, cursor *productCursor) *ProductPage {
+	sort.Slice(matched, func(i, j int) bool {
+		c := compareProducts(matched[i], matched[j], q.Sort)
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	page := &ProductPage{Products: []*models.Product{}, Total: len(matched)}
+	start := 0
+	if cursor != nil {
+		// The last product of the previous page, rebuilt from the cursor
+		last, err := cursorProduct(cursor)
+		if err != nil {
+			return page
+		}
+		start = sort.Search(len(matched), func(i int) bool {
+			c := compareProducts(matched[i], last, q.Sort)
+			if q.Desc {
+				return c < 0
+			}
+			return c > 0
+		})
+	}
+	end := start + q.Limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	page.Products = append(page.Products, matched[start:end]...)
+	if end < len(matched) {
+		last := matched[end-1]
+		page.NextCursor = productCursor{Sort: q.Sort, Desc: q.Desc, Value: sortValue(last, q.Sort), ID: last.ID}.encode()
+	}
+	return page
+}
+
+// cursorProduct rebuilds a product that compares like the cursor
+func cursorProduct(c *productCursor) (*models.Product, error) {
+	p := &models.Product{ID: c.ID}
+	v, err := cursorArg(c)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Sort {
+	case ProductSortPrice:
+		p.Price = v.(float64)
+	case ProductSortSize:
+		p.Size = v.(int)
+	case ProductSortUpdatedAt:
+		p.UpdatedAt = v.(time.Time)
+	case ProductSortBrand:
+		p.Brand = c.Value
+	case ProductSortCategory:
+		p.Category = c.Value
+	default:
+		p.Name = c.Value
+	}
+	return p, nil
+}
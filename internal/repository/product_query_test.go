@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// testQueryProducts runs against any store; PostgreSQL runs it with -tags integration
+func testQueryProducts(t *testing.T, store Repository) {
+	t.Helper()
+	// Start empty: other tests' products would count too
+	if err := store.Restore(&Snapshot{}); err != nil {
+		t.Fatalf("emptying the store failed: %v", err)
+	}
+	category := "drinks"
+	add := func(name, brand string, size int, container string, price float64) string {
+		t.Helper()
+		id, err := store.AddProduct(&models.Product{Name: name, Brand: brand, Size: size, ContainerType: container, BoxSize: 6, Price: price, Category: category})
+		if err != nil {
+			t.Fatalf("AddProduct %s failed: %v", name, err)
+		}
+		return id
+	}
+	cola := add("Cola", "Coke", 330, "can", 5)
+	add("Fanta", "Fanta", 330, "can", 5)
+	add("Sprite", "Sprite", 500, "bottle", 7.5)
+	water := add("Water", "Eden", 1500, "bottle", 3)
+	beer := add("Beer", "Goldstar", 330, "bottle", 12)
+	if err := store.DeleteProduct(beer, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if err := store.SetMinStock(water, 10, 0); err != nil {
+		t.Fatalf("SetMinStock failed: %v", err)
+	}
+
+	names := func(page *ProductPage) []string {
+		var res []string
+		for _, p := range page.Products {
+			res = append(res, p.Name)
+		}
+		return res
+	}
+
+	// Walk all pages of two: every active product once, in order
+	var walked []string
+	q := ProductQuery{Category: category, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("paging does not end")
+		}
+		page, err := store.QueryProducts(q)
+		if err != nil {
+			t.Fatalf("QueryProducts failed: %v", err)
+		}
+		if page.Total != 4 {
+			t.Errorf("total: got %d, want 4", page.Total)
+		}
+		walked = append(walked, names(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if fmt.Sprint(walked) != "[Cola Fanta Sprite Water]" {
+		t.Errorf("pages by name: got %v", walked)
+	}
+
+	// Price descending; Cola and Fanta tie and go by ID, descending too
+	q = ProductQuery{Category: category, Sort: ProductSortPrice, Desc: true, Limit: 3}
+	page, err := store.QueryProducts(q)
+	if err != nil {
+		t.Fatalf("QueryProducts by price failed: %v", err)
+	}
+	first := names(page)
+	q.Cursor = page.NextCursor
+	page, _ = store.QueryProducts(q)
+	if got := fmt.Sprint(append(first, names(page)...)); got != "[Sprite Fanta Cola Water]" {
+		t.Errorf("by price desc: got %s", got)
+	}
+
+	tests := []struct {
+		name  string
+		query ProductQuery
+		want  string
+	}{
+		{"brand, any case", ProductQuery{Brand: "COKE"}, "[Cola]"},
+		{"container", ProductQuery{ContainerType: "bottle"}, "[Sprite Water]"},
+		{"price range", ProductQuery{MinPrice: 4, MaxPrice: 7.5}, "[Cola Fanta Sprite]"},
+		{"inactive", ProductQuery{Status: ProductStatusInactive}, "[Beer]"},
+		{"all", ProductQuery{Status: ProductStatusAll, Sort: ProductSortSize}, "[Cola Fanta Beer Sprite Water]"},
+		{"low stock", ProductQuery{LowStock: true}, "[Water]"},
+	}
+	for _, tt := range tests {
+		tt.query.Category = category
+		page, err := store.QueryProducts(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := fmt.Sprint(names(page)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := store.QueryProducts(ProductQuery{Sort: "color"}); !errors.Is(err, ErrInvalidProductQuery) {
+		t.Errorf("unknown sort: expected ErrInvalidProductQuery, got %v", err)
+	}
+	if _, err := store.QueryProducts(ProductQuery{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor: expected ErrInvalidCursor, got %v", err)
+	}
+	page, _ = store.QueryProducts(ProductQuery{Category: category, Limit: 1})
+	if _, err := store.QueryProducts(ProductQuery{Category: category, Sort: ProductSortPrice, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another sort: expected ErrInvalidCursor, got %v", err)
+	}
+	if p, _ := store.GetProduct(cola); p == nil {
+		t.Error("queries must not change products")
+	}
+}
+
+func TestMemoryStoreQueryProducts(t *testing.T) {
+	testQueryProducts(t, NewMemoryStore())
+}
+
+func TestMemoryStoreListProductsByName(t *testing.T) {
+	store := NewMemoryStore()
+	for _, name := range []string{"Water", "Cola", "Sprite", "Fanta"} {
+		if _, err := store.AddProduct(&models.Product{Name: name, Brand: name, Size: 330, ContainerType: "can", Category: "drinks"}); err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+	}
+	var got []string
+	for _, p := range store.ListProducts() {
+		got = append(got, p.Name)
+	}
+	if fmt.Sprint(got) != "[Cola Fanta Sprite Water]" {
+		t.Errorf("ListProducts order: got %v", got)
+	}
+}
@@ -3,7 +3,11 @@
 // and the database, making it easier to test and swap databases.
 package repository
 
-import "github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+import (
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
 
 // ============================================
 // REPOSITORY INTERFACE
@@ -24,17 +28,47 @@ type ProductRepository interface {
 	// GetProduct retrieves a product by ID
 	GetProduct(id string) (*models.Product, error)
 
-	// ListProducts returns all active products
+	// ListProducts returns all active products, by name
 	ListProducts() []*models.Product
 
-	// SearchProducts finds products matching query (name/brand)
+	// QueryProducts returns one page of the products matching q, with
+	// the total count and the cursor of the next page (see product_query.go)
+	QueryProducts(q ProductQuery) (*ProductPage, error)
+
+	// SearchProducts finds products matching query (name/brand/aliases),
+	// typo-tolerant and ordered by relevance
 	SearchProducts(query string) []*models.Product
 
-	// UpdateProduct updates an existing product
+	// SearchProductsRanked is SearchProducts with scores and what matched
+	// (field, and the alias text when an alias matched)
+	SearchProductsRanked(query string) []ProductMatch
+
+	// UpdateProduct updates an existing product and bumps p.Version.
+	// A non-zero p.Version must match the stored one (else ErrVersionConflict).
 	UpdateProduct(p *models.Product) error
 
 	// DeleteProduct soft-deletes a product
-	DeleteProduct(id string) error
+	// version is the one the caller last saw (0 = don't check)
+	DeleteProduct(id string, version int) error
+
+	// RestoreProduct brings a soft-deleted product back (IsActive = true)
+	// version is the one the caller last saw (0 = don't check)
+	RestoreProduct(id string, version int) error
+
+	// PurgeProduct deletes an archived product for good, with its stock
+	// row, aliases, barcodes and supplier SKUs. Refused (ErrProductInUse)
+	// while it has stock or movements refer to it, and for active
+	// products (ErrProductActive).
+	PurgeProduct(id string, version int) error
+
+	// MergeProducts folds the duplicate fromID into intoID: stock,
+	// movements, aliases, barcodes and supplier SKUs move over and the
+	// duplicate is archived (see merge.go)
+	MergeProducts(fromID, intoID string) (*MergeResult, error)
+
+	// ListProductsChangedSince returns products (deleted ones too) changed
+	// at or after since, oldest change first; zero since returns all
+	ListProductsChangedSince(since time.Time) ([]*models.Product, error)
 }
 
 // StockRepository defines operations for managing stock
@@ -46,10 +80,215 @@ type StockRepository interface {
 	UpdateStock(productID string, boxes, units int) error
 
 	// SetMinStock sets the minimum stock alert threshold
-	SetMinStock(productID string, minStock int) error
+	// version is the stock version the caller last saw (0 = don't check)
+	SetMinStock(productID string, minStock, version int) error
 
 	// GetLowStockProducts returns products below minimum
 	GetLowStockProducts() []*models.Product
+
+	// ListStockChangedSince returns stock rows changed at or after since,
+	// oldest change first; zero since returns all
+	ListStockChangedSince(since time.Time) ([]*models.Stock, error)
+}
+
+// MovementFilter narrows down ListMovements results
+// Zero values mean "don't filter on this field"
+type MovementFilter struct {
+	ProductID string    // Only movements for this product
+	Type      string    // Only this movement type (IN, OUT, ...)
+	From      time.Time // CreatedAt >= From
+	To        time.Time // CreatedAt < To
+	StaffID   string    // Only movements performed or reported by this staff member
+}
+
+// MovementRepository defines operations for the stock movement log
+type MovementRepository interface {
+	// RecordMovement applies the movement to stock and logs it
+	// in one step, returns generated ID. A ClientID that was already
+	// logged gives ErrMovementExists and changes nothing.
+	RecordMovement(m *models.StockMovement) (string, error)
+
+	// GetMovementByClientID finds a movement by the ID its offline client made up
+	GetMovementByClientID(clientID string) (*models.StockMovement, error)
+
+	// ListMovements returns movements matching the filter, oldest first
+	ListMovements(filter MovementFilter) []*models.StockMovement
+}
+
+// AliasRepository defines operations for product aliases
+// (other names staff use for a product, matched by SearchProducts)
+type AliasRepository interface {
+	// AddAlias adds an alias to a product, returns the stored alias
+	AddAlias(productID, alias string) (*models.ProductAlias, error)
+
+	// ListAliases returns a product's aliases, oldest first
+	ListAliases(productID string) ([]*models.ProductAlias, error)
+
+	// DeleteAlias removes one alias from a product
+	DeleteAlias(productID, aliasID string) error
+}
+
+// BarcodeRepository defines operations for product barcodes
+// A barcode belongs to exactly one product and pack level
+type BarcodeRepository interface {
+	// AddBarcode validates and stores a barcode for a product
+	AddBarcode(productID, code, packLevel string) (*models.ProductBarcode, error)
+
+	// ListBarcodes returns a product's barcodes, oldest first
+	ListBarcodes(productID string) ([]*models.ProductBarcode, error)
+
+	// DeleteBarcode removes one barcode from a product
+	DeleteBarcode(productID, code string) error
+
+	// GetProductByBarcode finds the product and pack level for a scanned code
+	GetProductByBarcode(code string) (*models.Product, *models.ProductBarcode, error)
+}
+
+// SupplierRepository maps supplier item codes to products
+// (used to match invoice lines)
+type SupplierRepository interface {
+	// SetSupplierSKU creates or replaces the mapping for (supplier, SKU)
+	SetSupplierSKU(m *models.SupplierSKU) error
+
+	// GetSupplierSKU returns the mapping for a supplier's item code
+	GetSupplierSKU(supplier, sku string) (*models.SupplierSKU, error)
+}
+
+// UserRepository defines operations for user accounts
+// Usernames are unique and stored lowercase
+type UserRepository interface {
+	// CreateUser stores a new user, returns generated ID
+	CreateUser(u *models.User) (string, error)
+
+	// GetUser retrieves a user by ID
+	GetUser(id string) (*models.User, error)
+
+	// GetUserByUsername retrieves a user by login name (case-insensitive)
+	GetUserByUsername(username string) (*models.User, error)
+
+	// UpdateUser saves all fields of an existing user
+	UpdateUser(u *models.User) error
+
+	// ListUsers returns all users ordered by username
+	ListUsers() []*models.User
+
+	// CountUsers returns the number of users (0 = first-run setup)
+	CountUsers() int
+}
+
+// SessionRepository defines operations for login sessions
+type SessionRepository interface {
+	// CreateSession stores a new session
+	CreateSession(s *models.Session) error
+
+	// GetSession retrieves a session by ID
+	GetSession(id string) (*models.Session, error)
+
+	// UpdateSession saves the refresh hash, expiry and revocation
+	UpdateSession(s *models.Session) error
+}
+
+// APIKeyRepository defines operations for machine API keys
+type APIKeyRepository interface {
+	// CreateAPIKey stores a new key (the ID is set by the caller)
+	CreateAPIKey(k *models.APIKey) error
+
+	// GetAPIKey retrieves a key by ID
+	GetAPIKey(id string) (*models.APIKey, error)
+
+	// UpdateAPIKey saves the revocation
+	UpdateAPIKey(k *models.APIKey) error
+
+	// TouchAPIKey sets when an active key was last used; it never
+	// brings back a revoked key (ErrAPIKeyNotFound)
+	TouchAPIKey(id string, t time.Time) error
+
+	// ListAPIKeys returns all keys (active and revoked), newest first
+	ListAPIKeys() []*models.APIKey
+}
+
+// IdempotencyRepository stores first responses per Idempotency-Key
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey claims rec.Scope/rec.Key for a new request.
+	// Returns nil if the caller now owns the key (an expired record is
+	// replaced), or the live record that already holds it.
+	ReserveIdempotencyKey(rec *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+
+	// SaveIdempotentResponse stores the response of a reserved key
+	SaveIdempotentResponse(rec *models.IdempotencyRecord) error
+
+	// ReleaseIdempotencyKey forgets a reserved key, so the request can be retried
+	ReleaseIdempotencyKey(scope, key string) error
+
+	// PurgeIdempotencyKeys deletes records expired at now, returns how many
+	PurgeIdempotencyKeys(now time.Time) int
+}
+
+// AuditRepository gives access to the audit trail of products and settings
+type AuditRepository interface {
+	// ListAudit returns matching entries, newest first
+	ListAudit(filter AuditFilter) []*models.AuditEntry
+
+	// WithActor returns the same store, recording actor as the one who
+	// made the audited changes (see audit.go)
+	WithActor(actor string) Repository
+}
+
+// StaffRepository defines operations for the staff directory
+// (the people movements are performed and reported by)
+type StaffRepository interface {
+	// AddStaff stores a new staff member, returns generated ID
+	AddStaff(s *models.Staff) (string, error)
+
+	// GetStaff retrieves a staff member by ID
+	GetStaff(id string) (*models.Staff, error)
+
+	// GetStaffByUser retrieves the staff member linked to a login account
+	GetStaffByUser(userID string) (*models.Staff, error)
+
+	// UpdateStaff saves names, login link and active flag
+	UpdateStaff(s *models.Staff) error
+
+	// ListStaff returns all staff members (active and inactive) by ID
+	ListStaff() []*models.Staff
+
+	// SetMovementStaff links an already logged movement to staff members
+	// (used to migrate movements logged with free-text names)
+	SetMovementStaff(movementID, performedByID, reportedByID string) error
+}
+
+// CategoryRepository defines operations for product categories
+// (see category.go). It is the models.CategoryChecker that
+// Product.Validate asks.
+type CategoryRepository interface {
+	// HasCategory reports whether a category exists
+	HasCategory(id string) bool
+
+	// GetCategory retrieves a category by ID
+	GetCategory(id string) (*models.Category, error)
+
+	// ListCategories returns all categories by SortOrder, then ID
+	ListCategories() []*models.Category
+
+	// AddCategory stores a new category under its own ID
+	AddCategory(c *models.Category) error
+
+	// UpdateCategory saves names, sort order, parent and default min stock
+	UpdateCategory(c *models.Category) error
+
+	// DeleteCategory removes a category no product or subcategory uses
+	// (else ErrCategoryInUse)
+	DeleteCategory(id string) error
+}
+
+// BackupRepository reads and replaces the whole store (see backup.go)
+type BackupRepository interface {
+	// Snapshot returns every table as of one moment
+	Snapshot() (*Snapshot, error)
+
+	// Restore replaces all data with the snapshot (after snap.Validate),
+	// dropping sessions and Idempotency-Key records
+	Restore(snap *Snapshot) error
 }
 
 // Repository combines all repository interfaces
@@ -57,6 +296,18 @@ type StockRepository interface {
 type Repository interface {
 	ProductRepository
 	StockRepository
+	MovementRepository
+	AliasRepository
+	BarcodeRepository
+	SupplierRepository
+	UserRepository
+	SessionRepository
+	APIKeyRepository
+	StaffRepository
+	CategoryRepository
+	AuditRepository
+	IdempotencyRepository
+	BackupRepository
 }
 
 // ============================================
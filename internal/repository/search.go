@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+// ============================================
+// PRODUCT SEARCH: normalization + fuzzy ranking
+// ============================================
+// Both stores rank with the same Go code, so MemoryStore and
+// PostgresStore always return the same results in the same order.
+// The catalog is a few hundred products - scoring them in Go is cheap.
+
+// MinSearchScore is the lowest score a product needs to be returned
+const MinSearchScore = 0.5
+
+// Matched fields reported in ProductMatch.MatchedField
+const (
+	MatchName  = "name"
+	MatchBrand = "brand"
+	MatchAlias = "alias"
+)
+
+// ProductMatch is one search result with its relevance
+type ProductMatch struct {
+	Product      *models.Product
+	Score        float64 // 0..1, higher is better
+	MatchedField string  // Field that matched best: "name", "brand", "alias"
+	MatchedAlias string  // The alias text when MatchedField is "alias"
+}
+
+// finalLetters maps Hebrew final forms to regular forms (ך→כ)
+var finalLetters = map[rune]rune{
+	'ך': 'כ', 'ם': 'מ', 'ן': 'נ', 'ף': 'פ', 'ץ': 'צ',
+}
+
+// NormalizeSearchText prepares text for matching:
+// lowercase, no niqqud, no geresh/gershayim/quotes ("מ״ל" = "מ"ל" = "מל"),
+// final letters as regular letters, punctuation as spaces.
+func NormalizeSearchText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 0x0591 && r <= 0x05C7 && r != 0x05BE && r != 0x05C0 && r != 0x05C3 && r != 0x05C6:
+			// Niqqud and cantillation marks - drop
+		case r == '׳' || r == '״' || r == '\'' || r == '"' || r == '`' || r == '’' || r == '‘' || r == '“' || r == '”':
+			// Geresh, gershayim and quotes inside abbreviations - drop
+		case finalLetters[r] != 0:
+			b.WriteRune(finalLetters[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// searchField is one piece of product text we match against
+type searchField struct {
+	name   string   // MatchName, MatchBrand, MatchAlias
+	text   string   // Original text (reported for aliases)
+	weight float64  // How much a match here counts (name = 1)
+	words  []string // Normalized words
+}
+
+// productFields returns the searchable fields of a product.
+// Every alias is its own field, so we can report which one matched.
+func productFields(p *models.Product, aliases []string) []searchField {
+	fields := []searchField{
+		{name: MatchName, text: p.Name, weight: 1, words: strings.Fields(NormalizeSearchText(p.Name))},
+		{name: MatchBrand, text: p.Brand, weight: 0.95, words: strings.Fields(NormalizeSearchText(p.Brand))},
+	}
+	for _, a := range aliases {
+		fields = append(fields, searchField{name: MatchAlias, text: a, weight: 1, words: strings.Fields(NormalizeSearchText(a))})
+	}
+	return fields
+}
+
+// scoreFields rates how well the query words match the fields.
+// Each query word takes its best match in any field; the score is the
+// average. The reported field is the one that matches the query best
+// on its own (ties go to the earlier field: name, brand, aliases).
+func scoreFields(queryWords []string, fields []searchField) (float64, int) {
+	if len(queryWords) == 0 || len(fields) == 0 {
+		return 0, -1
+	}
+	total := 0.0
+	fieldTotal := make([]float64, len(fields))
+	for _, q := range queryWords {
+		best := 0.0
+		for i, f := range fields {
+			fieldBest := 0.0
+			for _, w := range f.words {
+				fieldBest = max(fieldBest, wordScore(q, w)*f.weight)
+			}
+			fieldTotal[i] += fieldBest
+			best = max(best, fieldBest)
+		}
+		total += best
+	}
+
+	matched := -1
+	for i := range fields {
+		if fieldTotal[i] > 0 && (matched < 0 || fieldTotal[i] > fieldTotal[matched]) {
+			matched = i
+		}
+	}
+	return total / float64(len(queryWords)), matched
+}
+
+// TextScore rates how well query matches text (0..1) with the same
+// normalization and typo tolerance as product search.
+// Used to match free-text names, e.g. staff names on old movements.
+func TextScore(query, text string) float64 {
+	score, _ := scoreFields(strings.Fields(NormalizeSearchText(query)),
+		[]searchField{{weight: 1, words: strings.Fields(NormalizeSearchText(text))}})
+	return score
+}
+
+// wordScore compares one query word with one product word
+func wordScore(q, w string) float64 {
+	switch {
+	case q == w:
+		return 1
+	case strings.HasPrefix(w, q):
+		return 0.9
+	case strings.Contains(w, q):
+		return 0.8
+	}
+
+	best := 0.0
+	for _, variant := range hebrewPrefixVariants(q) {
+		if variant == w {
+			best = 0.85 // "הקולה" → "קולה"
+		}
+	}
+
+	// Typos: edit distance for short words, trigrams for long ones
+	ql, wl := len([]rune(q)), len([]rune(w))
+	if ql >= 3 {
+		allowed := 1
+		if ql >= 7 {
+			allowed = 2
+		}
+		if d := editDistance(q, w); d <= allowed {
+			best = max(best, 0.75-0.1*float64(d-1))
+		}
+		if ql >= 4 && wl >= 4 {
+			if sim := trigramSimilarity(q, w); sim >= 0.4 {
+				best = max(best, 0.7*sim+0.1)
+			}
+		}
+	}
+	return best
+}
+
+// hebrewPrefixVariants strips one-letter Hebrew prefixes (ו, ה, ב, ל, מ, ש)
+func hebrewPrefixVariants(word string) []string {
+	var res []string
+	runes := []rune(word)
+	for i := 0; i < 2 && len(runes)-i > 3; i++ {
+		if !strings.ContainsRune("והבלמש", runes[i]) {
+			break
+		}
+		res = append(res, string(runes[i+1:]))
+	}
+	return res
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment)
+// distance: insert, delete, substitute or swap two neighbours = 1 edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// trigrams returns the set of 3-letter pieces of "  word " (pg_trgm style)
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// trigramSimilarity is shared trigrams / all trigrams (0..1)
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	union := len(ta) + len(tb) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// rankProducts scores products against the query and returns the
+// matches above MinSearchScore, best first. aliases maps product ID to
+// alias texts. An empty query matches everything (same as the old
+// substring search).
+func rankProducts(query string, products []*models.Product, aliases map[string][]string) []ProductMatch {
+	queryWords := strings.Fields(NormalizeSearchText(query))
+	matches := make([]ProductMatch, 0)
+
+	for _, p := range products {
+		if len(queryWords) == 0 {
+			matches = append(matches, ProductMatch{Product: p, Score: 1, MatchedField: MatchName})
+			continue
+		}
+		fields := productFields(p, aliases[p.ID])
+		score, best := scoreFields(queryWords, fields)
+		if score < MinSearchScore || best < 0 {
+			continue
+		}
+		m := ProductMatch{Product: p, Score: score, MatchedField: fields[best].name}
+		if m.MatchedField == MatchAlias {
+			m.MatchedAlias = fields[best].text
+		}
+		matches = append(matches, m)
+	}
+
+	sortMatches(matches)
+	return matches
+}
+
+// sortMatches orders by score, then name, then ID - stable across stores
+func sortMatches(matches []ProductMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Product.Name != matches[j].Product.Name {
+			return matches[i].Product.Name < matches[j].Product.Name
+		}
+		return matches[i].Product.ID < matches[j].Product.ID
+	})
+}
+
+// matchedProducts drops the scores
+func matchedProducts(matches []ProductMatch) []*models.Product {
+	res := make([]*models.Product, 0, len(matches))
+	for _, m := range matches {
+		res = append(res, m.Product)
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := map[string]string{
+		`קוקה קולה 330 מ״ל`: "קוקה קולה 330 מל",
+		`קוקה קולה 330 מ"ל`: "קוקה קולה 330 מל",
+		"שָׁלוֹם":           "שלומ",
+		"לחם":               "לחמ",
+		"Coca-Cola  Zero":   "coca cola zero",
+		"צ׳יפס":             "ציפס",
+	}
+	for in, want := range tests {
+		if got := NormalizeSearchText(in); got != want {
+			t.Errorf("NormalizeSearchText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchProductsRanking(t *testing.T) {
+	store := NewMemoryStore()
+	for _, p := range []*models.Product{
+		{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"},
+		{Name: "קוקה קולה זירו 1.5 ליטר", Brand: "Coca Cola", Size: 1500, ContainerType: "bottle", BoxSize: 6, Price: 9, Category: "drinks"},
+		{Name: "פנטה 330 מ״ל פחית", Brand: "Fanta", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"},
+		{Name: "חומוס 400 גרם", Brand: "עשי", Size: 400, ContainerType: "can", BoxSize: 12, Price: 8, Category: "canned"},
+	} {
+		if _, err := store.AddProduct(p); err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string // IDs in order
+	}{
+		{`קולה 330 מ"ל`, []string{"PROD-001", "PROD-003"}}, // quotes = gershayim
+		{"קולה", []string{"PROD-001", "PROD-002"}},
+		{"coca cola", []string{"PROD-001", "PROD-002"}},
+		{"fnata", []string{"PROD-003"}},                 // swapped letters
+		{"חומס", []string{"PROD-004"}},                  // missing letter
+		{"החומוס", []string{"PROD-004"}},                // Hebrew prefix
+		{"קולה זירו", []string{"PROD-002", "PROD-001"}}, // best first
+		{"no-such-product-xyz", nil},
+	}
+	for _, tt := range tests {
+		got := store.SearchProducts(tt.query)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %d results %v, want %v", tt.query, len(got), ids(got), tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].ID != tt.want[i] {
+				t.Errorf("%q: got %v, want %v", tt.query, ids(got), tt.want)
+				break
+			}
+		}
+	}
+
+	// Inactive products never show up
+	if err := store.DeleteProduct("PROD-003", 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	if got := store.SearchProducts("fanta"); len(got) != 0 {
+		t.Fatalf("expected no results for deleted product, got %v", ids(got))
+	}
+}
+
+// ids lists product IDs for readable failure messages
+func ids(products []*models.Product) []string {
+	res := make([]string, 0, len(products))
+	for _, p := range products {
+		res = append(res, p.ID)
+	}
+	return res
+}
+
+func TestSearchProductsByAlias(t *testing.T) {
+	store := NewMemoryStore()
+	can := &models.Product{Name: "קוקה קולה 330 מ״ל פחית", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5.5, Category: "drinks"}
+	bottle := &models.Product{Name: "קוקה קולה 1.5 ליטר", Brand: "Coca Cola", Size: 1500, ContainerType: "bottle", BoxSize: 6, Price: 9, Category: "drinks"}
+	for _, p := range []*models.Product{can, bottle} {
+		if _, err := store.AddProduct(p); err != nil {
+			t.Fatalf("AddProduct failed: %v", err)
+		}
+	}
+	for _, alias := range []string{"קולה קטנה", "coke can"} {
+		if _, err := store.AddAlias(can.ID, alias); err != nil {
+			t.Fatalf("AddAlias(%q) failed: %v", alias, err)
+		}
+	}
+	if _, err := store.AddAlias(can.ID, "Coke  CAN"); !errors.Is(err, ErrAliasExists) {
+		t.Fatalf("expected ErrAliasExists for duplicate alias, got %v", err)
+	}
+	if _, err := store.AddAlias("PROD-999", "x"); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("expected ErrProductNotFound, got %v", err)
+	}
+
+	for _, query := range []string{"קולה קטנה", "coke can", "cok can"} {
+		matches := store.SearchProductsRanked(query)
+		if len(matches) == 0 || matches[0].Product.ID != can.ID {
+			t.Fatalf("%q: expected the can first, got %+v", query, matches)
+		}
+		if matches[0].MatchedField != MatchAlias || matches[0].MatchedAlias == "" {
+			t.Fatalf("%q: expected an alias match, got field %q alias %q", query, matches[0].MatchedField, matches[0].MatchedAlias)
+		}
+	}
+
+	// Name matches still report the name
+	matches := store.SearchProductsRanked("קוקה קולה ליטר")
+	if len(matches) == 0 || matches[0].Product.ID != bottle.ID || matches[0].MatchedField != MatchName {
+		t.Fatalf("expected the bottle by name first, got %+v", matches)
+	}
+
+	// Deleted aliases stop matching
+	aliases, err := store.ListAliases(can.ID)
+	if err != nil || len(aliases) != 2 {
+		t.Fatalf("ListAliases: got %v, err %v", aliases, err)
+	}
+	for _, a := range aliases {
+		if err := store.DeleteAlias(can.ID, a.ID); err != nil {
+			t.Fatalf("DeleteAlias failed: %v", err)
+		}
+	}
+	if got := store.SearchProducts("coke can"); len(got) != 0 {
+		t.Fatalf("expected no results after deleting aliases, got %v", ids(got))
+	}
+}
@@ -3,6 +3,7 @@ package repostest
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,11 +19,28 @@ type Store interface {
 	SearchProducts(string) []*models.Product
 	ListProducts() []*models.Product
 	UpdateProduct(*models.Product) error
-	DeleteProduct(string) error
+	DeleteProduct(string, int) error
 	GetStock(string) (*models.Stock, error)
 	UpdateStock(string, int, int) error
-	SetMinStock(string, int) error
+	SetMinStock(string, int, int) error
 	GetLowStockProducts() []*models.Product
+	RecordMovement(*models.StockMovement) (string, error)
+	GetMovementByClientID(string) (*models.StockMovement, error)
+	ListProductsChangedSince(time.Time) ([]*models.Product, error)
+	ListStockChangedSince(time.Time) ([]*models.Stock, error)
+	AddAlias(string, string) (*models.ProductAlias, error)
+	ListAliases(string) ([]*models.ProductAlias, error)
+	DeleteAlias(string, string) error
+	AddBarcode(string, string, string) (*models.ProductBarcode, error)
+	ListBarcodes(string) ([]*models.ProductBarcode, error)
+	DeleteBarcode(string, string) error
+	GetProductByBarcode(string) (*models.Product, *models.ProductBarcode, error)
+	SetSupplierSKU(*models.SupplierSKU) error
+	GetSupplierSKU(string, string) (*models.SupplierSKU, error)
+	AddStaff(*models.Staff) (string, error)
+	GetStaff(string) (*models.Staff, error)
+	UpdateStaff(*models.Staff) error
+	SetMovementStaff(string, string, string) error
 }
 
 // RunStoreIntegrationTests runs the common integration tests against any
@@ -57,6 +75,13 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 		t.Fatalf("AddProduct failed: %v", err)
 	}
 
+	// 2b) Brand + size + container is unique: the same product again is refused
+	dup := *p
+	dup.ID, dup.Name = "", "ITEST Product again"
+	if _, err := store.AddProduct(&dup); err == nil || !strings.Contains(err.Error(), "product already exists") {
+		t.Fatalf("adding the same brand, size and container again: got %v, want product already exists", err)
+	}
+
 	// 3) GetProduct
 	got, err := store.GetProduct(id)
 	if err != nil {
@@ -79,6 +104,96 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 		t.Fatalf("SearchProducts did not find inserted product")
 	}
 
+	// 4b) SearchProducts tolerates a typo ("itset" for "itest")
+	found = false
+	for _, r := range store.SearchProducts("itset product") {
+		if r.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("SearchProducts with a typo did not find inserted product")
+	}
+
+	// 4c) Aliases: add, reject duplicate, search by alias, delete
+	alias, err := store.AddAlias(id, tsPrefix+" nickname")
+	if err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+	if _, err := store.AddAlias(id, tsPrefix+" NICKNAME"); err == nil {
+		t.Fatalf("expected error when adding the same alias twice")
+	}
+	if aliases, err := store.ListAliases(id); err != nil || len(aliases) != 1 {
+		t.Fatalf("ListAliases: got %v, err %v; want 1 alias", aliases, err)
+	}
+	found = false
+	for _, r := range store.SearchProducts(tsPrefix + " nickname") {
+		if r.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("SearchProducts did not find product by alias")
+	}
+	if err := store.DeleteAlias(id, alias.ID); err != nil {
+		t.Fatalf("DeleteAlias failed: %v", err)
+	}
+	if err := store.DeleteAlias(id, alias.ID); err == nil {
+		t.Fatalf("expected error when deleting a missing alias")
+	}
+
+	// 4d) Barcodes: unit + case, lookup, wrong check digit, delete
+	// Codes are unique across the table, so build fresh ones per run
+	unitCode := withCheckDigit(fmt.Sprintf("2%011d", time.Now().UnixNano()%1e11))
+	caseCode := withCheckDigit(fmt.Sprintf("1%012d", time.Now().UnixNano()%1e12))
+	if _, err := store.AddBarcode(id, unitCode, models.PackUnit); err != nil {
+		t.Fatalf("AddBarcode unit failed: %v", err)
+	}
+	if _, err := store.AddBarcode(id, caseCode, models.PackCase); err != nil {
+		t.Fatalf("AddBarcode case failed: %v", err)
+	}
+	if _, err := store.AddBarcode(id, unitCode, models.PackUnit); err == nil {
+		t.Fatalf("expected error when adding the same barcode twice")
+	}
+	wrongDigit := caseCode[:len(caseCode)-1] + fmt.Sprint((int(caseCode[len(caseCode)-1]-'0')+1)%10)
+	if _, _, err := store.GetProductByBarcode(wrongDigit); err == nil {
+		t.Fatalf("expected check digit error for %s", wrongDigit)
+	}
+	byCode, bc, err := store.GetProductByBarcode(caseCode)
+	if err != nil || byCode.ID != id || bc.PackLevel != models.PackCase {
+		t.Fatalf("GetProductByBarcode: got %+v %+v, err %v", byCode, bc, err)
+	}
+	if codes, err := store.ListBarcodes(id); err != nil || len(codes) != 2 {
+		t.Fatalf("ListBarcodes: got %v, err %v; want 2 barcodes", codes, err)
+	}
+	for _, code := range []string{unitCode, caseCode} {
+		if err := store.DeleteBarcode(id, code); err != nil {
+			t.Fatalf("DeleteBarcode failed: %v", err)
+		}
+	}
+	if _, _, err := store.GetProductByBarcode(unitCode); err == nil {
+		t.Fatalf("expected not found after DeleteBarcode")
+	}
+
+	// 4e) Supplier SKUs: set, replace, get, missing
+	sku := &models.SupplierSKU{Supplier: tsPrefix + "-supplier", SKU: "A-1", ProductID: id, PackLevel: models.PackUnit}
+	if err := store.SetSupplierSKU(sku); err != nil {
+		t.Fatalf("SetSupplierSKU failed: %v", err)
+	}
+	sku.PackLevel = models.PackCase
+	if err := store.SetSupplierSKU(sku); err != nil {
+		t.Fatalf("SetSupplierSKU replace failed: %v", err)
+	}
+	gotSKU, err := store.GetSupplierSKU(sku.Supplier, "A-1")
+	if err != nil || gotSKU.ProductID != id || gotSKU.PackLevel != models.PackCase {
+		t.Fatalf("GetSupplierSKU: got %+v, err %v", gotSKU, err)
+	}
+	if _, err := store.GetSupplierSKU(sku.Supplier, "missing"); err == nil {
+		t.Fatalf("expected error for unknown supplier SKU")
+	}
+
 	// 5) ListProducts contains it (active)
 	all := store.ListProducts()
 	found = false
@@ -106,6 +221,19 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 		t.Fatalf("Update did not persist: got=%+v", got2)
 	}
 
+	// 6b) Versions: the update bumped it, an update from version 1 is refused
+	if got2.Version != 2 {
+		t.Fatalf("expected version 2 after one update, got %d", got2.Version)
+	}
+	stale := *got2
+	stale.Version, stale.Price = 1, 9.99
+	if err := store.UpdateProduct(&stale); err == nil {
+		t.Fatalf("expected version conflict when updating a stale product")
+	}
+	if again, _ := store.GetProduct(id); again.Price != 3.14 || again.Version != 2 {
+		t.Fatalf("stale update changed the product: %+v", again)
+	}
+
 	// 7) Stock: initial stock row should exist
 	st, err := store.GetStock(id)
 	if err != nil {
@@ -139,10 +267,96 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 		t.Fatalf("stock changed despite failed remove: %+v", st3)
 	}
 
-	// 10) SetMinStock and GetLowStockProducts
-	if err := store.SetMinStock(id, 1000); err != nil {
+	// 9b) RecordMovement: OUT updates stock, too-large OUT is refused
+	out := &models.StockMovement{ProductID: id, Type: models.MovementOut, Boxes: 1, PerformedBy: "itest"}
+	movID, err := store.RecordMovement(out)
+	if err != nil {
+		t.Fatalf("RecordMovement OUT failed: %v", err)
+	}
+	if movID == "" {
+		t.Fatalf("RecordMovement returned empty ID")
+	}
+	tooMuch := &models.StockMovement{ProductID: id, Type: models.MovementWaste, Units: 50, PerformedBy: "itest"}
+	if _, err := store.RecordMovement(tooMuch); err == nil {
+		t.Fatalf("expected error when wasting more units than available")
+	}
+	st4, err := store.GetStock(id)
+	if err != nil {
+		t.Fatalf("GetStock after movements failed: %v", err)
+	}
+	if st4.QuantityBoxes != 1 || st4.QuantityUnits != 5 {
+		t.Fatalf("unexpected stock after movements: %+v", st4)
+	}
+
+	// 9c) Staff: add, rename, link the OUT movement to them
+	if _, err := store.AddStaff(&models.Staff{IsActive: true}); err == nil {
+		t.Fatalf("expected validation error when adding staff without a name")
+	}
+	worker := &models.Staff{NameHe: "עובד " + tsPrefix, IsActive: true}
+	staffID, err := store.AddStaff(worker)
+	if err != nil {
+		t.Fatalf("AddStaff failed: %v", err)
+	}
+	worker.NameEn = tsPrefix + "-worker"
+	if err := store.UpdateStaff(worker); err != nil {
+		t.Fatalf("UpdateStaff failed: %v", err)
+	}
+	gotStaff, err := store.GetStaff(staffID)
+	if err != nil || gotStaff.NameEn != worker.NameEn || !gotStaff.IsActive {
+		t.Fatalf("GetStaff: got %+v, err %v", gotStaff, err)
+	}
+	if err := store.SetMovementStaff(movID, staffID, staffID); err != nil {
+		t.Fatalf("SetMovementStaff failed: %v", err)
+	}
+	if err := store.SetMovementStaff(movID, "STF-missing", ""); err == nil {
+		t.Fatalf("expected error when linking a movement to unknown staff")
+	}
+	if err := store.SetMovementStaff("MOV-missing", staffID, staffID); err == nil {
+		t.Fatalf("expected error when linking an unknown movement")
+	}
+
+	// 9d) Offline sync: a client ID is applied once; changed stock is listed
+	since := time.Now().Add(-time.Minute)
+	offline := &models.StockMovement{ProductID: id, Type: models.MovementIn, Units: 1, PerformedBy: "itest",
+		ClientID: tsPrefix + "-offline", CreatedAt: time.Now().Add(-time.Hour)}
+	if _, err := store.RecordMovement(offline); err != nil {
+		t.Fatalf("RecordMovement with client ID failed: %v", err)
+	}
+	again := *offline
+	again.ID = ""
+	if _, err := store.RecordMovement(&again); err == nil {
+		t.Fatalf("expected error when recording the same client ID twice")
+	}
+	byClient, err := store.GetMovementByClientID(offline.ClientID)
+	if err != nil || byClient.ID != offline.ID {
+		t.Fatalf("GetMovementByClientID: got %+v, err %v", byClient, err)
+	}
+	if _, err := store.GetMovementByClientID(tsPrefix + "-never-sent"); err == nil {
+		t.Fatalf("expected error for an unknown client ID")
+	}
+	if st, _ := store.GetStock(id); st.QuantityUnits != 6 {
+		t.Fatalf("duplicate client ID changed stock: %+v", st)
+	}
+	changed, err := store.ListStockChangedSince(since)
+	if err != nil || !hasStock(changed, id) {
+		t.Fatalf("ListStockChangedSince did not include the stock just changed (err %v)", err)
+	}
+	if later, _ := store.ListStockChangedSince(time.Now().Add(time.Hour)); hasStock(later, id) {
+		t.Fatalf("ListStockChangedSince included stock not changed since then")
+	}
+
+	// 10) SetMinStock (checking the stock version) and GetLowStockProducts
+	stockNow, err := store.GetStock(id)
+	if err != nil {
+		t.Fatalf("GetStock failed: %v", err)
+	}
+	seen := stockNow.Version
+	if err := store.SetMinStock(id, 1000, seen); err != nil {
 		t.Fatalf("SetMinStock failed: %v", err)
 	}
+	if err := store.SetMinStock(id, 5, seen); err == nil {
+		t.Fatalf("expected version conflict when setting min stock from a stale version")
+	}
 	lows := store.GetLowStockProducts()
 	found = false
 	for _, r := range lows {
@@ -156,7 +370,10 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 	}
 
 	// 11) DeleteProduct (soft-delete) and verify IsActive=false
-	if err := store.DeleteProduct(id); err != nil {
+	if err := store.DeleteProduct(id, 1); err == nil {
+		t.Fatalf("expected version conflict when deleting a stale product")
+	}
+	if err := store.DeleteProduct(id, 2); err != nil {
 		t.Fatalf("DeleteProduct failed: %v", err)
 	}
 	afterDel, err := store.GetProduct(id)
@@ -166,6 +383,23 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 	if afterDel.IsActive {
 		t.Fatalf("expected product to be inactive after DeleteProduct")
 	}
+	archivedMove, _ := models.NewStockMovement(id, models.MovementIn, 1, 0, "Yossi", "", "")
+	if _, err := store.RecordMovement(archivedMove); err == nil {
+		t.Fatalf("expected error when recording a movement of a deleted product")
+	}
+	changedProducts, err := store.ListProductsChangedSince(since)
+	if err != nil {
+		t.Fatalf("ListProductsChangedSince failed: %v", err)
+	}
+	found = false
+	for _, p := range changedProducts {
+		if p.ID == id {
+			found = !p.IsActive
+		}
+	}
+	if !found {
+		t.Fatalf("ListProductsChangedSince did not include the deleted product")
+	}
 
 	// 12) Search for non-existent
 	empt := store.SearchProducts("no-such-product-xyz")
@@ -173,3 +407,26 @@ func RunStoreIntegrationTests(t *testing.T, newStore func(*sql.DB) Store, db *sq
 		t.Fatalf("expected empty search results for non-matching query, got %d", len(empt))
 	}
 }
+
+// hasStock reports whether the stock of productID is in the list
+func hasStock(stocks []*models.Stock, productID string) bool {
+	for _, st := range stocks {
+		if st.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// withCheckDigit appends the GS1 mod-10 check digit to body
+func withCheckDigit(body string) string {
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		digit := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return fmt.Sprintf("%s%d", body, (10-sum%10)%10)
+}
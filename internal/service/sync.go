@@ -0,0 +1,239 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/staff"
+)
+
+// ============================================
+// OFFLINE SYNC: movements logged without signal
+// ============================================
+// A phone in the basement storeroom queues movements with IDs and times of
+// its own. Back in range it sends the queue as one batch: movements are
+// applied in the order sent, a clientId seen before is reported as a
+// duplicate (the batch may be sent twice), and the answer carries the
+// products and stock that changed since the phone's last sync token.
+
+var (
+	ErrInvalidSyncToken  = errors.New("invalid sync token")
+	ErrSyncBatchTooLarge = fmt.Errorf("at most %d movements per sync", MaxSyncBatch)
+)
+
+// MaxSyncBatch is the most movements one sync may carry
+const MaxSyncBatch = 500
+
+// syncOverlap rewinds the sync token, so a change committed while the
+// delta was being read comes again next time instead of never
+const syncOverlap = 5 * time.Second
+
+// Result statuses of a synced movement
+const (
+	SyncApplied   = "applied"
+	SyncDuplicate = "duplicate" // Its clientId was synced before; nothing changed
+	SyncRejected  = "rejected"  // See Error and Message; nothing changed
+)
+
+// SyncMovement is a movement logged offline
+type SyncMovement struct {
+	ClientID    string    `json:"clientId"` // Made up by the device (a UUID); required
+	ProductID   string    `json:"productId"`
+	Type        string    `json:"type"`
+	Boxes       int       `json:"boxes"`
+	Units       int       `json:"units"`
+	PerformedBy string    `json:"performedBy"`
+	ReportedBy  string    `json:"reportedBy"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"createdAt"` // Device clock; empty = now
+
+	// Staff directory IDs; take precedence over the names
+	PerformedByID string `json:"performedById"`
+	ReportedByID  string `json:"reportedById"`
+}
+
+// SyncRequest is one batch from a device
+type SyncRequest struct {
+	Since     string         `json:"since"` // syncToken of the last answer; empty = send everything
+	Movements []SyncMovement `json:"movements"`
+}
+
+// SyncResult is what happened to one movement
+type SyncResult struct {
+	ClientID   string `json:"clientId"`
+	Status     string `json:"status"`               // applied, duplicate, rejected
+	MovementID string `json:"movementId,omitempty"` // Server ID (applied and duplicate)
+	Error      string `json:"error,omitempty"`      // insufficient_stock, not_found, forbidden, ...
+	Message    string `json:"message,omitempty"`
+}
+
+// SyncResponse is the answer to a batch
+type SyncResponse struct {
+	Results   []SyncResult      `json:"results"`  // One per movement, in order
+	Products  []*models.Product `json:"products"` // Changed since the token (deleted ones too)
+	Stock     []*models.Stock   `json:"stock"`    // Changed since the token
+	SyncToken string            `json:"syncToken"`
+}
+
+// MovementCheck says whether the caller may record a movement; nil = yes
+type MovementCheck func(m *models.StockMovement) error
+
+// SyncService applies offline batches
+type SyncService struct {
+	store repository.Repository
+	now   func() time.Time
+}
+
+// NewSyncService creates a sync service
+func NewSyncService(store repository.Repository) *SyncService {
+	return &SyncService{store: store, now: time.Now}
+}
+
+// Sync applies the movements of a batch in order and returns the results
+// with the delta since req.Since. Movements the caller may not record
+// (check) or that don't fit the stock are rejected one by one; an error
+// means the store failed, and the device should send the batch again.
+func (s *SyncService) Sync(req SyncRequest, check MovementCheck) (*SyncResponse, error) {
+	since, err := parseSyncToken(req.Since)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Movements) > MaxSyncBatch {
+		return nil, ErrSyncBatchTooLarge
+	}
+
+	resp := &SyncResponse{Results: make([]SyncResult, 0, len(req.Movements))}
+	for _, in := range req.Movements {
+		res, err := s.apply(in, check)
+		if err != nil {
+			return nil, fmt.Errorf("movement %s: %w", in.ClientID, err)
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	now := s.now()
+	if !since.IsZero() {
+		since = since.Add(-syncOverlap)
+	}
+	// Without the whole delta the token must not move: the device would
+	// never see what it missed. Movements already applied come back as
+	// duplicates when the batch is sent again.
+	if resp.Products, err = s.store.ListProductsChangedSince(since); err != nil {
+		return nil, fmt.Errorf("changed products: %w", err)
+	}
+	if resp.Stock, err = s.store.ListStockChangedSince(since); err != nil {
+		return nil, fmt.Errorf("changed stock: %w", err)
+	}
+	resp.SyncToken = formatSyncToken(now)
+	return resp, nil
+}
+
+// apply records one movement unless it was synced before
+func (s *SyncService) apply(in SyncMovement, check MovementCheck) (SyncResult, error) {
+	res := SyncResult{ClientID: in.ClientID}
+	if in.ClientID == "" {
+		return reject(res, "validation_error", "clientId is required"), nil
+	}
+	if m, err := s.store.GetMovementByClientID(in.ClientID); err == nil {
+		res.Status, res.MovementID = SyncDuplicate, m.ID
+		return res, nil
+	} else if !errors.Is(err, repository.ErrMovementNotFound) {
+		return res, err
+	}
+
+	if in.PerformedBy == "" && in.PerformedByID != "" {
+		st, err := s.store.GetStaff(in.PerformedByID)
+		if err != nil {
+			return reject(res, "unknown_staff", fmt.Sprintf("%v: %s", staff.ErrUnknownStaff, in.PerformedByID)), nil
+		}
+		in.PerformedBy = st.DisplayName()
+	}
+	m, err := models.NewStockMovement(in.ProductID, in.Type, in.Boxes, in.Units, in.PerformedBy, in.ReportedBy, in.Reason)
+	if err != nil {
+		return reject(res, "validation_error", err.Error()), nil
+	}
+	m.PerformedByID, m.ReportedByID = in.PerformedByID, in.ReportedByID
+	m.ClientID, m.Source = in.ClientID, models.SourceOffline
+	if now := s.now(); !in.CreatedAt.IsZero() && in.CreatedAt.Before(now) {
+		m.CreatedAt = in.CreatedAt // A clock running ahead gets the server time
+	} else {
+		m.CreatedAt = now
+	}
+	if check != nil {
+		if err := check(m); err != nil {
+			return reject(res, "forbidden", err.Error()), nil
+		}
+	}
+
+	if _, err := s.store.RecordMovement(m); err != nil {
+		if errors.Is(err, repository.ErrMovementExists) {
+			// Same clientId in a batch running at the same time
+			existing, getErr := s.store.GetMovementByClientID(in.ClientID)
+			if getErr != nil {
+				return res, getErr
+			}
+			res.Status, res.MovementID = SyncDuplicate, existing.ID
+			return res, nil
+		}
+		code, ok := rejectionCode(err)
+		if !ok {
+			return res, err
+		}
+		return reject(res, code, err.Error()), nil
+	}
+	res.Status, res.MovementID = SyncApplied, m.ID
+	return res, nil
+}
+
+// reject marks a result as rejected
+func reject(res SyncResult, code, message string) SyncResult {
+	res.Status, res.Error, res.Message = SyncRejected, code, message
+	return res
+}
+
+// rejectionCode names the RecordMovement errors that reject one movement;
+// anything else is a store failure
+func rejectionCode(err error) (string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return "insufficient_stock", true
+	case errors.Is(err, repository.ErrStockNotFound):
+		return "not_found", true
+	case errors.Is(err, repository.ErrProductInactive):
+		return "product_inactive", true
+	case errors.Is(err, staff.ErrUnknownStaff), errors.Is(err, staff.ErrAmbiguousStaff):
+		return "unknown_staff", true
+	case errors.Is(err, staff.ErrStaffInactive):
+		return "staff_inactive", true
+	case errors.Is(err, models.ErrMovementInvalidType), errors.Is(err, models.ErrMovementNoQuantity),
+		errors.Is(err, models.ErrMovementNoPerformer), errors.Is(err, models.ErrMovementClientID):
+		return "validation_error", true
+	}
+	return "", false
+}
+
+// formatSyncToken turns a server time into an opaque token
+func formatSyncToken(t time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.UnixNano(), 10)))
+}
+
+// parseSyncToken reads a token back; empty means "never synced"
+func parseSyncToken(token string) (time.Time, error) {
+	if token == "" {
+		return time.Time{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, ErrInvalidSyncToken
+	}
+	nanos, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || nanos <= 0 {
+		return time.Time{}, ErrInvalidSyncToken
+	}
+	return time.Unix(0, nanos), nil
+}
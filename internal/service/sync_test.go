@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func TestSyncAppliesBatchOnce(t *testing.T) {
+	store := repository.NewMemoryStore()
+	colaID, err := store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	s := NewSyncService(store)
+
+	loggedAt := time.Now().Add(-2 * time.Hour)
+	req := SyncRequest{Movements: []SyncMovement{
+		{ClientID: "c1", ProductID: colaID, Type: models.MovementIn, Units: 10, PerformedBy: "יוסף", CreatedAt: loggedAt},
+		{ClientID: "c2", ProductID: colaID, Type: models.MovementOut, Units: 4, PerformedBy: "יוסף"},
+		{ClientID: "c3", ProductID: colaID, Type: models.MovementOut, Units: 50, PerformedBy: "יוסף"},
+		{ClientID: "c4", ProductID: "PROD-999", Type: models.MovementIn, Units: 1, PerformedBy: "יוסף"},
+		{ProductID: colaID, Type: models.MovementIn, Units: 1, PerformedBy: "יוסף"},
+		{ClientID: "c1", ProductID: colaID, Type: models.MovementIn, Units: 10, PerformedBy: "יוסף"},
+	}}
+	resp, err := s.Sync(req, nil)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	want := []struct{ status, code string }{
+		{SyncApplied, ""},
+		{SyncApplied, ""},
+		{SyncRejected, "insufficient_stock"},
+		{SyncRejected, "not_found"},
+		{SyncRejected, "validation_error"},
+		{SyncDuplicate, ""},
+	}
+	for i, w := range want {
+		if got := resp.Results[i]; got.Status != w.status || got.Error != w.code {
+			t.Errorf("result %d: got %s/%s, want %s/%s (%s)", i, got.Status, got.Error, w.status, w.code, got.Message)
+		}
+	}
+	if st, _ := store.GetStock(colaID); st.QuantityUnits != 6 {
+		t.Errorf("stock units = %d, want 6", st.QuantityUnits)
+	}
+	if m, _ := store.GetMovementByClientID("c1"); !m.CreatedAt.Equal(loggedAt) || m.Source != models.SourceOffline {
+		t.Errorf("offline movement should keep the device time and source: %+v", m)
+	}
+	if len(resp.Products) != 1 || len(resp.Stock) != 1 || resp.SyncToken == "" {
+		t.Errorf("first sync should send everything: %d products, %d stock, token %q", len(resp.Products), len(resp.Stock), resp.SyncToken)
+	}
+
+	// The same batch again (the answer was lost): nothing changes
+	again, err := s.Sync(SyncRequest{Since: resp.SyncToken, Movements: req.Movements[:2]}, nil)
+	if err != nil {
+		t.Fatalf("Sync again failed: %v", err)
+	}
+	for i, r := range again.Results {
+		if r.Status != SyncDuplicate || r.MovementID != resp.Results[i].MovementID {
+			t.Errorf("resent result %d: %+v", i, r)
+		}
+	}
+	if st, _ := store.GetStock(colaID); st.QuantityUnits != 6 {
+		t.Errorf("stock units after resend = %d, want 6", st.QuantityUnits)
+	}
+}
+
+func TestSyncDelta(t *testing.T) {
+	store := repository.NewMemoryStore()
+	store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	fantaID, _ := store.AddProduct(&models.Product{Name: "פנטה", Brand: "Fanta", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	s := NewSyncService(store)
+
+	first, err := s.Sync(SyncRequest{}, nil)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(first.Products) != 2 || len(first.Stock) != 2 {
+		t.Fatalf("first sync: %d products, %d stock, want 2 and 2", len(first.Products), len(first.Stock))
+	}
+
+	// As if the device synced right now; then the fanta is deleted elsewhere
+	token := formatSyncToken(time.Now().Add(syncOverlap))
+	if err := store.DeleteProduct(fantaID, 0); err != nil {
+		t.Fatalf("DeleteProduct failed: %v", err)
+	}
+	delta, err := s.Sync(SyncRequest{Since: token}, nil)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(delta.Products) != 1 || delta.Products[0].ID != fantaID || delta.Products[0].IsActive {
+		t.Errorf("delta should carry only the deleted fanta, got %d products", len(delta.Products))
+	}
+	if len(delta.Stock) != 0 {
+		t.Errorf("no stock changed, got %d rows", len(delta.Stock))
+	}
+}
+
+func TestSyncRejections(t *testing.T) {
+	store := repository.NewMemoryStore()
+	colaID, _ := store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	s := NewSyncService(store)
+
+	if _, err := s.Sync(SyncRequest{Since: "not-a-token"}, nil); !errors.Is(err, ErrInvalidSyncToken) {
+		t.Errorf("bad token: expected ErrInvalidSyncToken, got %v", err)
+	}
+	if _, err := s.Sync(SyncRequest{Movements: make([]SyncMovement, MaxSyncBatch+1)}, nil); !errors.Is(err, ErrSyncBatchTooLarge) {
+		t.Errorf("big batch: expected ErrSyncBatchTooLarge, got %v", err)
+	}
+
+	noIn := func(m *models.StockMovement) error {
+		if m.Type == models.MovementIn {
+			return errors.New("role employee may not record IN movements")
+		}
+		return nil
+	}
+	resp, err := s.Sync(SyncRequest{Movements: []SyncMovement{
+		{ClientID: "c1", ProductID: colaID, Type: models.MovementIn, Units: 1, PerformedBy: "דני"},
+	}}, noIn)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if r := resp.Results[0]; r.Status != SyncRejected || r.Error != "forbidden" {
+		t.Errorf("forbidden movement: %+v", r)
+	}
+	if _, err := store.GetMovementByClientID("c1"); err == nil {
+		t.Errorf("a rejected movement must not be recorded")
+	}
+}
+
+// failingDeltaStore can't read what changed, like a database that is down
+type failingDeltaStore struct {
+	*repository.MemoryStore
+}
+
+var errDeltaDown = errors.New("database is down")
+
+func (failingDeltaStore) ListStockChangedSince(time.Time) ([]*models.Stock, error) {
+	return nil, errDeltaDown
+}
+
+func TestSyncFailsWithoutDelta(t *testing.T) {
+	store := repository.NewMemoryStore()
+	colaID, err := store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	req := SyncRequest{Movements: []SyncMovement{
+		{ClientID: "c1", ProductID: colaID, Type: models.MovementIn, Units: 10, PerformedBy: "יוסף"},
+	}}
+
+	// No token without the delta, so the device asks again from its old token
+	resp, err := NewSyncService(failingDeltaStore{store}).Sync(req, nil)
+	if !errors.Is(err, errDeltaDown) || resp != nil {
+		t.Fatalf("expected the store error and no response, got %+v, %v", resp, err)
+	}
+
+	// Sending the batch again doesn't record the movement twice
+	resp, err = NewSyncService(store).Sync(req, nil)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if resp.Results[0].Status != SyncDuplicate || len(resp.Stock) != 1 {
+		t.Fatalf("retry: got %+v", resp)
+	}
+}
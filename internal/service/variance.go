@@ -0,0 +1,192 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// ============================================
+// VARIANCE REPORT: theoretical vs actual usage
+// ============================================
+// Theoretical usage = what we SHOULD have used (from sales or recipes)
+// Actual usage      = what really left the shelf (counts or OUT/WASTE log)
+// A big positive gap means theft, over-portioning or unlogged waste.
+
+var (
+	ErrInvalidPeriod  = errors.New("period end must be after period start")
+	ErrNoUsageInput   = errors.New("at least one product usage is required")
+	ErrNegativeUsage  = errors.New("theoretical usage cannot be negative")
+	ErrCountsMismatch = errors.New("opening and closing counts must be given together")
+)
+
+// Sources for the actual usage figure
+const (
+	UsageSourceCounts    = "counts"    // opening + received - closing
+	UsageSourceMovements = "movements" // sum of OUT + WASTE movements
+)
+
+// UsageInput is the expected consumption of one product over the period
+type UsageInput struct {
+	ProductID        string  `json:"productId"`
+	TheoreticalUnits float64 `json:"theoreticalUnits"`       // From sales/recipes
+	OpeningCount     *int    `json:"openingCount,omitempty"` // Physical count at start (units)
+	ClosingCount     *int    `json:"closingCount,omitempty"` // Physical count at end (units)
+}
+
+// VarianceThresholds decide when a gap is suspicious
+// A zero value disables that threshold
+type VarianceThresholds struct {
+	Percent float64 `json:"percent"` // e.g. 5 = flag gaps above 5%
+	Value   float64 `json:"value"`   // e.g. 50 = flag gaps above 50 NIS
+}
+
+// VarianceLine is one product row in the report
+type VarianceLine struct {
+	ProductID        string  `json:"productId"`
+	ProductName      string  `json:"productName"`
+	TheoreticalUnits float64 `json:"theoreticalUnits"`
+	ActualUnits      float64 `json:"actualUnits"`
+	ActualSource     string  `json:"actualSource"`
+	ReceivedUnits    int     `json:"receivedUnits"`
+	OutUnits         int     `json:"outUnits"`
+	WasteUnits       int     `json:"wasteUnits"`
+	VarianceUnits    float64 `json:"varianceUnits"`   // actual - theoretical
+	VariancePercent  float64 `json:"variancePercent"` // of theoretical
+	VarianceValue    float64 `json:"varianceValue"`   // NIS, units × price
+	Flagged          bool    `json:"flagged"`
+}
+
+// VarianceReport is the full per-product report for a period
+type VarianceReport struct {
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Thresholds   VarianceThresholds `json:"thresholds"`
+	Lines        []VarianceLine     `json:"lines"`
+	TotalValue   float64            `json:"totalValue"` // Sum of VarianceValue
+	FlaggedCount int                `json:"flaggedCount"`
+	GeneratedAt  time.Time          `json:"generatedAt"`
+}
+
+// VarianceService builds variance reports from the repository
+type VarianceService struct {
+	store repository.Repository
+}
+
+// NewVarianceService creates a VarianceService backed by the given store
+func NewVarianceService(store repository.Repository) *VarianceService {
+	return &VarianceService{store: store}
+}
+
+// Report compares theoretical and actual usage for every input product
+// over [from, to). Products without counts fall back to the movement log.
+func (s *VarianceService) Report(from, to time.Time, inputs []UsageInput, th VarianceThresholds) (*VarianceReport, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidPeriod
+	}
+	if len(inputs) == 0 {
+		return nil, ErrNoUsageInput
+	}
+
+	report := &VarianceReport{
+		From:        from,
+		To:          to,
+		Thresholds:  th,
+		Lines:       make([]VarianceLine, 0, len(inputs)),
+		GeneratedAt: time.Now(),
+	}
+
+	for _, in := range inputs {
+		line, err := s.line(from, to, in, th)
+		if err != nil {
+			return nil, fmt.Errorf("product %s: %w", in.ProductID, err)
+		}
+		report.Lines = append(report.Lines, line)
+		report.TotalValue += line.VarianceValue
+		if line.Flagged {
+			report.FlaggedCount++
+		}
+	}
+	report.TotalValue = roundTo(report.TotalValue, 2)
+
+	return report, nil
+}
+
+// line calculates the variance row for a single product
+func (s *VarianceService) line(from, to time.Time, in UsageInput, th VarianceThresholds) (VarianceLine, error) {
+	if in.TheoreticalUnits < 0 {
+		return VarianceLine{}, ErrNegativeUsage
+	}
+	if (in.OpeningCount == nil) != (in.ClosingCount == nil) {
+		return VarianceLine{}, ErrCountsMismatch
+	}
+
+	product, err := s.store.GetProduct(in.ProductID)
+	if err != nil {
+		return VarianceLine{}, err
+	}
+
+	line := VarianceLine{
+		ProductID:        product.ID,
+		ProductName:      product.Name,
+		TheoreticalUnits: in.TheoreticalUnits,
+	}
+
+	// Sum the movement log for the period, converted to units
+	movements := s.store.ListMovements(repository.MovementFilter{ProductID: product.ID, From: from, To: to})
+	for _, m := range movements {
+		units := m.TotalUnits(product.BoxSize)
+		switch m.Type {
+		case models.MovementIn:
+			line.ReceivedUnits += units
+		case models.MovementOut:
+			line.OutUnits += units
+		case models.MovementWaste:
+			line.WasteUnits += units
+		}
+	}
+
+	// Physical counts win over the log - they catch what nobody logged.
+	// Adjustments are ignored here: a count already reflects the real shelf.
+	if in.OpeningCount != nil {
+		line.ActualUnits = float64(*in.OpeningCount + line.ReceivedUnits - *in.ClosingCount)
+		line.ActualSource = UsageSourceCounts
+	} else {
+		line.ActualUnits = float64(line.OutUnits + line.WasteUnits)
+		line.ActualSource = UsageSourceMovements
+	}
+
+	line.VarianceUnits = roundTo(line.ActualUnits-line.TheoreticalUnits, 3)
+	line.VarianceValue = roundTo(line.VarianceUnits*product.Price, 2)
+	if line.TheoreticalUnits > 0 {
+		line.VariancePercent = roundTo(line.VarianceUnits/line.TheoreticalUnits*100, 2)
+	}
+	line.Flagged = isSuspicious(line, th)
+
+	return line, nil
+}
+
+// isSuspicious applies the thresholds to a variance line
+// Any usage with zero theoretical usage counts as 100% over.
+func isSuspicious(line VarianceLine, th VarianceThresholds) bool {
+	if th.Value > 0 && math.Abs(line.VarianceValue) > th.Value {
+		return true
+	}
+	if th.Percent > 0 {
+		if line.TheoreticalUnits == 0 {
+			return line.ActualUnits > 0
+		}
+		return math.Abs(line.VariancePercent) > th.Percent
+	}
+	return false
+}
+
+// roundTo rounds a float to the given number of decimal places
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+func TestVarianceReport(t *testing.T) {
+	store := repository.NewMemoryStore()
+	colaID, err := store.AddProduct(&models.Product{Name: "קולה", Brand: "Coca Cola", Size: 330, ContainerType: "can", BoxSize: 24, Price: 5, Category: "drinks"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	hummusID, err := store.AddProduct(&models.Product{Name: "חומוס", Brand: "עשי", Size: 400, ContainerType: "can", BoxSize: 12, Price: 8, Category: "canned"})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+
+	from := time.Now().Add(-time.Minute)
+	movements := []*models.StockMovement{
+		{ProductID: colaID, Type: models.MovementIn, Boxes: 2, PerformedBy: "יוסף"},
+		{ProductID: colaID, Type: models.MovementOut, Boxes: 1, PerformedBy: "יוסף"},
+		{ProductID: colaID, Type: models.MovementIn, Units: 10, PerformedBy: "יוסף"},
+		{ProductID: colaID, Type: models.MovementWaste, Units: 2, PerformedBy: "יוסף"},
+		{ProductID: hummusID, Type: models.MovementIn, Boxes: 1, PerformedBy: "דני"},
+	}
+	for _, m := range movements {
+		if _, err := store.RecordMovement(m); err != nil {
+			t.Fatalf("RecordMovement failed: %v", err)
+		}
+	}
+	to := time.Now().Add(time.Minute)
+
+	// Cola: no counts -> OUT 24 + WASTE 2 = 26 actual vs 20 theoretical
+	// Hummus: counts 3 + received 12 - 10 left = 5 actual vs 5 theoretical
+	opening, closing := 3, 10
+	report, err := NewVarianceService(store).Report(from, to, []UsageInput{
+		{ProductID: colaID, TheoreticalUnits: 20},
+		{ProductID: hummusID, TheoreticalUnits: 5, OpeningCount: &opening, ClosingCount: &closing},
+	}, VarianceThresholds{Percent: 10})
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	cola := report.Lines[0]
+	if cola.ActualSource != UsageSourceMovements || cola.ActualUnits != 26 || cola.VarianceUnits != 6 {
+		t.Fatalf("unexpected cola line: %+v", cola)
+	}
+	if cola.VarianceValue != 30 || cola.VariancePercent != 30 || !cola.Flagged {
+		t.Fatalf("unexpected cola variance: %+v", cola)
+	}
+
+	hummus := report.Lines[1]
+	if hummus.ActualSource != UsageSourceCounts || hummus.ActualUnits != 5 || hummus.Flagged {
+		t.Fatalf("unexpected hummus line: %+v", hummus)
+	}
+	if report.FlaggedCount != 1 || report.TotalValue != 30 {
+		t.Fatalf("unexpected totals: flagged=%d total=%v", report.FlaggedCount, report.TotalValue)
+	}
+
+	// Edge cases
+	if _, err := NewVarianceService(store).Report(to, from, []UsageInput{{ProductID: colaID}}, VarianceThresholds{}); !errors.Is(err, ErrInvalidPeriod) {
+		t.Fatalf("expected ErrInvalidPeriod, got %v", err)
+	}
+	if _, err := NewVarianceService(store).Report(from, to, []UsageInput{{ProductID: hummusID, OpeningCount: &opening}}, VarianceThresholds{}); !errors.Is(err, ErrCountsMismatch) {
+		t.Fatalf("expected ErrCountsMismatch, got %v", err)
+	}
+	if _, err := NewVarianceService(store).Report(from, to, []UsageInput{{ProductID: "PROD-999"}}, VarianceThresholds{}); !errors.Is(err, repository.ErrProductNotFound) {
+		t.Fatalf("expected ErrProductNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+// Package staff keeps the staff directory: the people who perform and
+// report stock movements. Free-text names ("Yosef", "yosef", "יוסף")
+// are resolved to one staff ID, so reports count one person once.
+package staff
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+var (
+	ErrUnknownStaff   = errors.New("no staff member matches this name")
+	ErrAmbiguousStaff = errors.New("name matches more than one staff member")
+	ErrStaffInactive  = errors.New("staff member is no longer active")
+)
+
+// A name is linked automatically only with a score of at least
+// linkScore that beats the runner-up by clearMargin
+const (
+	linkScore   = 0.75
+	clearMargin = 0.1
+)
+
+// Match is a staff member that a name could refer to
+type Match struct {
+	Staff *models.Staff `json:"staff"`
+	Score float64       `json:"score"`
+}
+
+// Service resolves names to staff members
+type Service struct {
+	store repository.Repository
+}
+
+// NewService creates a staff directory service
+func NewService(store repository.Repository) *Service {
+	return &Service{store: store}
+}
+
+// ============================================
+// MATCHING
+// ============================================
+
+// nameScore rates a name against both names of a staff member
+func nameScore(name string, st *models.Staff) float64 {
+	best := 0.0
+	for _, candidate := range []string{st.NameHe, st.NameEn} {
+		if candidate != "" {
+			best = max(best, repository.TextScore(name, candidate))
+		}
+	}
+	return best
+}
+
+// rank returns the staff members a name could refer to, best first
+func rank(name string, staff []*models.Staff) []Match {
+	var matches []Match
+	for _, st := range staff {
+		if score := nameScore(name, st); score >= repository.MinSearchScore {
+			matches = append(matches, Match{Staff: st, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// pick returns the clear winner among matches
+func pick(name string, matches []Match) (*models.Staff, float64, error) {
+	if len(matches) == 0 || matches[0].Score < linkScore {
+		return nil, 0, fmt.Errorf("%w: %q", ErrUnknownStaff, name)
+	}
+	if len(matches) > 1 && matches[0].Score-matches[1].Score < clearMargin {
+		names := make([]string, 0, len(matches))
+		for _, m := range matches {
+			names = append(names, m.Staff.DisplayName())
+		}
+		return nil, 0, fmt.Errorf("%w: %q could be %s", ErrAmbiguousStaff, name, strings.Join(names, ", "))
+	}
+	return matches[0].Staff, matches[0].Score, nil
+}
+
+// activeStaff returns the staff members who can be put on new movements
+func (s *Service) activeStaff() []*models.Staff {
+	var active []*models.Staff
+	for _, st := range s.store.ListStaff() {
+		if st.IsActive {
+			active = append(active, st)
+		}
+	}
+	return active
+}
+
+// Match returns the active staff members a name could refer to, best first
+func (s *Service) Match(name string) []Match {
+	return rank(name, s.activeStaff())
+}
+
+// Resolve finds the one active staff member a name refers to.
+// A login name resolves to the staff member linked to that account.
+func (s *Service) Resolve(name string) (*models.Staff, error) {
+	return s.resolve(name, s.activeStaff())
+}
+
+func (s *Service) resolve(name string, active []*models.Staff) (*models.Staff, error) {
+	if u, err := s.store.GetUserByUsername(name); err == nil {
+		st, err := s.LinkUser(u)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsActive {
+			return nil, fmt.Errorf("%w: %s", ErrStaffInactive, st.DisplayName())
+		}
+		return st, nil
+	}
+	st, _, err := pick(name, rank(name, active))
+	return st, err
+}
+
+// find returns the staff member for an ID, or else for a name
+func (s *Service) find(id, name string, active []*models.Staff) (*models.Staff, error) {
+	if id == "" {
+		return s.resolve(name, active)
+	}
+	st, err := s.store.GetStaff(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrStaffNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStaff, id)
+		}
+		return nil, err
+	}
+	if !st.IsActive {
+		return nil, fmt.Errorf("%w: %s", ErrStaffInactive, st.DisplayName())
+	}
+	return st, nil
+}
+
+// Bind links a new movement to the staff directory: PerformedBy and
+// ReportedBy (IDs or names) are resolved to staff IDs, and the names are
+// rewritten to the directory spelling. Until the first staff member is
+// added, movements keep their free-text names.
+func (s *Service) Bind(m *models.StockMovement) error {
+	active := s.activeStaff()
+	if len(active) == 0 && m.PerformedByID == "" && m.ReportedByID == "" {
+		return nil
+	}
+
+	performer, err := s.find(m.PerformedByID, m.PerformedBy, active)
+	if err != nil {
+		return fmt.Errorf("performedBy: %w", err)
+	}
+	reporter := performer
+	if m.ReportedByID != "" || (m.ReportedBy != "" && m.ReportedBy != m.PerformedBy) {
+		if reporter, err = s.find(m.ReportedByID, m.ReportedBy, active); err != nil {
+			return fmt.Errorf("reportedBy: %w", err)
+		}
+	}
+
+	m.PerformedByID, m.PerformedBy = performer.ID, performer.DisplayName()
+	m.ReportedByID, m.ReportedBy = reporter.ID, reporter.DisplayName()
+	return nil
+}
+
+// LinkUser returns the staff member of a login account. An account
+// without one is linked to the unlinked staff member matching its
+// display name, or gets a new staff record.
+func (s *Service) LinkUser(u *models.User) (*models.Staff, error) {
+	if st, err := s.store.GetStaffByUser(u.ID); err == nil {
+		return st, nil
+	} else if !errors.Is(err, repository.ErrStaffNotFound) {
+		return nil, err
+	}
+
+	name := u.DisplayName
+	if name == "" {
+		name = u.Username
+	}
+	var unlinked []*models.Staff
+	for _, st := range s.activeStaff() {
+		if st.UserID == "" {
+			unlinked = append(unlinked, st)
+		}
+	}
+	if st, _, err := pick(name, rank(name, unlinked)); err == nil {
+		st.UserID = u.ID
+		if err := s.store.UpdateStaff(st); err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+
+	st := newStaff(name)
+	st.UserID = u.ID
+	if _, err := s.store.AddStaff(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// newStaff creates an active staff record, putting the name in the
+// Hebrew or English field by its script
+func newStaff(name string) *models.Staff {
+	st := &models.Staff{IsActive: true}
+	if isHebrew(name) {
+		st.NameHe = strings.TrimSpace(name)
+	} else {
+		st.NameEn = strings.TrimSpace(name)
+	}
+	return st
+}
+
+// isHebrew reports whether text contains Hebrew letters
+func isHebrew(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Hebrew, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================
+// BACKFILL: link movements logged with free-text names
+// ============================================
+
+// NameLink is where one free-text name was linked
+type NameLink struct {
+	Name      string  `json:"name"`      // Most common spelling
+	Spellings int     `json:"spellings"` // Spellings that normalize the same ("Yosef", "yosef")
+	Count     int     `json:"count"`     // Times it appears on movements
+	StaffID   string  `json:"staffId"`
+	StaffName string  `json:"staffName"`
+	Score     float64 `json:"score"`   // Match score (1 = same name)
+	Created   bool    `json:"created"` // No staff member matched: a new one was added
+}
+
+// BackfillReport summarizes a backfill run
+type BackfillReport struct {
+	DryRun    bool       `json:"dryRun"`
+	Names     []NameLink `json:"names"`
+	Created   int        `json:"created"`   // New staff records
+	Movements int        `json:"movements"` // Movements linked
+}
+
+// nameGroup collects the spellings of one normalized name
+type nameGroup struct {
+	key       string
+	count     int
+	spellings map[string]int
+}
+
+// best returns the most common spelling (alphabetical on ties)
+func (g *nameGroup) best() string {
+	best := ""
+	for name, n := range g.spellings {
+		if best == "" || n > g.spellings[best] || (n == g.spellings[best] && name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+// Backfill links movements that have no staff IDs. Names are grouped by
+// their normalized form, matched to the directory (inactive staff too -
+// old movements may be theirs), and names matching nobody get a new staff
+// record. Most common names go first, so later typos match them.
+// With dryRun nothing is written; the report shows what would happen.
+func (s *Service) Backfill(dryRun bool) (*BackfillReport, error) {
+	if !dryRun {
+		for _, u := range s.store.ListUsers() {
+			if _, err := s.LinkUser(u); err != nil {
+				return nil, fmt.Errorf("link user %s: %w", u.Username, err)
+			}
+		}
+	}
+
+	movements := s.store.ListMovements(repository.MovementFilter{})
+	groups := map[string]*nameGroup{}
+	add := func(name string) {
+		key := repository.NormalizeSearchText(name)
+		if key == "" {
+			return
+		}
+		g := groups[key]
+		if g == nil {
+			g = &nameGroup{key: key, spellings: map[string]int{}}
+			groups[key] = g
+		}
+		g.count++
+		g.spellings[name]++
+	}
+	for _, m := range movements {
+		if m.PerformedByID == "" {
+			add(m.PerformedBy)
+		}
+		if m.ReportedByID == "" {
+			add(m.ReportedBy)
+		}
+	}
+
+	ordered := make([]*nameGroup, 0, len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].count != ordered[j].count {
+			return ordered[i].count > ordered[j].count
+		}
+		return ordered[i].key < ordered[j].key
+	})
+
+	report := &BackfillReport{DryRun: dryRun, Names: []NameLink{}}
+	directory := s.store.ListStaff()
+	linked := map[string]string{} // normalized name → staff ID
+	for _, g := range ordered {
+		name := g.best()
+		link := NameLink{Name: name, Spellings: len(g.spellings), Count: g.count}
+		st, score, err := pick(name, rank(name, directory))
+		if err != nil {
+			st, score = newStaff(name), 1
+			if dryRun {
+				st.ID = fmt.Sprintf("new-%d", report.Created+1)
+			} else if _, err := s.store.AddStaff(st); err != nil {
+				return nil, err
+			}
+			directory = append(directory, st)
+			link.Created = true
+			report.Created++
+		}
+		link.StaffID, link.StaffName, link.Score = st.ID, st.DisplayName(), score
+		linked[g.key] = st.ID
+		report.Names = append(report.Names, link)
+	}
+
+	for _, m := range movements {
+		performer, reporter := m.PerformedByID, m.ReportedByID
+		if performer == "" {
+			performer = linked[repository.NormalizeSearchText(m.PerformedBy)]
+		}
+		if reporter == "" {
+			reporter = linked[repository.NormalizeSearchText(m.ReportedBy)]
+			if m.ReportedBy == "" {
+				reporter = performer // Self-reported
+			}
+		}
+		if performer == m.PerformedByID && reporter == m.ReportedByID {
+			continue
+		}
+		if !dryRun {
+			if err := s.store.SetMovementStaff(m.ID, performer, reporter); err != nil {
+				return nil, err
+			}
+		}
+		report.Movements++
+	}
+	return report, nil
+}
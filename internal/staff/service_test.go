@@ -0,0 +1,180 @@
+package staff
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// newTestStore returns a staff-binding store with 50 loose cans of cola
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	repo := repository.NewMemoryStore()
+	id, err := repo.AddProduct(&models.Product{Name: "קולה", Size: 330, ContainerType: "can", BoxSize: 24, Price: 2, Category: "drinks", IsActive: true})
+	if err != nil {
+		t.Fatalf("AddProduct failed: %v", err)
+	}
+	if err := repo.UpdateStock(id, 0, 50); err != nil {
+		t.Fatalf("UpdateStock failed: %v", err)
+	}
+	return NewStore(repo), id
+}
+
+func addStaff(t *testing.T, store *Store, he, en string) *models.Staff {
+	t.Helper()
+	st := &models.Staff{NameHe: he, NameEn: en, IsActive: true}
+	if _, err := store.AddStaff(st); err != nil {
+		t.Fatalf("AddStaff failed: %v", err)
+	}
+	return st
+}
+
+func out(productID, performedBy, reportedBy string) *models.StockMovement {
+	return &models.StockMovement{ProductID: productID, Type: models.MovementOut, Units: 1, PerformedBy: performedBy, ReportedBy: reportedBy}
+}
+
+func TestBindWithoutDirectoryKeepsNames(t *testing.T) {
+	store, productID := newTestStore(t)
+	m := out(productID, "whoever", "")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	if m.PerformedByID != "" || m.PerformedBy != "whoever" {
+		t.Errorf("expected free-text movement, got %+v", m)
+	}
+}
+
+func TestBindResolvesSpellings(t *testing.T) {
+	store, productID := newTestStore(t)
+	yosef := addStaff(t, store, "יוסף", "Yosef")
+	dana := addStaff(t, store, "דנה", "Dana")
+
+	for _, name := range []string{"Yosef", "yosef", "יוסף", "Yossef"} {
+		m := out(productID, name, "dana")
+		if _, err := store.RecordMovement(m); err != nil {
+			t.Fatalf("RecordMovement(%q) failed: %v", name, err)
+		}
+		if m.PerformedByID != yosef.ID || m.PerformedBy != "יוסף" {
+			t.Errorf("%q: performer = %s %q, want %s", name, m.PerformedByID, m.PerformedBy, yosef.ID)
+		}
+		if m.ReportedByID != dana.ID {
+			t.Errorf("%q: reporter = %s, want %s", name, m.ReportedByID, dana.ID)
+		}
+	}
+
+	// No reporter: self-reported
+	m := out(productID, "Dana", "")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	if m.ReportedByID != dana.ID {
+		t.Errorf("self-reported: reporter = %s, want %s", m.ReportedByID, dana.ID)
+	}
+
+	got := store.ListMovements(repository.MovementFilter{StaffID: yosef.ID})
+	if len(got) != 4 {
+		t.Errorf("ListMovements by staff: got %d, want 4", len(got))
+	}
+}
+
+func TestBindRejectsUnknownAmbiguousAndInactive(t *testing.T) {
+	store, productID := newTestStore(t)
+	addStaff(t, store, "משה", "Moshe")
+	addStaff(t, store, "", "Dan Levi")
+	addStaff(t, store, "", "Dan Cohen")
+	gone := addStaff(t, store, "רינה", "Rina")
+	gone.IsActive = false
+	if err := store.UpdateStaff(gone); err != nil {
+		t.Fatalf("UpdateStaff failed: %v", err)
+	}
+
+	if _, err := store.RecordMovement(out(productID, "Avi", "")); !errors.Is(err, ErrUnknownStaff) {
+		t.Errorf("expected ErrUnknownStaff, got %v", err)
+	}
+	if _, err := store.RecordMovement(out(productID, "Dan", "")); !errors.Is(err, ErrAmbiguousStaff) {
+		t.Errorf("expected ErrAmbiguousStaff, got %v", err)
+	}
+	m := out(productID, "", "")
+	m.PerformedBy, m.PerformedByID = "x", gone.ID
+	if _, err := store.RecordMovement(m); !errors.Is(err, ErrStaffInactive) {
+		t.Errorf("expected ErrStaffInactive, got %v", err)
+	}
+	if got := store.ListMovements(repository.MovementFilter{}); len(got) != 0 {
+		t.Errorf("rejected movements were recorded: %d", len(got))
+	}
+}
+
+func TestReporterUsernameLinksAccount(t *testing.T) {
+	store, productID := newTestStore(t)
+	moshe := addStaff(t, store, "משה", "Moshe")
+	u := &models.User{Username: "dana", DisplayName: "דנה", PasswordHash: "x", Role: models.RoleEmployee, IsActive: true}
+	if _, err := store.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	m := out(productID, "Moshe", "dana")
+	if _, err := store.RecordMovement(m); err != nil {
+		t.Fatalf("RecordMovement failed: %v", err)
+	}
+	linked, err := store.GetStaffByUser(u.ID)
+	if err != nil {
+		t.Fatalf("expected a staff record for the account: %v", err)
+	}
+	if m.PerformedByID != moshe.ID || m.ReportedByID != linked.ID || m.ReportedBy != "דנה" {
+		t.Errorf("unexpected binding: %+v", m)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	repo := repository.NewMemoryStore()
+	productID, _ := repo.AddProduct(&models.Product{Name: "קולה", Size: 330, ContainerType: "can", BoxSize: 24, Price: 2, Category: "drinks", IsActive: true})
+	if err := repo.UpdateStock(productID, 0, 50); err != nil {
+		t.Fatalf("UpdateStock failed: %v", err)
+	}
+	// Logged before the directory existed
+	for _, name := range []string{"Yosef", "yosef", "יוסף", "Avi", "avi", "Avi"} {
+		if _, err := repo.RecordMovement(out(productID, name, "")); err != nil {
+			t.Fatalf("RecordMovement failed: %v", err)
+		}
+	}
+	yosef := &models.Staff{NameHe: "יוסף", NameEn: "Yosef", IsActive: true}
+	if _, err := repo.AddStaff(yosef); err != nil {
+		t.Fatalf("AddStaff failed: %v", err)
+	}
+	svc := NewService(repo)
+
+	dry, err := svc.Backfill(true)
+	if err != nil {
+		t.Fatalf("Backfill dry run failed: %v", err)
+	}
+	if dry.Movements != 6 || dry.Created != 1 || len(dry.Names) != 3 {
+		t.Fatalf("unexpected dry run report: %+v", dry)
+	}
+	if n := len(repo.ListStaff()); n != 1 {
+		t.Fatalf("dry run changed the directory: %d staff", n)
+	}
+
+	report, err := svc.Backfill(false)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if report.Movements != 6 || report.Created != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got := repo.ListMovements(repository.MovementFilter{StaffID: yosef.ID}); len(got) != 3 {
+		t.Errorf("Yosef/yosef/יוסף: got %d movements for %s, want 3", len(got), yosef.ID)
+	}
+	for _, m := range repo.ListMovements(repository.MovementFilter{}) {
+		if m.PerformedByID == "" || m.ReportedByID != m.PerformedByID {
+			t.Errorf("movement not linked: %+v", m)
+		}
+	}
+
+	// Nothing left to do the second time
+	again, err := svc.Backfill(false)
+	if err != nil || again.Movements != 0 || again.Created != 0 {
+		t.Errorf("second backfill: %+v, err %v", again, err)
+	}
+}
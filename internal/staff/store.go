@@ -0,0 +1,35 @@
+package staff
+
+import (
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/models"
+	"github.com/mennyaboush/restaurant-inventory-ai/internal/repository"
+)
+
+// Store wraps a repository so every movement recorded through it - by
+// the API, a barcode scan, the assistant or an invoice - is bound to the
+// staff directory before it is saved
+type Store struct {
+	repository.Repository
+	directory *Service
+}
+
+// NewStore wraps repo with staff binding
+func NewStore(repo repository.Repository) *Store {
+	return &Store{Repository: repo, directory: NewService(repo)}
+}
+
+// RecordMovement resolves the performer and reporter, then records.
+// Invalid movements go straight through to get the store's validation error.
+func (s *Store) RecordMovement(m *models.StockMovement) (string, error) {
+	if m.Validate() == nil {
+		if err := s.directory.Bind(m); err != nil {
+			return "", err
+		}
+	}
+	return s.Repository.RecordMovement(m)
+}
+
+// WithActor keeps staff binding on the store that records actor
+func (s *Store) WithActor(actor string) repository.Repository {
+	return &Store{Repository: s.Repository.WithActor(actor), directory: s.directory}
+}
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- Where a movement was logged from: typed by a person or proposed by the AI assistant
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual';
+
+-- +migrate Down
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS source;
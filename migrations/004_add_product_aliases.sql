@@ -0,0 +1,15 @@
+-- +migrate Up
+-- Other names staff use for a product (nicknames, transliterations, slang)
+CREATE TABLE IF NOT EXISTS product_aliases (
+    id VARCHAR(50) PRIMARY KEY,
+    product_id VARCHAR(50) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    normalized VARCHAR(100) NOT NULL, -- NormalizeSearchText(alias), for uniqueness
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The same alias can't be added twice to one product
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_aliases_unique ON product_aliases (product_id, normalized);
+
+-- +migrate Down
+DROP TABLE IF EXISTS product_aliases;
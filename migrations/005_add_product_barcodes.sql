@@ -0,0 +1,13 @@
+-- +migrate Up
+-- EAN/UPC barcodes per product; pack_level says if one scan is a unit or a case (box)
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    code VARCHAR(14) PRIMARY KEY, -- Normalized: UPC-A stored as EAN-13 with leading 0
+    product_id VARCHAR(50) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    pack_level VARCHAR(10) NOT NULL CHECK (pack_level IN ('unit', 'case')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_product ON product_barcodes (product_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS product_barcodes;
@@ -0,0 +1,13 @@
+-- +migrate Up
+-- Supplier item codes (מק"ט) mapped to our products, for matching invoice lines
+CREATE TABLE IF NOT EXISTS supplier_skus (
+    supplier VARCHAR(100) NOT NULL,
+    sku VARCHAR(50) NOT NULL,
+    product_id VARCHAR(50) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    pack_level VARCHAR(10) NOT NULL CHECK (pack_level IN ('unit', 'case')),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (supplier, sku)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS supplier_skus;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- Roles: owner (everything), manager (products, IN/OUT), employee (OUT only)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'employee'
+    CHECK (role IN ('owner', 'manager', 'employee'));
+
+-- Existing installs: the first account (created during setup) becomes the owner
+UPDATE users SET role = 'owner'
+WHERE id = (SELECT id FROM users ORDER BY created_at, id LIMIT 1)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'owner');
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,26 @@
+-- +migrate Up
+-- Staff directory: the people who move stock (with or without a login)
+CREATE TABLE IF NOT EXISTS staff (
+    id VARCHAR(50) PRIMARY KEY,
+    name_he VARCHAR(100) NOT NULL DEFAULT '',
+    name_en VARCHAR(100) NOT NULL DEFAULT '',
+    user_id VARCHAR(50) NULL UNIQUE REFERENCES users(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Movements point to staff; NULL on rows logged before the directory
+-- (POST /staff/backfill links them by fuzzy name matching)
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS performed_by_id VARCHAR(50) NULL REFERENCES staff(id);
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS reported_by_id VARCHAR(50) NULL REFERENCES staff(id);
+
+CREATE INDEX IF NOT EXISTS idx_movements_performed_by ON stock_movements (performed_by_id);
+CREATE INDEX IF NOT EXISTS idx_movements_reported_by ON stock_movements (reported_by_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_movements_reported_by;
+DROP INDEX IF EXISTS idx_movements_performed_by;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS reported_by_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS performed_by_id;
+DROP TABLE IF EXISTS staff;
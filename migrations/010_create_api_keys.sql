@@ -0,0 +1,15 @@
+-- +migrate Up
+-- Keys for machine integrations (POS, scripts); only the hash is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '', -- Space-separated: "inventory:view movements:out"
+    created_by VARCHAR(50) NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS api_keys;
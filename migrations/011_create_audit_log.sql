@@ -0,0 +1,17 @@
+-- +migrate Up
+-- Who changed products and settings, with a field-level before/after diff
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(50) PRIMARY KEY,
+    entity VARCHAR(30) NOT NULL,    -- "product"
+    entity_id VARCHAR(50) NOT NULL, -- No FK: entries outlive what they describe
+    action VARCHAR(30) NOT NULL,    -- "create", "update", "delete", "set_min_stock"
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    changes JSONB NOT NULL DEFAULT '[]', -- [{"Field", "Before", "After"}]
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_log (entity, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_log (actor, created_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS audit_log;
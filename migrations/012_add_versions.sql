@@ -0,0 +1,9 @@
+-- +migrate Up
+-- Optimistic concurrency: every change bumps the version, which the API
+-- returns as the ETag; a PUT/DELETE with a stale If-Match gets 412
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- +migrate Down
+ALTER TABLE stocks DROP COLUMN IF EXISTS version;
+ALTER TABLE products DROP COLUMN IF EXISTS version;
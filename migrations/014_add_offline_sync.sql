@@ -0,0 +1,13 @@
+-- +migrate Up
+-- Offline sync: movements carry the ID the device made up (a batch sent
+-- twice is applied once), and clients fetch what changed since their last sync
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_movements_client_id ON stock_movements (client_id);
+CREATE INDEX IF NOT EXISTS idx_products_updated_at ON products (updated_at);
+CREATE INDEX IF NOT EXISTS idx_stocks_last_updated ON stocks (last_updated);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_stocks_last_updated;
+DROP INDEX IF EXISTS idx_products_updated_at;
+DROP INDEX IF EXISTS idx_movements_client_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS client_id;
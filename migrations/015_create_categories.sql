@@ -0,0 +1,37 @@
+-- +migrate Up
+-- Product categories as data (they were a map in the code). Products keep
+-- the category ID in products.category; the app checks it exists.
+CREATE TABLE IF NOT EXISTS categories (
+    id VARCHAR(50) PRIMARY KEY,
+    name_he VARCHAR(100) NOT NULL DEFAULT '',
+    name_en VARCHAR(100) NOT NULL DEFAULT '',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    parent_id VARCHAR(50) NULL REFERENCES categories(id),
+    default_min_stock INTEGER NOT NULL DEFAULT 0 CHECK (default_min_stock >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories (parent_id);
+
+-- The categories the code knew (models.DefaultCategories)
+INSERT INTO categories (id, name_he, name_en, sort_order) VALUES
+    ('drinks', 'משקאות', 'Drinks', 10),
+    ('vegetables', 'ירקות', 'Vegetables', 20),
+    ('dairy', 'מוצרי חלב', 'Dairy', 30),
+    ('meat', 'בשר', 'Meat', 40),
+    ('basics', 'מוצרים בסיסיים', 'Basics', 50),
+    ('dry_goods', 'מוצרים יבשים', 'Dry goods', 60),
+    ('sauces', 'רטבים', 'Sauces', 70),
+    ('canned', 'שימורים', 'Canned goods', 80),
+    ('packaging', 'אריזות', 'Packaging', 90)
+ON CONFLICT (id) DO NOTHING;
+
+-- Any other category products are already in, so they stay valid
+INSERT INTO categories (id, name_en, sort_order)
+SELECT DISTINCT category, category, 1000 FROM products WHERE category <> ''
+ON CONFLICT (id) DO NOTHING;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_categories_parent;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- Language of API messages per user; '' = take it from Accept-Language
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language VARCHAR(5) NOT NULL DEFAULT ''
+    CHECK (language IN ('', 'he', 'en'));
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS language;